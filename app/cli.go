@@ -6,8 +6,12 @@ package app
 // - UI: reflect the constraint in the "Target" header line to stay truthful.
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +19,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"find-words/config"
 	"find-words/search"
 )
 
@@ -22,28 +27,164 @@ var version = "0.2"
 
 // Arguments for CLI flags (used to seed TUI)
 type Arguments struct {
+	Roots             []string
 	SearchWords       []string
+	Query             *search.Query // SearchWords parsed as a boolean query (set by Run)
 	ExcludeWords      []string
 	IncludeCode       bool
+	IncludeSheets     bool // also search xlsx/ods
+	IncludeSlides     bool // also search pptx/odp
+	NoArchives        bool // do not look inside zip/tar/gz archives
 	SmartForms        bool
+	FoldDiacritics    bool // "resume" also finds "résumé"
+	DocExtras         bool // docx/odt headers, footers, footnotes and comments too
+	Regex             bool // every search word is a regular expression
 	Distance          int
 	HeavyConcurrency  int
 	FilterWorkers     int
 	FileTimeoutBinary int
 	OnlyType          string
+	Types             []string // --type: search only these type groups or extensions
+	TypeAdd           []string // --type-add NAME:GLOB[,GLOB...] definitions, registered by loadArguments
+	TypeMap           []string // --type-map EXT=FORMAT mappings, registered by loadArguments
+	Profile           string   // -P NAME: the config profile applied under the flags
+	Format            string   // "" (TUI), "text", "json" or "ndjson"
+	Sort              string   // --sort: score, path, mtime, size or date ("" is score in the TUI, found order otherwise)
+	Encoding          string   // --encoding: the encoding of text files ("" or "auto": detected)
+	NoIndex           bool     // ignore saved indexes (see `garp index`)
+
+	// Directory selection: enter dot-directories, disregard ignore files and the built-in
+	// skip list, and directory globs to always skip or always enter
+	Hidden      bool
+	NoIgnore    bool
+	ExcludeDirs []string
+	IncludeDirs []string
+
+	// Email header filters (--from, --to, --subject, --after, --before) as given,
+	// and the filter built from them (set by Run)
+	From, To, Subject string
+	After, Before     string
+	EmailFilter       *search.EmailFilter
+
+	// Front matter conditions (--meta key=value, repeatable) as given, and the filter
+	// built from them (set by Run)
+	Meta       []string
+	MetaFilter *search.MetaFilter
 }
 
-// parseArguments parses command line args
-func parseArguments(args []string) *Arguments {
-	result := &Arguments{
+// defaultArguments returns the built-in defaults (HeavyConcurrency 0 is derived by parseArguments)
+func defaultArguments() *Arguments {
+	return &Arguments{
 		SearchWords:       []string{},
 		ExcludeWords:      []string{},
 		IncludeCode:       false,
 		Distance:          0,
-		HeavyConcurrency:  2,
+		HeavyConcurrency:  0,
 		FilterWorkers:     4,
 		FileTimeoutBinary: 1000,
 	}
+}
+
+// loadArguments parses command line args over the defaults of the config files and of the
+// profile selected with -P (see config.Load); flags override both, except that --not words,
+// directory globs and type definitions add to the configured ones. The type definitions and
+// mappings are then registered (see config.AddType and config.MapType).
+func loadArguments(argv []string) (*Arguments, error) {
+	cfg, err := config.Load(".")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Apply()
+	base := defaultArguments()
+	applySettings(base, cfg.Settings)
+	if name := profileFlag(argv); name != "" {
+		p, err := cfg.Profile(name)
+		if err != nil {
+			return nil, err
+		}
+		applySettings(base, p)
+	}
+	args := parseArguments(argv, base)
+	for _, spec := range args.TypeAdd {
+		if err := config.AddType(spec); err != nil {
+			return nil, err
+		}
+	}
+	for _, spec := range args.TypeMap {
+		if err := config.MapType(spec); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// profileFlag returns the value of -P/--profile in argv ("" when absent)
+func profileFlag(argv []string) string {
+	for i, a := range argv {
+		if (a == "-P" || a == "--profile") && i+1 < len(argv) {
+			return argv[i+1]
+		}
+	}
+	return ""
+}
+
+// applySettings overlays the fields a config file or profile sets onto a
+func applySettings(a *Arguments, s config.Settings) {
+	if len(s.Roots) > 0 {
+		a.Roots = nil
+		for _, r := range s.Roots {
+			a.Roots = append(a.Roots, expandHome(r))
+		}
+	}
+	if len(s.Types) > 0 {
+		a.Types = nil
+		for _, t := range s.Types {
+			a.Types = append(a.Types, strings.TrimPrefix(strings.ToLower(t), "."))
+		}
+	}
+	a.TypeAdd = append(a.TypeAdd, s.TypeAdd...)
+	a.TypeMap = append(a.TypeMap, s.TypeMap...)
+	for _, p := range []struct {
+		dst *bool
+		src *bool
+	}{
+		{&a.IncludeCode, s.Code}, {&a.IncludeSheets, s.IncludeSheets}, {&a.IncludeSlides, s.IncludeSlides},
+		{&a.NoArchives, s.NoArchives}, {&a.SmartForms, s.SmartForms}, {&a.FoldDiacritics, s.FoldDiacritics},
+		{&a.DocExtras, s.DocExtras}, {&a.NoIndex, s.NoIndex}, {&a.Hidden, s.Hidden}, {&a.NoIgnore, s.NoIgnore},
+	} {
+		if p.src != nil {
+			*p.dst = *p.src
+		}
+	}
+	for _, p := range []struct {
+		dst *int
+		src *int
+	}{
+		{&a.Distance, s.Distance}, {&a.FilterWorkers, s.Workers},
+		{&a.HeavyConcurrency, s.HeavyConcurrency}, {&a.FileTimeoutBinary, s.FileTimeout},
+	} {
+		if p.src != nil && *p.src >= 0 {
+			*p.dst = *p.src
+		}
+	}
+	if len(s.Exclude) > 0 {
+		a.ExcludeWords = slices.Clone(s.Exclude)
+	}
+	if len(s.ExcludeDirs) > 0 {
+		a.ExcludeDirs = slices.Clone(s.ExcludeDirs)
+	}
+	if len(s.IncludeDirs) > 0 {
+		a.IncludeDirs = slices.Clone(s.IncludeDirs)
+	}
+}
+
+// parseArguments parses command line args over base (defaultArguments when nil).
+// --root and --type replace the roots and types of base; other flags override single values.
+func parseArguments(args []string, base *Arguments) *Arguments {
+	result := base
+	if result == nil {
+		result = defaultArguments()
+	}
 
 	parsingExcludes := false
 	expectDistance := false
@@ -51,7 +192,17 @@ func parseArguments(args []string) *Arguments {
 	expectTimeout := false
 	expectWorkers := false
 	expectOnly := false
-	heavyProvided := false
+	expectRoot := false
+	expectFormat := false
+	expectMeta := false
+	expectEncoding := false
+	expectSort := false
+	var expectHeader *string // header filter flag awaiting its value
+	var expectList *[]string // repeatable flag (directory globs, type definitions) awaiting its value
+	expectProfile := false
+	expectType := false
+	cliRoots := false
+	cliTypes := false
 
 	for _, a := range args {
 		if expectDistance {
@@ -64,7 +215,6 @@ func parseArguments(args []string) *Arguments {
 		if expectHeavy {
 			if n, err := strconv.Atoi(a); err == nil && n > 0 {
 				result.HeavyConcurrency = n
-				heavyProvided = true
 			}
 			expectHeavy = false
 			continue
@@ -88,9 +238,72 @@ func parseArguments(args []string) *Arguments {
 			expectOnly = false
 			continue
 		}
+		if expectRoot {
+			if !cliRoots {
+				// Roots on the command line replace configured ones
+				result.Roots, cliRoots = nil, true
+			}
+			result.Roots = append(result.Roots, expandHome(a))
+			expectRoot = false
+			continue
+		}
+		if expectFormat {
+			result.Format = strings.ToLower(a)
+			expectFormat = false
+			continue
+		}
+		if expectMeta {
+			result.Meta = append(result.Meta, a)
+			expectMeta = false
+			continue
+		}
+		if expectEncoding {
+			result.Encoding = a
+			expectEncoding = false
+			continue
+		}
+		if expectSort {
+			result.Sort = strings.ToLower(a)
+			expectSort = false
+			continue
+		}
+		if expectProfile {
+			result.Profile = a
+			expectProfile = false
+			continue
+		}
+		if expectType {
+			if !cliTypes {
+				// Types on the command line replace configured ones
+				result.Types, cliTypes = nil, true
+			}
+			for _, t := range strings.Split(a, ",") {
+				if t = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(t)), "."); t != "" {
+					result.Types = append(result.Types, t)
+				}
+			}
+			expectType = false
+			continue
+		}
+		if expectList != nil {
+			*expectList = append(*expectList, a)
+			expectList = nil
+			continue
+		}
+		if expectHeader != nil {
+			*expectHeader = a
+			expectHeader = nil
+			continue
+		}
 		switch a {
 		case "--code":
 			result.IncludeCode = true
+		case "--include-sheets":
+			result.IncludeSheets = true
+		case "--include-slides":
+			result.IncludeSlides = true
+		case "--no-archives":
+			result.NoArchives = true
 		case "--not":
 			parsingExcludes = true
 		case "--distance", "-distance":
@@ -103,8 +316,52 @@ func parseArguments(args []string) *Arguments {
 			expectWorkers = true
 		case "--only":
 			expectOnly = true
+		case "--root":
+			expectRoot = true
+		case "--format":
+			expectFormat = true
+		case "--sort":
+			expectSort = true
+		case "--from":
+			expectHeader = &result.From
+		case "--to":
+			expectHeader = &result.To
+		case "--subject":
+			expectHeader = &result.Subject
+		case "--after":
+			expectHeader = &result.After
+		case "--before":
+			expectHeader = &result.Before
+		case "--meta":
+			expectMeta = true
+		case "-P", "--profile":
+			expectProfile = true
+		case "--hidden":
+			result.Hidden = true
+		case "--no-ignore":
+			result.NoIgnore = true
+		case "--exclude-dir":
+			expectList = &result.ExcludeDirs
+		case "--include-dir":
+			expectList = &result.IncludeDirs
+		case "--type":
+			expectType = true
+		case "--type-add":
+			expectList = &result.TypeAdd
+		case "--type-map":
+			expectList = &result.TypeMap
 		case "--smart-forms":
 			result.SmartForms = true
+		case "--fold-diacritics":
+			result.FoldDiacritics = true
+		case "--doc-extras":
+			result.DocExtras = true
+		case "--regex":
+			result.Regex = true
+		case "--encoding":
+			expectEncoding = true
+		case "--no-index":
+			result.NoIndex = true
 		case "--help", "-h":
 			showUsage()
 			os.Exit(0)
@@ -121,7 +378,7 @@ func parseArguments(args []string) *Arguments {
 	}
 
 	// Auto-derive HeavyConcurrency when not explicitly provided: base on workers and available RAM
-	if !heavyProvided {
+	if result.HeavyConcurrency <= 0 {
 		// default derived heavy = max(1, workers/2)
 		workers := result.FilterWorkers
 		derived := workers / 2
@@ -174,6 +431,18 @@ func parseArguments(args []string) *Arguments {
 	return result
 }
 
+// expandHome expands a leading "~" to the user's home directory (the shell does not expand "~" inside quotes).
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
 // showUsage (styled)
 func showUsage() {
 	fmt.Println()
@@ -191,18 +460,52 @@ func showUsage() {
 
 	// Usage
 	fmt.Println(subHeaderStyle.Render("USAGE"))
-	fmt.Println(infoStyle.Render(wrapTextWithIndent("  garp ", "[--code] [--distance N] [--heavy-concurrency N] [--workers N] [--file-timeout-binary N] <word1> <word2> ... [--not <exclude1> <exclude2> ...]", 100)))
+	fmt.Println(infoStyle.Render(wrapTextWithIndent("  garp ", "[-P PROFILE] [--code] [--include-sheets] [--include-slides] [--no-archives] [--root DIR ...] [--distance N] [--heavy-concurrency N] [--workers N] [--file-timeout-binary N] [--format text|json|ndjson] [--sort score|path|mtime|size|date] [--no-index] [--hidden] [--no-ignore] [--exclude-dir GLOB ...] [--include-dir GLOB ...] [--from S] [--to S] [--subject S] [--after DATE] [--before DATE] [--meta KEY=VALUE ...] [--encoding NAME] [--type NAME ...] [--type-add NAME:GLOB,...] [--type-map EXT=FORMAT] <word1> <word2> ... [--not <exclude1> <exclude2> ...]", 100)))
+	fmt.Println(infoStyle.Render(wrapTextWithIndent("  garp ", "index build|update|status [--root DIR ...] [--code] [--include-sheets] [--include-slides] [--only <type>] [--type NAME ...] [--type-add NAME:GLOB,...] [--type-map EXT=FORMAT] [--hidden] [--no-ignore] [--exclude-dir GLOB ...] [--include-dir GLOB ...]", 100)))
+	fmt.Println(infoStyle.Render(wrapTextWithIndent("  garp ", "config show|path [-P PROFILE] [flags...]", 100)))
 	fmt.Println()
 
 	// Flags
 	fmt.Println(subHeaderStyle.Render("FLAGS"))
+	fmt.Println(infoStyle.Render("  -P, --profile NAME      Apply [profile.NAME] of the config files under the flags"))
 	fmt.Println(infoStyle.Render("  --code                  Include code files in the search"))
+	fmt.Println(infoStyle.Render("  --include-sheets        Also search spreadsheets (xlsx, ods)"))
+	fmt.Println(infoStyle.Render("  --include-slides        Also search presentations (pptx, odp)"))
+	fmt.Println(infoStyle.Render("  --no-archives           Do not search inside zip, tar, tgz and gz archives"))
+	fmt.Println(infoStyle.Render("  --root DIR              Search under DIR instead of \".\"; repeat for several roots"))
 	fmt.Println(infoStyle.Render("  --distance N            Proximity window in characters (default 5000)"))
 	fmt.Println(infoStyle.Render("  --heavy-concurrency N   Concurrent heavy extractions (auto if omitted)"))
 	fmt.Println(infoStyle.Render("  --workers N             Stage 2 text filter workers (default 2)"))
 	fmt.Println(infoStyle.Render("  --file-timeout-binary N Timeout in ms for binary extraction (default 1000)"))
 	fmt.Println(infoStyle.Render("  --smart-forms          Enable smart word forms (s, es, ed, ing, al, tion/ation)"))
+	fmt.Println(infoStyle.Render("  --fold-diacritics       Ignore accents when matching (resume finds résumé)"))
+	fmt.Println(infoStyle.Render("  --doc-extras            Also search headers, footers, footnotes and comments of doc/docx/odt"))
 	fmt.Println(infoStyle.Render("  --only <type>          Search only a single file type (e.g., pdf); ignores --code"))
+	fmt.Println(infoStyle.Render("  --type NAME             Search only the type group NAME (documents, code, sheets,"))
+	fmt.Println(infoStyle.Render("                          slides, archives or one added with --type-add) or the"))
+	fmt.Println(infoStyle.Render("                          extension NAME; repeat or separate with commas"))
+	fmt.Println(infoStyle.Render("  --type-add NAME:GLOBS   Add comma-separated globs to the group NAME, defining it"))
+	fmt.Println(infoStyle.Render("                          when new (--type-add notes:*.org,*.note); repeatable"))
+	fmt.Println(infoStyle.Render("  --type-map EXT=FORMAT   Read .EXT files like FORMAT files (--type-map note=md)"))
+	fmt.Println(infoStyle.Render("  --regex                 Treat every search word as a regular expression"))
+	fmt.Println(infoStyle.Render("                          (or write single terms as /pattern/)"))
+	fmt.Println(infoStyle.Render("  --encoding NAME         Read text files as NAME (windows-1252, shift_jis, utf-16le,"))
+	fmt.Println(infoStyle.Render("                          ...) instead of detecting their encoding"))
+	fmt.Println(infoStyle.Render("  --format F              Print results as text, json or ndjson instead of the TUI"))
+	fmt.Println(infoStyle.Render("                          (exit 0 = matches, 1 = no matches, 2 = error)"))
+	fmt.Println(infoStyle.Render("  --sort KEY              Order results by score (most relevant first, the TUI"))
+	fmt.Println(infoStyle.Render("                          default), path, mtime, size or date (newest/largest first)"))
+	fmt.Println(infoStyle.Render("  --no-index              Ignore indexes saved by 'garp index' and read every file"))
+	fmt.Println(infoStyle.Render("  --hidden                Also enter dot-directories (.github, .config, ...)"))
+	fmt.Println(infoStyle.Render("  --no-ignore             Disregard .gitignore/.ignore/.garpignore and the built-in"))
+	fmt.Println(infoStyle.Render("                          skip list (node_modules, vendor, build, dist, ...)"))
+	fmt.Println(infoStyle.Render("  --exclude-dir GLOB      Never enter matching directories; repeatable"))
+	fmt.Println(infoStyle.Render("  --include-dir GLOB      Always enter matching directories, even hidden or ignored ones"))
+	fmt.Println(infoStyle.Render("  --from S, --to S        Only emails whose sender / any To or Cc recipient contains S"))
+	fmt.Println(infoStyle.Render("  --subject S             Only emails whose subject contains S"))
+	fmt.Println(infoStyle.Render("  --after D, --before D   Only emails dated on or after / on or before D (YYYY-MM-DD)"))
+	fmt.Println(infoStyle.Render("  --meta KEY=VALUE        Only Markdown files whose front matter KEY contains VALUE"))
+	fmt.Println(infoStyle.Render("                          (a list KEY must have the item VALUE); repeat to AND"))
 	fmt.Println(infoStyle.Render("  --not ...               Tokens after this are exclusions;"))
 	fmt.Println(infoStyle.Render("                          extensions starting with '.' exclude types; others exclude words"))
 	fmt.Println(infoStyle.Render("  --help, -h              Show help"))
@@ -214,9 +517,23 @@ func showUsage() {
 	fmt.Println(infoStyle.Render("  garp contract payment agreement"))
 	fmt.Println(infoStyle.Render("  garp contract payment agreement --distance 200"))
 	fmt.Println(infoStyle.Render("  garp mutex changed --code"))
+	fmt.Println(infoStyle.Render("  garp --root ~/mail --root /srv/contracts contract payment"))
 	fmt.Println(infoStyle.Render("  garp bank wire update --not .txt test"))
+	fmt.Println(infoStyle.Render("  garp \"wire transfer\" approval"))
+	fmt.Println(infoStyle.Render("  garp invoice \"(wire\" OR \"ach)\" -test"))
+	fmt.Println(infoStyle.Render("  garp account '/\\b\\d{8,12}\\b/' --format text"))
 	fmt.Println(infoStyle.Render("  garp approval chris gemini --smart-forms"))
 	fmt.Println(infoStyle.Render("  garp report earnings --only pdf"))
+	fmt.Println(infoStyle.Render("  garp invoice overdue --format ndjson | jq .path"))
+	fmt.Println(infoStyle.Render("  garp contract --from alice@ --to legal@ --after 2023-01-01 --before 2023-06-30"))
+	fmt.Println(infoStyle.Render("  garp deploy --meta tags=kubernetes --meta author=dana"))
+	fmt.Println(infoStyle.Render("  garp dataframe groupby --code --only ipynb"))
+	fmt.Println(infoStyle.Render("  garp meeting agenda --type-add notes:*.org,*.note --type notes"))
+	fmt.Println(infoStyle.Render("  garp release notes --type-map page=html --type documents"))
+	fmt.Println(infoStyle.Render("  garp release checklist --include-dir .github --exclude-dir 'docs/generated'"))
+	fmt.Println(infoStyle.Render("  garp index update --root /srv/share"))
+	fmt.Println(infoStyle.Render("  garp -P mail wire approval"))
+	fmt.Println(infoStyle.Render("  garp config show -P mail"))
 	fmt.Println()
 }
 
@@ -226,32 +543,176 @@ func showVersion() {
 	fmt.Println(successStyle.Render("garp v" + version))
 }
 
-// Run parses CLI arguments and starts the TUI. Returns a process exit code.
+// parseQuery turns the search words into a query. With --regex every word is a
+// regular expression (ANDed, no query grammar, since patterns use parentheses and "-").
+// Regex terms (including /.../ exclusions) are validated up front.
+func parseQuery(args *Arguments) (*search.Query, error) {
+	var query *search.Query
+	if args.Regex {
+		terms := make([]string, len(args.SearchWords))
+		for i, w := range args.SearchWords {
+			terms[i] = "/" + strings.TrimSuffix(strings.TrimPrefix(w, "/"), "/") + "/"
+		}
+		query = search.NewQuery(terms)
+		if err := query.Validate(); err != nil {
+			return nil, err
+		}
+	} else {
+		q, err := search.ParseQuery(args.SearchWords)
+		if err != nil {
+			return nil, err
+		}
+		query = q
+	}
+	for _, w := range args.ExcludeWords {
+		if err := search.ValidateTerm(w); err != nil {
+			return nil, err
+		}
+	}
+	return query, nil
+}
+
+// newSearchEngine builds a search engine from parsed CLI arguments.
+// Shared by the TUI and the non-interactive output modes so both search identically.
+func newSearchEngine(args *Arguments) *search.SearchEngine {
+	query := args.Query
+	if query == nil {
+		query = search.NewQuery(args.SearchWords)
+	}
+	// Negated query terms behave like --not words
+	se := search.NewSearchEngineWithWorkers(
+		query.Terms(),
+		slices.Concat(args.ExcludeWords, query.Negated),
+		indexFileTypes(args),
+		args.IncludeCode,
+		args.HeavyConcurrency,
+		args.FileTimeoutBinary,
+		args.FilterWorkers,
+	)
+	se.Silent = true
+	se.Groups = query.Groups
+	se.EmailFilter = args.EmailFilter
+	se.MetaFilter = args.MetaFilter
+	se.Walk = walkOptions(args)
+	se.Encoding = args.Encoding
+	se.DocExtras = args.DocExtras
+	se.FoldDiacritics = args.FoldDiacritics
+	if len(args.Roots) > 0 {
+		se.Roots = args.Roots
+	}
+	// Override default proximity window if provided
+	if args.Distance > 0 {
+		se.Distance = args.Distance
+	}
+	if !args.NoIndex {
+		se.Indexes = loadIndexes(args.Roots)
+	}
+	return se
+}
+
+// walkOptions returns the directory selection given on the command line
+func walkOptions(args *Arguments) search.WalkOptions {
+	return search.WalkOptions{
+		Hidden:      args.Hidden,
+		NoIgnore:    args.NoIgnore,
+		ExcludeDirs: args.ExcludeDirs,
+		IncludeDirs: args.IncludeDirs,
+	}
+}
+
+// Run parses CLI arguments and starts the TUI (or prints results when --format is given).
+// Returns a process exit code.
 func Run() int {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "index" {
+		return runIndex(os.Args[2:], os.Stdout)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		return runConfig(os.Args[2:], os.Stdout)
+	}
+
 	// Parse args
-	args := parseArguments(os.Args[1:])
+	args, err := loadArguments(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+		return exitError
+	}
 	if len(args.SearchWords) == 0 {
 		showUsage()
+		if args.Format != "" {
+			return exitError
+		}
 		return 1
 	}
+	query, err := parseQuery(args)
+	if err == nil {
+		args.EmailFilter, err = search.NewEmailFilter(args.From, args.To, args.Subject, args.After, args.Before)
+	}
+	if err == nil {
+		args.MetaFilter, err = search.NewMetaFilter(args.Meta)
+	}
+	if err == nil {
+		err = search.ValidateEncoding(args.Encoding)
+	}
+	if err == nil {
+		err = search.ValidateSort(args.Sort)
+	}
+	if err != nil {
+		if args.Format != "" {
+			fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+			return exitError
+		}
+		fmt.Println(errorStyle.Render("Error: " + err.Error()))
+		return 1
+	}
+	args.Query = query
+	if args.Format != "" && !isOutputFormat(args.Format) {
+		fmt.Fprintf(os.Stderr, "garp: unknown --format %q (want text, json or ndjson)\n", args.Format)
+		return exitError
+	}
+	// Every search root must exist before we take over the terminal
+	for _, root := range args.Roots {
+		if st, err := os.Stat(root); err != nil || !st.IsDir() {
+			if args.Format != "" {
+				fmt.Fprintf(os.Stderr, "garp: search root %q is not a directory\n", root)
+				return exitError
+			}
+			fmt.Println(errorStyle.Render(fmt.Sprintf("Error: search root %q is not a directory", root)))
+			return 1
+		}
+	}
 	// Hook for matching layer: advertise smart-forms via environment (consumed by matching)
 	if args.SmartForms {
 		_ = os.Setenv("GARP_SMART_FORMS", "1")
 	}
 
+	// Non-interactive mode: no TUI, results go straight to stdout
+	if args.Format != "" {
+		return runOutput(args, os.Stdout)
+	}
+
+	// Cancelled when the user quits so the background search stops with the TUI
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Seed model for TUI
 	m := model{
+		ctx:               ctx,
+		cancel:            cancel,
 		results:           []search.SearchResult{},
 		currentPage:       0,
 		pageSize:          1,
 		totalPages:        0,
+		sortKey:           cmp.Or(args.Sort, "score"),
 		searchTime:        0,
 		quitting:          false,
 		loading:           true,
 		width:             0,
 		height:            0,
-		searchWords:       args.SearchWords,
-		excludeWords:      args.ExcludeWords,
+		args:              args,
+		roots:             args.Roots,
+		searchWords:       query.Terms(),
+		excludeWords:      slices.Concat(args.ExcludeWords, query.Negated),
 		includeCode:       args.IncludeCode,
 		onlyType:          args.OnlyType,
 		distance:          args.Distance,
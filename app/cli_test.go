@@ -0,0 +1,98 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"find-words/config"
+)
+
+func TestParseTypeFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		argv   []string
+		base   []string // types a config file or profile set
+		types  []string
+		add    []string
+		mapped []string
+		words  []string
+	}{
+		{"comma list", []string{"--type", "Notes, .PDF,,md", "invoice"}, nil,
+			[]string{"notes", "pdf", "md"}, nil, nil, []string{"invoice"}},
+		{"repeated", []string{"--type", "sheets", "total", "--type", "csv"}, nil,
+			[]string{"sheets", "csv"}, nil, nil, []string{"total"}},
+		{"replaces configured", []string{"--type", "md", "x"}, []string{"pdf", "docx"},
+			[]string{"md"}, nil, nil, []string{"x"}},
+		{"configured kept", []string{"x"}, []string{"pdf"},
+			[]string{"pdf"}, nil, nil, []string{"x"}},
+		{"definitions", []string{"--type-add", "notes:*.org", "agenda", "--type-add", "memo:memo", "--type-map", "page=html"}, nil,
+			nil, []string{"notes:*.org", "memo:memo"}, []string{"page=html"}, []string{"agenda"}},
+	}
+	for _, tt := range tests {
+		base := defaultArguments()
+		base.Types = tt.base
+		args := parseArguments(tt.argv, base)
+		if !slices.Equal(args.Types, tt.types) || !slices.Equal(args.TypeAdd, tt.add) || !slices.Equal(args.TypeMap, tt.mapped) {
+			t.Errorf("%s: types %q, type-add %q, type-map %q; want %q, %q, %q", tt.name, args.Types, args.TypeAdd, args.TypeMap, tt.types, tt.add, tt.mapped)
+		}
+		if !slices.Equal(args.SearchWords, tt.words) {
+			t.Errorf("%s: words %q, want %q", tt.name, args.SearchWords, tt.words)
+		}
+	}
+}
+
+func TestTypeSelection(t *testing.T) {
+	tests := []struct {
+		name string
+		argv []string
+		want []string
+	}{
+		{"defaults", nil, []string{"documents", "archives"}},
+		{"opt-in groups", []string{"--code", "--include-sheets", "--include-slides", "--no-archives"}, []string{"documents", "code", "sheets", "slides"}},
+		{"type wins over opt-ins", []string{"--code", "--type", "md"}, []string{"md"}},
+		{"only wins over type", []string{"--type", "md", "--only", ".PDF"}, []string{"pdf"}},
+	}
+	for _, tt := range tests {
+		args := parseArguments(append(tt.argv, "x"), nil)
+		if got := typeSelection(args); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The selection becomes -g globs: a group's extensions, an extension as *.ext
+	args := parseArguments([]string{"--type", "slides,csv", "x"}, nil)
+	if got, want := indexFileTypes(args), []string{"-g", "*.pptx", "-g", "*.odp", "-g", "*.csv"}; !slices.Equal(got, want) {
+		t.Errorf("indexFileTypes = %q, want %q", got, want)
+	}
+}
+
+func TestLoadArgumentsRegistersTypes(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "home"))
+	t.Chdir(dir)
+	if err := os.WriteFile(".garp.toml", []byte("type_add = [\"ledger:*.ledger\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := loadArguments([]string{"--type-add", "ledger:journal", "--type-map", "gloss=md", "--type", "ledger", "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := indexFileTypes(args), []string{"-g", "*.ledger", "-g", "*.journal"}; !slices.Equal(got, want) {
+		t.Errorf("configured and command-line globs: got %q, want %q", got, want)
+	}
+	if config.FormatOf("gloss") != "md" {
+		t.Errorf("FormatOf(gloss) = %q, want md", config.FormatOf("gloss"))
+	}
+	if docs, _ := config.LookupType("documents"); !slices.Contains(docs.Globs, "*.gloss") {
+		t.Errorf("documents = %q, want *.gloss added by its mapping", docs.Globs)
+	}
+
+	for _, bad := range [][]string{{"--type-add", "ledger", "x"}, {"--type-map", "gloss=nope", "x"}} {
+		if _, err := loadArguments(bad); err == nil {
+			t.Errorf("loadArguments(%q): want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	"find-words/config"
+	"find-words/search"
+)
+
+// runConfig implements `garp config show|path [-P NAME] [flags...]`. show prints the
+// effective configuration as TOML: the defaults after the config files, the selected
+// profile and any flags given, then the global tunables and the defined profiles; path
+// prints the config files that are read. Returns a process exit code.
+func runConfig(argv []string, w io.Writer) int {
+	if len(argv) == 0 || (argv[0] != "show" && argv[0] != "path") {
+		fmt.Fprintln(os.Stderr, "usage: garp config show|path [-P NAME] [flags...]")
+		return exitError
+	}
+	action := argv[0]
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+		return exitError
+	}
+	if action == "path" {
+		user, err := config.UserConfigPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(w, "user:  %s%s\n", user, missingNote(user))
+		if local := config.LocalConfigPath("."); local != "" {
+			fmt.Fprintf(w, "local: %s\n", local)
+		} else {
+			fmt.Fprintf(w, "local: none (%s in this directory or a parent)\n", config.LocalFileName)
+		}
+		return 0
+	}
+
+	args, err := loadArguments(argv[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+		return exitError
+	}
+	effective := config.Config{
+		Settings:        argumentSettings(args),
+		SkipDirectories: config.SkipDirectories,
+		FileTypes:       config.FileTypes{Documents: config.DocumentTypes, Code: config.CodeTypes},
+		Limits:          config.ActiveLimits,
+		Profiles:        cfg.Profiles,
+	}
+
+	if len(cfg.Files) == 0 {
+		fmt.Fprintln(w, "# No config files; built-in defaults")
+	}
+	for _, path := range cfg.Files {
+		fmt.Fprintf(w, "# Read %s\n", path)
+	}
+	if args.Profile != "" {
+		fmt.Fprintf(w, "# Profile %q applied\n", args.Profile)
+	}
+	if err := toml.NewEncoder(w).Encode(effective); err != nil {
+		fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+		return exitError
+	}
+	return 0
+}
+
+// argumentSettings returns the search defaults of args with every field set
+func argumentSettings(args *Arguments) config.Settings {
+	distance := args.Distance
+	if distance <= 0 {
+		distance = search.DefaultDistance
+	}
+	return config.Settings{
+		Roots:            args.Roots,
+		Types:            args.Types,
+		TypeAdd:          args.TypeAdd,
+		TypeMap:          args.TypeMap,
+		Code:             &args.IncludeCode,
+		IncludeSheets:    &args.IncludeSheets,
+		IncludeSlides:    &args.IncludeSlides,
+		NoArchives:       &args.NoArchives,
+		Distance:         &distance,
+		Workers:          &args.FilterWorkers,
+		HeavyConcurrency: &args.HeavyConcurrency,
+		FileTimeout:      &args.FileTimeoutBinary,
+		SmartForms:       &args.SmartForms,
+		FoldDiacritics:   &args.FoldDiacritics,
+		DocExtras:        &args.DocExtras,
+		NoIndex:          &args.NoIndex,
+		Hidden:           &args.Hidden,
+		NoIgnore:         &args.NoIgnore,
+		Exclude:          args.ExcludeWords,
+		ExcludeDirs:      args.ExcludeDirs,
+		IncludeDirs:      args.IncludeDirs,
+	}
+}
+
+// missingNote marks a config file that does not exist
+func missingNote(path string) string {
+	if _, err := os.Stat(path); err != nil {
+		return " (not present)"
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"time"
+
+	"find-words/config"
+	"find-words/search"
+)
+
+// runIndex implements `garp index build|update|status [--root DIR ...] [--code] [--include-sheets] [--include-slides] [--only T]`
+// (plus the directory selection flags of the search: --hidden, --no-ignore, --exclude-dir, --include-dir).
+// build re-extracts every file, update only files whose size or mtime changed, and status
+// reports how far each root's index has drifted. Returns a process exit code.
+func runIndex(argv []string, w io.Writer) int {
+	args, err := loadArguments(argv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+		return exitError
+	}
+	if len(args.SearchWords) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: garp index build|update|status [--root DIR ...] [--code] [--include-sheets] [--include-slides] [--only TYPE] [--hidden] [--no-ignore] [--exclude-dir GLOB] [--include-dir GLOB]")
+		return exitError
+	}
+	action := args.SearchWords[0]
+	if action != "build" && action != "update" && action != "status" {
+		fmt.Fprintf(os.Stderr, "garp: unknown index action %q (want build, update or status)\n", action)
+		return exitError
+	}
+
+	roots := args.Roots
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	fileTypes := indexFileTypes(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for _, root := range roots {
+		if st, err := os.Stat(root); err != nil || !st.IsDir() {
+			fmt.Fprintf(os.Stderr, "garp: search root %q is not a directory\n", root)
+			return exitError
+		}
+		path, err := search.IndexPath(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+			return exitError
+		}
+
+		prev, err := search.LoadIndex(root)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "garp: %v (rebuilding)\n", err)
+		}
+
+		if action == "status" {
+			if prev == nil {
+				fmt.Fprintf(w, "%s: not indexed\n", root)
+				continue
+			}
+			stats, err := prev.Status(ctx, fileTypes, walkOptions(args))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+				return exitError
+			}
+			fmt.Fprintf(w, "%s: %s\n", root, path)
+			fmt.Fprintf(w, "  Built:   %s\n", prev.Built.Format(time.RFC3339))
+			fmt.Fprintf(w, "  Indexed: %d files\n", len(prev.Files))
+			fmt.Fprintf(w, "  Fresh: %d • Changed: %d • New: %d • Removed: %d\n", stats.Fresh, stats.Changed, stats.Added, stats.Removed)
+			continue
+		}
+
+		if action == "build" {
+			prev = nil
+		}
+		start := time.Now()
+		ix, stats, err := search.BuildIndex(ctx, root, prev, search.IndexOptions{
+			FileTypes:   fileTypes,
+			Workers:     args.FilterWorkers,
+			FileTimeout: time.Duration(args.FileTimeoutBinary) * time.Millisecond,
+			Walk:        walkOptions(args),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "garp: indexing %s: %v\n", root, err)
+			return exitError
+		}
+		if err := ix.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "garp: saving index for %s: %v\n", root, err)
+			return exitError
+		}
+		fmt.Fprintf(w, "%s: indexed %d files in %.1fs → %s\n", root, len(ix.Files), time.Since(start).Seconds(), path)
+		fmt.Fprintf(w, "  Reused: %d • Re-extracted: %d • New: %d • Removed: %d • Failed: %d\n", stats.Fresh, stats.Changed, stats.Added, stats.Removed, stats.Failed)
+	}
+	return 0
+}
+
+// indexFileTypes returns the globs an index covers; the same selection the search uses
+func indexFileTypes(args *Arguments) []string {
+	return config.BuildTypeList(typeSelection(args))
+}
+
+// typeSelection returns the type groups and extensions searched: --only, else --type, else
+// documents plus the opt-in groups the flags enable
+func typeSelection(args *Arguments) []string {
+	if args.OnlyType != "" {
+		return []string{args.OnlyType}
+	}
+	if len(args.Types) > 0 {
+		return args.Types
+	}
+	names := []string{"documents"}
+	if args.IncludeCode {
+		names = append(names, "code")
+	}
+	if args.IncludeSheets {
+		names = append(names, "sheets")
+	}
+	if args.IncludeSlides {
+		names = append(names, "slides")
+	}
+	if !args.NoArchives {
+		names = append(names, "archives")
+	}
+	return names
+}
+
+// loadIndexes returns the saved index of every root that has one. Missing or unreadable
+// indexes are skipped: the search then simply reads those files.
+func loadIndexes(roots []string) []*search.Index {
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	var indexes []*search.Index
+	for _, root := range roots {
+		if ix, err := search.LoadIndex(root); err == nil {
+			indexes = append(indexes, ix)
+		}
+	}
+	return indexes
+}
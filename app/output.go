@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"find-words/search"
+)
+
+// Exit codes for non-interactive output, mirroring grep.
+const (
+	exitMatch   = 0
+	exitNoMatch = 1
+	exitError   = 2
+)
+
+// outputResult is the machine-readable shape of a search result.
+// Excerpts are plain text (ANSI highlighting stripped).
+type outputResult struct {
+	Path         string         `json:"path"`
+	Root         string         `json:"root"`
+	FullPath     string         `json:"full_path"`
+	Size         int64          `json:"size"`
+	Modified     string         `json:"modified,omitempty"` // modification time, RFC 3339
+	Score        float64        `json:"score"`              // relevance, higher is better (see --sort)
+	Excerpts     []string       `json:"excerpts"`
+	EmailDate    string         `json:"email_date,omitempty"`
+	EmailSubject string         `json:"email_subject,omitempty"`
+	Message      int            `json:"message,omitempty"`    // 1-based message number within an mbox
+	Attachment   string         `json:"attachment,omitempty"` // email attachment the match came from
+	Section      string         `json:"section,omitempty"`    // e-book chapter or notebook cell the match came from
+	Meta         map[string]any `json:"meta,omitempty"`       // Markdown front matter: key -> string, or list of strings
+	Sender       string         `json:"sender,omitempty"`
+	Recipients   []string       `json:"recipients,omitempty"` // To, then Cc
+	Date         string         `json:"date,omitempty"`       // parsed email date, RFC 3339
+
+	meta search.FrontMatter // Meta in document order, for text output
+}
+
+// isOutputFormat reports whether f is a supported --format value
+func isOutputFormat(f string) bool {
+	switch f {
+	case "text", "json", "ndjson":
+		return true
+	}
+	return false
+}
+
+// toOutputResult converts a search result into its plain, serializable form
+func toOutputResult(r search.SearchResult) outputResult {
+	excerpts := make([]string, 0, len(r.Excerpts))
+	for _, ex := range r.Excerpts {
+		excerpts = append(excerpts, search.StripANSI(ex))
+	}
+	var date, modified string
+	if !r.Date.IsZero() {
+		date = r.Date.Format(time.RFC3339)
+	}
+	if !r.ModTime.IsZero() {
+		modified = r.ModTime.Format(time.RFC3339)
+	}
+	return outputResult{
+		Path:         r.FilePath,
+		Root:         r.Root,
+		FullPath:     r.FullPath(),
+		Size:         r.FileSize,
+		Modified:     modified,
+		Score:        r.Score,
+		Excerpts:     excerpts,
+		EmailDate:    r.EmailDate,
+		EmailSubject: r.EmailSubject,
+		Message:      r.Message,
+		Attachment:   r.Attachment,
+		Section:      r.Section,
+		Meta:         frontMatterMap(r.Meta),
+		Sender:       r.Sender,
+		Recipients:   r.Recipients,
+		Date:         date,
+		meta:         r.Meta,
+	}
+}
+
+// frontMatterMap converts front matter to its JSON form: lists stay lists, other fields are strings
+func frontMatterMap(fm search.FrontMatter) map[string]any {
+	if len(fm) == 0 {
+		return nil
+	}
+	m := make(map[string]any, len(fm))
+	for _, f := range fm {
+		if f.List {
+			m[f.Key] = f.Values
+		} else if len(f.Values) > 0 {
+			m[f.Key] = f.Values[0]
+		}
+	}
+	return m
+}
+
+// formatFrontMatter renders front matter on one line: "title: Notes • tags: go, search"
+func formatFrontMatter(fm search.FrontMatter) string {
+	fields := make([]string, 0, len(fm))
+	for _, f := range fm {
+		fields = append(fields, f.Key+": "+strings.Join(f.Values, ", "))
+	}
+	return strings.Join(fields, " • ")
+}
+
+// writeText prints one result as a path line followed by indented metadata and excerpts
+func writeText(w io.Writer, r outputResult) {
+	fmt.Fprintf(w, "%s (%s)\n", search.AttachmentPath(r.FullPath, r.Attachment), formatFileSize(r.Size))
+	if r.Sender != "" {
+		fmt.Fprintf(w, "  From: %s\n", r.Sender)
+	}
+	if len(r.Recipients) > 0 {
+		fmt.Fprintf(w, "  To: %s\n", strings.Join(r.Recipients, ", "))
+	}
+	if r.EmailSubject != "" {
+		fmt.Fprintf(w, "  Subject: %s\n", r.EmailSubject)
+	}
+	if r.EmailDate != "" {
+		fmt.Fprintf(w, "  Date: %s\n", r.EmailDate)
+	}
+	if r.Section != "" {
+		fmt.Fprintf(w, "  Section: %s\n", r.Section)
+	}
+	if len(r.meta) > 0 {
+		fmt.Fprintf(w, "  Meta: %s\n", formatFrontMatter(r.meta))
+	}
+	for _, ex := range r.Excerpts {
+		fmt.Fprintf(w, "  %s\n", ex)
+	}
+	fmt.Fprintln(w)
+}
+
+// runOutput runs the search without the TUI and writes results to w in args.Format.
+// Returns exitMatch, exitNoMatch or exitError.
+func runOutput(args *Arguments, w io.Writer) int {
+	// Ctrl-C stops the search; the results found so far are still written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return searchOutput(ctx, args, w)
+}
+
+// searchOutput is runOutput under ctx. When ctx is cancelled, the results found until
+// then are written (json: the array so far) and the exit code tells whether there were any.
+func searchOutput(ctx context.Context, args *Arguments, w io.Writer) int {
+	se := newSearchEngine(args)
+
+	// text and ndjson are line-oriented: stream each result as soon as it is built.
+	// json is a single array, and a --sort order needs every result, so those are
+	// written once the search completes.
+	streamed := 0
+	var writeErr error
+	enc := json.NewEncoder(w)
+	writeLine := func(r search.SearchResult) {
+		if args.Format == "ndjson" {
+			if err := enc.Encode(toOutputResult(r)); err != nil && writeErr == nil {
+				writeErr = err
+			}
+			return
+		}
+		writeText(w, toOutputResult(r))
+	}
+	if args.Format != "json" && args.Sort == "" {
+		se.OnResult = func(r search.SearchResult) {
+			streamed++
+			writeLine(r)
+		}
+	}
+
+	results, err := se.Execute(ctx)
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, "garp: search interrupted")
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "garp: %v\n", err)
+		return exitError
+	}
+
+	search.SortResults(results, args.Sort)
+	switch {
+	case args.Format == "json":
+		out := make([]outputResult, 0, len(results))
+		for _, r := range results {
+			out = append(out, toOutputResult(r))
+		}
+		enc.SetIndent("", "  ")
+		writeErr = enc.Encode(out)
+	case args.Sort != "":
+		for _, r := range results {
+			writeLine(r)
+		}
+	}
+	if writeErr != nil {
+		fmt.Fprintf(os.Stderr, "garp: %v\n", writeErr)
+		return exitError
+	}
+
+	if len(results) == 0 && streamed == 0 {
+		return exitNoMatch
+	}
+	return exitMatch
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// outputArgs returns the arguments of a search for words under dir printed as format
+func outputArgs(dir, format string, words ...string) *Arguments {
+	args := defaultArguments()
+	args.Roots = []string{dir}
+	args.SearchWords = words
+	args.Format = format
+	args.NoIndex = true
+	args.HeavyConcurrency = 2
+	return args
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunOutput(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.txt": "The quarterly invoice was paid on time.",
+		"b.txt": "Another invoice, still open.",
+		"c.txt": "Nothing to see here.",
+	})
+
+	var out bytes.Buffer
+	if code := runOutput(outputArgs(dir, "ndjson", "invoice", "paid"), &out); code != exitMatch {
+		t.Fatalf("ndjson: exit %d, want %d", code, exitMatch)
+	}
+	var lines []outputResult
+	sc := bufio.NewScanner(&out)
+	for sc.Scan() {
+		var r outputResult
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("ndjson line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, r)
+	}
+	if len(lines) != 1 || lines[0].FullPath != filepath.Join(dir, "a.txt") || len(lines[0].Excerpts) == 0 {
+		t.Fatalf("ndjson results = %+v", lines)
+	}
+	if ex := lines[0].Excerpts[0]; strings.Contains(ex, "\x1b[") || !strings.Contains(ex, "invoice") {
+		t.Errorf("excerpt %q: want plain text with the term", ex)
+	}
+
+	out.Reset()
+	if code := runOutput(outputArgs(dir, "json", "invoice"), &out); code != exitMatch {
+		t.Fatalf("json: exit %d, want %d", code, exitMatch)
+	}
+	var all []outputResult
+	if err := json.Unmarshal(out.Bytes(), &all); err != nil || len(all) != 2 {
+		t.Fatalf("json: %d results, %v", len(all), err)
+	}
+
+	out.Reset()
+	if code := runOutput(outputArgs(dir, "text", "missing"), &out); code != exitNoMatch {
+		t.Errorf("no match: exit %d, want %d", code, exitNoMatch)
+	}
+	if out.Len() != 0 {
+		t.Errorf("no match: output %q, want none", out.String())
+	}
+
+	out.Reset()
+	if code := runOutput(outputArgs(dir, "json", "missing"), &out); code != exitNoMatch || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("json without matches: exit %d, output %q", code, out.String())
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	writeText(&out, outputResult{
+		FullPath:     "/mail/inbox.eml",
+		Size:         2048,
+		Attachment:   "report.pdf",
+		Sender:       "Ann <ann@example.com>",
+		Recipients:   []string{"bob@example.com", "carol@example.com"},
+		EmailSubject: "Q3 report",
+		Excerpts:     []string{"the invoice is attached"},
+	})
+	want := "/mail/inbox.eml › report.pdf (2.0 KB)\n" +
+		"  From: Ann <ann@example.com>\n" +
+		"  To: bob@example.com, carol@example.com\n" +
+		"  Subject: Q3 report\n" +
+		"  the invoice is attached\n\n"
+	if got := out.String(); got != want {
+		t.Errorf("writeText:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// cancelWriter cancels a search once the first result is written to it
+type cancelWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	defer w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestSearchOutputCancelled(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 50 {
+		files[fmt.Sprintf("f%02d.txt", i)] = "the invoice is due"
+	}
+	dir := writeFiles(t, files)
+
+	// Results streamed before the interruption are kept and count as a match
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &cancelWriter{cancel: cancel}
+	if code := searchOutput(ctx, outputArgs(dir, "ndjson", "invoice"), w); code != exitMatch {
+		t.Errorf("ndjson: exit %d, want %d", code, exitMatch)
+	}
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	if len(lines) == 0 || len(lines) == len(files) {
+		t.Errorf("ndjson: %d lines, want some but not all of %d", len(lines), len(files))
+	}
+	for _, line := range lines {
+		var r outputResult
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Errorf("ndjson line %q: %v", line, err)
+		}
+	}
+
+	// json still writes a (here empty) array
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	if code := searchOutput(ctx, outputArgs(dir, "json", "invoice"), &out); code != exitNoMatch || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("json: exit %d, output %q; want %d and []", code, out.String(), exitNoMatch)
+	}
+}
+
+// zipBytes returns a zip archive holding the given name, content pairs
+func zipBytes(t *testing.T, entries ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(entries); i += 2 {
+		w, err := zw.Create(entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(entries[i+1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestIncludeSheetsSlides(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"book.xlsx": zipBytes(t, "[Content_Types].xml", "<Types/>",
+			"xl/sharedStrings.xml", "<sst><si><t>quarterly forecast</t></si></sst>",
+			"xl/worksheets/sheet1.xml", `<worksheet><sheetData><row><c t="s"><v>0</v></c></row></sheetData></worksheet>`),
+		"deck.pptx": zipBytes(t, "[Content_Types].xml", "<Types/>",
+			"ppt/slides/slide1.xml", "<p:sld><a:t>quarterly forecast</a:t></p:sld>"),
+		"notes.txt": "the quarterly forecast",
+	})
+	tests := []struct {
+		name           string
+		sheets, slides bool
+		want           []string
+	}{
+		{"default", false, false, []string{"notes.txt"}},
+		{"sheets", true, false, []string{"book.xlsx", "notes.txt"}},
+		{"slides", false, true, []string{"deck.pptx", "notes.txt"}},
+		{"both", true, true, []string{"book.xlsx", "deck.pptx", "notes.txt"}},
+	}
+	for _, tt := range tests {
+		args := outputArgs(dir, "json", "forecast")
+		args.IncludeSheets, args.IncludeSlides = tt.sheets, tt.slides
+		var out bytes.Buffer
+		if code := runOutput(args, &out); code != exitMatch {
+			t.Errorf("%s: exit %d, want %d", tt.name, code, exitMatch)
+			continue
+		}
+		var results []outputResult
+		if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, filepath.Base(r.FullPath))
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: results %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package app
 
 import (
+	"context"
 	"fmt"
-	"regexp"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +25,9 @@ var latestProgress progressMsg
 var haveLatestProgress bool
 var progressMu sync.Mutex
 
+// resultChan carries results from the engine's builder goroutine to the TUI while loading.
+var resultChan = make(chan search.SearchResult, 256)
+
 // Excerpt sizing should exactly match the content box to avoid layout shifts.
 // These are set during View() and read by ExcerptCharBudget.
 var lastExcerptInnerWidth int
@@ -82,11 +87,14 @@ type model struct {
 	pageSize      int
 	totalPages    int
 	contentScroll int
+	sortKey       string // results are kept in this --sort order
 
 	// progress totals
 	totalFiles int
 
 	// Session and timing
+	ctx        context.Context
+	cancel     context.CancelFunc
 	searchTime time.Duration
 	quitting   bool
 	loading    bool
@@ -96,6 +104,8 @@ type model struct {
 	height int
 
 	// Search parameters
+	args              *Arguments
+	roots             []string
 	searchWords       []string
 	excludeWords      []string
 	includeCode       bool
@@ -116,9 +126,18 @@ type model struct {
 	progressText string // e.g., "⏳ Processing..."
 }
 
+// quit cancels the background search (if still running) and exits the TUI
+func (m model) quit() (tea.Model, tea.Cmd) {
+	m.quitting = true
+	if m.cancel != nil {
+		m.cancel()
+	}
+	return m, tea.Quit
+}
+
 func (m model) Init() tea.Cmd {
 	// Start polling progress and kick off the background search immediately.
-	return tea.Batch(pollProgress(), m.runSearch(), m.memUsageTick())
+	return tea.Batch(pollProgress(), m.runSearch(), m.memUsageTick(), waitForResult())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -156,12 +175,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
-		// While loading, only allow quit
-		if m.loading {
+		// While loading with nothing to show yet, only allow quit
+		if m.loading && len(m.results) == 0 {
 			switch msg.String() {
 			case "q", "ctrl+c":
-				m.quitting = true
-				return m, tea.Quit
+				return m.quit()
 			}
 			return m, nil
 		}
@@ -169,8 +187,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Selection navigation for highlighted buttons
 		switch msg.String() {
 		case "q", "ctrl+c":
-			m.quitting = true
-			return m, tea.Quit
+			return m.quit()
 		case "left", "h":
 			m.confirmSelected = "yes"
 			return m, nil
@@ -180,16 +197,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "enter":
 			if m.confirmSelected == "no" {
-				m.quitting = true
-				return m, tea.Quit
+				return m.quit()
 			}
-			// default/"yes": advance or quit if at end
+			// default/"yes": advance or quit if at end (keep waiting while results still stream in)
 			if m.currentPage < m.totalPages-1 {
 				m.currentPage++
 				return m, nil
 			}
-			m.quitting = true
-			return m, tea.Quit
+			if m.loading {
+				return m, nil
+			}
+			return m.quit()
 
 		// Legacy keys
 		case "y", "space":
@@ -197,8 +215,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.currentPage++
 				return m, nil
 			}
-			m.quitting = true
-			return m, tea.Quit
+			if m.loading {
+				return m, nil
+			}
+			return m.quit()
 		case "n":
 			if m.currentPage < m.totalPages-1 {
 				m.currentPage++
@@ -235,10 +255,39 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case resultFoundMsg:
+		// A result streamed in while searching: add a page and keep listening
+		if !m.loading {
+			// The final searchResultMsg already holds every result
+			return m, nil
+		}
+		// Insert it in sort order; a result placed before the one on screen shifts the page
+		// so the user keeps reading the same result
+		i := sort.Search(len(m.results), func(i int) bool {
+			return search.CompareResults(msg.result, m.results[i], m.sortKey) < 0
+		})
+		m.results = slices.Insert(m.results, i, msg.result)
+		if i <= m.currentPage && len(m.results) > 1 {
+			m.currentPage++
+		}
+		m.totalPages = len(m.results)
+		return m, waitForResult()
+
 	case searchResultMsg:
-		// Search completed: store results, compute pages, stop loading
+		// Search completed: store results in sort order, compute pages, stop loading.
+		// Stay on the result shown so far.
+		var shown string
+		if m.currentPage < len(m.results) {
+			shown = m.results[m.currentPage].FullPath()
+		}
 		m.results = msg.results
-		m.confirmSelected = "yes"
+		search.SortResults(m.results, m.sortKey)
+		if i := slices.IndexFunc(m.results, func(r search.SearchResult) bool { return r.FullPath() == shown }); i >= 0 {
+			m.currentPage = i
+		}
+		if m.currentPage >= len(m.results) {
+			m.currentPage = 0
+		}
 		m.searchTime = msg.searchTime
 		m.pdfScanned = msg.pdfScanned
 		m.pdfSkipped = msg.pdfSkipped
@@ -329,8 +378,8 @@ func (m model) View() string {
 
 	// Search terms (full list, wrapped)
 	{
-		// Build full comma-separated list and wrap
-		searchLine := wrapTextWithIndent("🔍 Searching: ", strings.Join(m.searchWords, ", "), width-4)
+		// Build full comma-separated list (OR groups in parentheses) and wrap
+		searchLine := wrapTextWithIndent("🔍 Searching: ", strings.Join(queryGroupLabels(m.args.Query), ", "), width-4)
 
 		// Append excluded non-extension words (full list)
 		var exWords []string
@@ -347,10 +396,10 @@ func (m model) View() string {
 
 	// Target description (aligned)
 	var targetDesc string
-	if m.onlyType != "" {
-		targetDesc = "only ." + m.onlyType
+	if m.onlyType != "" || len(m.args.Types) > 0 {
+		targetDesc = "only " + config.DescribeTypes(typeSelection(m.args))
 	} else {
-		targetDesc = config.GetFileTypeDescription(m.includeCode)
+		targetDesc = config.DescribeTypes(typeSelection(m.args))
 	}
 	targetPrefix := "📁 Target:    "
 	targetStyled := lipgloss.NewStyle().Foreground(lipgloss.Color("75"))
@@ -374,10 +423,23 @@ func (m model) View() string {
 		}
 		suffix = "  🚫 " + strings.Join(shown, ", ")
 	}
+	// List explicit search roots so the header says where we are looking
+	if len(m.roots) > 0 {
+		suffix += "  📂 " + strings.Join(m.roots, ", ")
+	}
 	headerLines = append(headerLines, targetStyled.Render(wrapTextWithIndent(targetPrefix, targetDesc+suffix, width-4)))
 
+	// Email header filters, when given
+	if label := emailFilterLabel(m.args); label != "" {
+		headerLines = append(headerLines, targetStyled.Render(wrapTextWithIndent("📧 Emails:    ", label, width-4)))
+	}
+	// Front matter filters, when given
+	if len(m.args.Meta) > 0 {
+		headerLines = append(headerLines, targetStyled.Render(wrapTextWithIndent("🏷️ Meta:      ", strings.Join(m.args.Meta, " • "), width-4)))
+	}
+
 	// Engine line with cores + RAM/CPU live (aligned)
-	engineContent := fmt.Sprintf("Workers %d • Concurrent %d%s", m.filterWorkers, m.heavyConcurrency, m.memUsageText)
+	engineContent := fmt.Sprintf("Workers %d • Concurrent %d • Sorted by %s%s", m.filterWorkers, m.heavyConcurrency, m.sortKey, m.memUsageText)
 	enginePrefix := "⚙️ Engine:    "
 	engineStyled := lipgloss.NewStyle().Foreground(lipgloss.Color("#bb9af7"))
 	headerLines = append(headerLines, engineStyled.Render(wrapTextWithIndent(enginePrefix, engineContent, width-4)))
@@ -440,29 +502,48 @@ func (m model) View() string {
 
 	// Main content box
 	var boxContent string
-	if m.loading {
+	if m.loading && len(m.results) == 0 {
 		boxContent = "Searching..."
 	} else if len(m.results) == 0 {
 		boxContent = "No results found."
 	} else {
 		// Display current result
 		result := m.results[m.currentPage]
-		boxContent = fmt.Sprintf("File: %s (%s)\n\n", result.FilePath, formatFileSize(result.FileSize))
+		boxContent = fmt.Sprintf("File: %s (%s) • Score %.2f\n", search.AttachmentPath(result.FilePath, result.Attachment), formatFileSize(result.FileSize), result.Score)
+		if len(m.roots) > 0 {
+			boxContent += fmt.Sprintf("Root: %s\n", result.Root)
+		}
+		boxContent += "\n"
 
 		// Add email metadata if available
+		if result.Sender != "" {
+			boxContent += fmt.Sprintf("From: %s\n", result.Sender)
+		}
+		if len(result.Recipients) > 0 {
+			boxContent += fmt.Sprintf("To: %s\n", strings.Join(result.Recipients, ", "))
+		}
 		if result.EmailSubject != "" {
 			boxContent += fmt.Sprintf("Subject: %s\n", result.EmailSubject)
 		}
 		if result.EmailDate != "" {
 			boxContent += fmt.Sprintf("Date: %s\n", result.EmailDate)
 		}
-		if result.EmailSubject != "" || result.EmailDate != "" {
+		if result.Sender != "" || len(result.Recipients) > 0 || result.EmailSubject != "" || result.EmailDate != "" {
 			boxContent += "\n"
 		}
 
+		// Markdown front matter
+		if len(result.Meta) > 0 {
+			boxContent += fmt.Sprintf("Meta: %s\n\n", formatFrontMatter(result.Meta))
+		}
+
 		// Add excerpts (single wrapped line with colored label)
 		for i, excerpt := range result.Excerpts {
-			label := subHeaderStyle.Render(fmt.Sprintf("Excerpt %d: ", i+1))
+			header := fmt.Sprintf("Excerpt %d: ", i+1)
+			if result.Section != "" {
+				header = fmt.Sprintf("Excerpt %d (%s): ", i+1, result.Section)
+			}
+			label := subHeaderStyle.Render(header)
 			innerWidth := (width - 4) - 6
 			if innerWidth < 10 {
 				innerWidth = 10
@@ -479,8 +560,7 @@ func (m model) View() string {
 					// Find missing terms (plural-aware whole-word)
 					missing := make([]string, 0, len(m.searchWords))
 					for _, term := range m.searchWords {
-						pat := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(term))
-						re := regexp.MustCompile(pat)
+						re := search.TermMatcher(term, m.args.FoldDiacritics)
 						if !re.MatchString(excerpt) {
 							missing = append(missing, term)
 						}
@@ -493,8 +573,7 @@ func (m model) View() string {
 							if len(extra) >= budget {
 								break
 							}
-							pat := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(term))
-							re := regexp.MustCompile(pat)
+							re := search.TermMatcher(term, m.args.FoldDiacritics)
 							loc := re.FindStringIndex(result.CleanContent)
 							if loc != nil {
 								start := loc[0] - 120
@@ -513,7 +592,7 @@ func (m model) View() string {
 							}
 						}
 						if extra != "" {
-							extra = search.HighlightTerms(extra, m.searchWords)
+							extra = search.HighlightTerms(extra, m.searchWords, m.args.FoldDiacritics)
 							excerpt = excerpt + "\n" + extra
 						}
 					}
@@ -568,7 +647,7 @@ func (m model) View() string {
 
 	// Non-scrolling bottom status (found count + buttons)
 	var bottomStatus string
-	if !m.loading && len(m.results) > 0 {
+	if len(m.results) > 0 {
 		// Inline highlighted buttons (no border boxes)
 		yesSel := lipgloss.NewStyle().
 			Bold(true).
@@ -596,7 +675,12 @@ func (m model) View() string {
 			noBtn = noUn.Render("[ No ]")
 		}
 
-		cont := infoStyle.Render(fmt.Sprintf("Result [ %d / %d ] -- Continue?  ", m.currentPage+1, len(m.results))) + yesBtn + "      " + noBtn
+		count := fmt.Sprintf("%d", len(m.results))
+		if m.loading {
+			// More results may still arrive
+			count += "+"
+		}
+		cont := infoStyle.Render(fmt.Sprintf("Result [ %d / %s ] -- Continue?  ", m.currentPage+1, count)) + yesBtn + "      " + noBtn
 		bottomStatus = cont
 	}
 
@@ -622,24 +706,7 @@ func (m model) View() string {
 // Background search command (now exposed on model)
 func (m model) runSearch() tea.Cmd {
 	// Prepare engine and wire progress callback
-	fileTypes := config.BuildRipgrepFileTypes(m.includeCode)
-	if m.onlyType != "" {
-		fileTypes = []string{"-g", "*." + m.onlyType}
-	}
-	se := search.NewSearchEngineWithWorkers(
-		m.searchWords,
-		m.excludeWords,
-		fileTypes,
-		m.includeCode,
-		m.heavyConcurrency,
-		m.fileTimeoutBinary,
-		m.filterWorkers,
-	)
-	se.Silent = true
-	// Override default proximity window if provided
-	if m.distance > 0 {
-		se.Distance = m.distance
-	}
+	se := newSearchEngine(m.args)
 	// Stream progress from the engine to the TUI header
 	se.OnProgress = func(stage string, processed, total int, path string) {
 		ps, sk, tr := se.GetPDFStatsDetailed()
@@ -681,6 +748,14 @@ func (m model) runSearch() tea.Cmd {
 		}
 	}
 
+	// Stream each result to the TUI as soon as it is built
+	se.OnResult = func(r search.SearchResult) {
+		select {
+		case resultChan <- r:
+		case <-m.ctx.Done():
+		}
+	}
+
 	// Provide excerpt budget callback based on inner content width
 	search.ExcerptCharBudget = func() int {
 		// Prefer exact dimensions captured during View()
@@ -715,7 +790,7 @@ func (m model) runSearch() tea.Cmd {
 		func() tea.Msg { return progressMsg{Stage: "Discovery", Count: 0, Total: total, Path: ""} },
 		func() tea.Msg {
 
-			results, _ := se.Execute()
+			results, _ := se.Execute(m.ctx)
 			ps, sk, tr := se.GetPDFStatsDetailed()
 			return searchResultMsg{
 				results:      results,
@@ -728,6 +803,19 @@ func (m model) runSearch() tea.Cmd {
 	)
 }
 
+// queryGroupLabels renders each query group for the header: a term, or "(a OR b)" for an OR group
+func queryGroupLabels(q *search.Query) []string {
+	labels := make([]string, 0, len(q.Groups))
+	for _, g := range q.Groups {
+		if len(g) == 1 {
+			labels = append(labels, g[0])
+			continue
+		}
+		labels = append(labels, "("+strings.Join(g, " OR ")+")")
+	}
+	return labels
+}
+
 func renderSearchTerms(searchWords, excludeWords []string, width int) string {
 	var terms []string
 	for _, w := range searchWords {
@@ -788,6 +876,13 @@ func (m model) memUsageTick() tea.Cmd {
 	})
 }
 
+// waitForResult blocks until the engine streams the next result
+func waitForResult() tea.Cmd {
+	return func() tea.Msg {
+		return resultFoundMsg{result: <-resultChan}
+	}
+}
+
 func pollProgress() tea.Cmd {
 	return tea.Tick(time.Millisecond*100, func(time.Time) tea.Msg {
 		// Always trigger a poll tick; Update will drain and coalesce newest progress message
@@ -862,8 +957,33 @@ type searchResultMsg struct {
 	pdfTruncated int64
 }
 
+type resultFoundMsg struct {
+	result search.SearchResult
+}
+
 type memUsageMsg struct {
 	Text string
 }
 
 type progressTick struct{}
+
+// emailFilterLabel describes the header filters in args for the header ("" when none)
+func emailFilterLabel(args *Arguments) string {
+	var parts []string
+	if args.From != "" {
+		parts = append(parts, "from "+args.From)
+	}
+	if args.To != "" {
+		parts = append(parts, "to "+args.To)
+	}
+	if args.Subject != "" {
+		parts = append(parts, "subject "+args.Subject)
+	}
+	if args.After != "" {
+		parts = append(parts, "after "+args.After)
+	}
+	if args.Before != "" {
+		parts = append(parts, "before "+args.Before)
+	}
+	return strings.Join(parts, " • ")
+}
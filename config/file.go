@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LocalFileName is the per-directory config file, read from the current directory or the
+// nearest parent that has one
+const LocalFileName = ".garp.toml"
+
+// Settings are the search defaults a config file or a profile can set. Unset fields (nil
+// pointers, empty lists) leave the value below them in place: built-in defaults, then the
+// user config, then .garp.toml, then the selected profile, then command-line flags.
+type Settings struct {
+	Roots            []string `toml:"roots,omitempty"`
+	Types            []string `toml:"types,omitempty"`    // search only these groups or extensions (like --type)
+	TypeAdd          []string `toml:"type_add,omitempty"` // "NAME:GLOB[,GLOB...]", like --type-add
+	TypeMap          []string `toml:"type_map,omitempty"` // "EXT=FORMAT", like --type-map
+	Code             *bool    `toml:"code,omitempty"`
+	IncludeSheets    *bool    `toml:"include_sheets,omitempty"`
+	IncludeSlides    *bool    `toml:"include_slides,omitempty"`
+	NoArchives       *bool    `toml:"no_archives,omitempty"`
+	Distance         *int     `toml:"distance,omitempty"`
+	Workers          *int     `toml:"workers,omitempty"`
+	HeavyConcurrency *int     `toml:"heavy_concurrency,omitempty"` // 0: derived from workers and RAM
+	FileTimeout      *int     `toml:"file_timeout_binary,omitempty"`
+	SmartForms       *bool    `toml:"smart_forms,omitempty"`
+	FoldDiacritics   *bool    `toml:"fold_diacritics,omitempty"`
+	DocExtras        *bool    `toml:"doc_extras,omitempty"`
+	NoIndex          *bool    `toml:"no_index,omitempty"`
+	Hidden           *bool    `toml:"hidden,omitempty"`
+	NoIgnore         *bool    `toml:"no_ignore,omitempty"`
+	Exclude          []string `toml:"exclude,omitempty"` // like --not: ".ext" excludes a type, others a word
+	ExcludeDirs      []string `toml:"exclude_dirs,omitempty"`
+	IncludeDirs      []string `toml:"include_dirs,omitempty"`
+}
+
+// merge overlays the fields set in o onto s; type definitions and mappings add up
+func (s *Settings) merge(o Settings) {
+	s.TypeAdd = append(s.TypeAdd, o.TypeAdd...)
+	s.TypeMap = append(s.TypeMap, o.TypeMap...)
+	setList(&s.Roots, o.Roots)
+	setList(&s.Types, o.Types)
+	setList(&s.Exclude, o.Exclude)
+	setList(&s.ExcludeDirs, o.ExcludeDirs)
+	setList(&s.IncludeDirs, o.IncludeDirs)
+	for _, p := range []struct{ dst, src **bool }{
+		{&s.Code, &o.Code}, {&s.IncludeSheets, &o.IncludeSheets}, {&s.IncludeSlides, &o.IncludeSlides},
+		{&s.NoArchives, &o.NoArchives}, {&s.SmartForms, &o.SmartForms}, {&s.FoldDiacritics, &o.FoldDiacritics},
+		{&s.DocExtras, &o.DocExtras}, {&s.NoIndex, &o.NoIndex}, {&s.Hidden, &o.Hidden}, {&s.NoIgnore, &o.NoIgnore},
+	} {
+		if *p.src != nil {
+			*p.dst = *p.src
+		}
+	}
+	for _, p := range []struct{ dst, src **int }{
+		{&s.Distance, &o.Distance}, {&s.Workers, &o.Workers},
+		{&s.HeavyConcurrency, &o.HeavyConcurrency}, {&s.FileTimeout, &o.FileTimeout},
+	} {
+		if *p.src != nil {
+			*p.dst = *p.src
+		}
+	}
+}
+
+// FileTypes replaces the built-in extension lists ([filetypes] in a config file)
+type FileTypes struct {
+	Documents []string `toml:"documents,omitempty"`
+	Code      []string `toml:"code,omitempty"`
+}
+
+// Limits are the size and time caps of reading and extraction ([limits] in a config file)
+type Limits struct {
+	PDFMaxPages     int   `toml:"pdf_max_pages"`      // pages of text read from a PDF
+	PDFMaxTextBytes int   `toml:"pdf_max_text_bytes"` // text kept from a PDF
+	PDFTimeoutMs    int   `toml:"pdf_timeout_ms"`     // wall-clock budget of one PDF extraction
+	LargeFileBytes  int64 `toml:"large_file_bytes"`   // files above this size are read in part ...
+	LargeFileRead   int64 `toml:"large_file_read"`    // ... up to this many bytes
+	MediumFileBytes int64 `toml:"medium_file_bytes"`  // files above this size (and below LargeFileBytes) ...
+	MediumFileRead  int64 `toml:"medium_file_read"`   // ... up to this many bytes
+}
+
+// DefaultLimits are the built-in caps
+var DefaultLimits = Limits{
+	PDFMaxPages:     200,
+	PDFMaxTextBytes: 128 * 1024,
+	PDFTimeoutMs:    250,
+	LargeFileBytes:  50 * 1024 * 1024,
+	LargeFileRead:   10 * 1024 * 1024,
+	MediumFileBytes: 10 * 1024 * 1024,
+	MediumFileRead:  5 * 1024 * 1024,
+}
+
+// ActiveLimits are the caps in effect (DefaultLimits unless a config file changes them)
+var ActiveLimits = DefaultLimits
+
+// merge overlays the non-zero caps of o onto l
+func (l *Limits) merge(o Limits) {
+	for _, p := range []struct{ dst, src *int }{
+		{&l.PDFMaxPages, &o.PDFMaxPages}, {&l.PDFMaxTextBytes, &o.PDFMaxTextBytes}, {&l.PDFTimeoutMs, &o.PDFTimeoutMs},
+	} {
+		if *p.src > 0 {
+			*p.dst = *p.src
+		}
+	}
+	for _, p := range []struct{ dst, src *int64 }{
+		{&l.LargeFileBytes, &o.LargeFileBytes}, {&l.LargeFileRead, &o.LargeFileRead},
+		{&l.MediumFileBytes, &o.MediumFileBytes}, {&l.MediumFileRead, &o.MediumFileRead},
+	} {
+		if *p.src > 0 {
+			*p.dst = *p.src
+		}
+	}
+}
+
+// Config is the content of the config files: top-level search defaults, the global
+// tunables and named profiles ([profile.NAME]), selected with -P NAME.
+type Config struct {
+	Settings
+	SkipDirectories []string            `toml:"skip_directories,omitempty"`
+	FileTypes       FileTypes           `toml:"filetypes"`
+	Limits          Limits              `toml:"limits"`
+	Profiles        map[string]Settings `toml:"profile,omitempty"`
+
+	// Files lists the config files read, lowest precedence first
+	Files []string `toml:"-"`
+}
+
+// UserConfigPath returns the user config file: $XDG_CONFIG_HOME/garp/config.toml,
+// usually ~/.config/garp/config.toml
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "garp", "config.toml"), nil
+}
+
+// LocalConfigPath returns the .garp.toml of dir or of its nearest parent that has one
+// ("" when there is none)
+func LocalConfigPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, LocalFileName)
+		if st, err := os.Stat(path); err == nil && !st.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load reads the user config and then the .garp.toml found from dir; settings of the
+// latter override the former, and a profile defined in both is merged field by field.
+// Missing files are skipped. Unknown keys are errors, so typos do not go unnoticed.
+func Load(dir string) (*Config, error) {
+	cfg := &Config{Limits: DefaultLimits}
+	var paths []string
+	if path, err := UserConfigPath(); err == nil {
+		paths = append(paths, path)
+	}
+	if path := LocalConfigPath(dir); path != "" && !slices.Contains(paths, path) {
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		var file Config
+		md, err := toml.DecodeFile(path, &file)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return nil, fmt.Errorf("config %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+		cfg.Settings.merge(file.Settings)
+		setList(&cfg.SkipDirectories, file.SkipDirectories)
+		setList(&cfg.FileTypes.Documents, file.FileTypes.Documents)
+		setList(&cfg.FileTypes.Code, file.FileTypes.Code)
+		cfg.Limits.merge(file.Limits)
+		for name, p := range file.Profiles {
+			if cfg.Profiles == nil {
+				cfg.Profiles = make(map[string]Settings)
+			}
+			merged := cfg.Profiles[name]
+			merged.merge(p)
+			cfg.Profiles[name] = merged
+		}
+		cfg.Files = append(cfg.Files, path)
+	}
+	return cfg, nil
+}
+
+// Profile returns the settings of the named profile
+func (c *Config) Profile(name string) (Settings, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		if len(c.Profiles) == 0 {
+			return Settings{}, fmt.Errorf("unknown profile %q (no profiles are defined)", name)
+		}
+		return Settings{}, fmt.Errorf("unknown profile %q (defined: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	return p, nil
+}
+
+// ProfileNames returns the defined profile names, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply makes the global tunables of the config (file types, skipped directories and
+// limits) the ones in effect
+func (c *Config) Apply() {
+	if len(c.FileTypes.Documents) > 0 {
+		DocumentTypes = normalizeTypes(c.FileTypes.Documents)
+	}
+	if len(c.FileTypes.Code) > 0 {
+		CodeTypes = normalizeTypes(c.FileTypes.Code)
+	}
+	if len(c.SkipDirectories) > 0 {
+		SkipDirectories = c.SkipDirectories
+	}
+	ActiveLimits = c.Limits
+}
+
+// normalizeTypes lowercases extensions and drops leading dots
+func normalizeTypes(types []string) []string {
+	out := make([]string, 0, len(types))
+	for _, t := range types {
+		if t = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(t)), "."); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// setList replaces *dst with src when src is set
+func setList(dst *[]string, src []string) {
+	if len(src) > 0 {
+		*dst = src
+	}
+}
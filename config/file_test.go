@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeConfigs points the user config at home and writes the given files, relative to
+// root; it returns root
+func writeConfigs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "home"))
+	for name, text := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLoad(t *testing.T) {
+	root := writeConfigs(t, map[string]string{
+		"home/garp/config.toml": `
+distance = 3
+type_add = ["notes:*.notes"]
+
+[limits]
+pdf_max_pages = 20
+large_file_read = 1024
+
+[profile.legal]
+types = ["pdf"]
+distance = 8
+`,
+		"project/.garp.toml": `
+workers = 2
+type_add = ["memo:*.memo"]
+
+[limits]
+pdf_timeout_ms = 900
+
+[profile.legal]
+fold_diacritics = true
+`,
+	})
+	cfg, err := Load(filepath.Join(root, "project", "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Files) != 2 {
+		t.Fatalf("Files = %q, want the user config and .garp.toml", cfg.Files)
+	}
+	if cfg.Distance == nil || *cfg.Distance != 3 || cfg.Workers == nil || *cfg.Workers != 2 {
+		t.Errorf("distance = %v, workers = %v; want 3 and 2", cfg.Distance, cfg.Workers)
+	}
+	if want := []string{"notes:*.notes", "memo:*.memo"}; !slices.Equal(cfg.TypeAdd, want) {
+		t.Errorf("TypeAdd = %q, want %q", cfg.TypeAdd, want)
+	}
+
+	// A profile defined in both files is merged field by field
+	p, err := cfg.Profile("legal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p.Types, []string{"pdf"}) || p.Distance == nil || *p.Distance != 8 || p.FoldDiacritics == nil || !*p.FoldDiacritics {
+		t.Errorf("profile legal = %+v", p)
+	}
+	s := cfg.Settings
+	s.merge(p)
+	if *s.Distance != 8 || *s.Workers != 2 {
+		t.Errorf("settings with the profile: distance %d, workers %d; want 8 and 2", *s.Distance, *s.Workers)
+	}
+	if _, err := cfg.Profile("other"); err == nil || !strings.Contains(err.Error(), "legal") {
+		t.Errorf("Profile(other) = %v, want an error listing legal", err)
+	}
+
+	// Unset caps keep their defaults
+	want := DefaultLimits
+	want.PDFMaxPages = 20
+	want.PDFTimeoutMs = 900
+	want.LargeFileRead = 1024
+	if cfg.Limits != want {
+		t.Errorf("Limits = %+v, want %+v", cfg.Limits, want)
+	}
+	defer func() { ActiveLimits = DefaultLimits }()
+	cfg.Apply()
+	if ActiveLimits != want {
+		t.Errorf("ActiveLimits after Apply = %+v, want %+v", ActiveLimits, want)
+	}
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name, text, key string
+	}{
+		{"top level", "distnace = 3\n", "distnace"},
+		{"limits", "[limits]\npdf_pages = 3\n", "limits.pdf_pages"},
+		{"profile", "[profile.x]\nworkres = 1\n", "profile.x.workres"},
+	}
+	for _, tt := range tests {
+		root := writeConfigs(t, map[string]string{".garp.toml": tt.text})
+		_, err := Load(root)
+		if err == nil || !strings.Contains(err.Error(), tt.key) {
+			t.Errorf("%s: Load = %v, want an error naming %s", tt.name, err, tt.key)
+		}
+	}
+}
+
+func TestLoadNoFiles(t *testing.T) {
+	root := writeConfigs(t, nil)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Files) != 0 || cfg.Limits != DefaultLimits || len(cfg.Profiles) != 0 {
+		t.Errorf("Load without files = %+v, want the defaults", cfg)
+	}
+	if _, err := cfg.Profile("legal"); err == nil {
+		t.Error("Profile without profiles: want an error")
+	}
+}
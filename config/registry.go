@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TypeGroup is a named set of file globs, selected with --type NAME
+type TypeGroup struct {
+	Name  string
+	Globs []string // "*.org", "*.txt.gz", ...
+}
+
+// TypeAliases map an extension to the format whose extractor reads it ("htm" → "html").
+// --type-map EXT=FORMAT and type_map in a config file add to them.
+var TypeAliases = map[string]string{
+	"htm":   "html",
+	"xhtml": "html",
+	"shtml": "html",
+}
+
+// builtinTypeNames are the built-in groups, in the order they are described
+var builtinTypeNames = []string{"documents", "code", "sheets", "slides", "archives"}
+
+// addedGlobs are the globs --type-add added to each group (built-in or user-defined), and
+// addedNames the user-defined group names in the order they were added
+var (
+	addedGlobs = make(map[string][]string)
+	addedNames []string
+)
+
+// builtinExtensions returns the extensions of a built-in group (nil for other names)
+func builtinExtensions(name string) []string {
+	switch name {
+	case "documents":
+		return DocumentTypes
+	case "code":
+		return CodeTypes
+	case "sheets":
+		return SheetTypes
+	case "slides":
+		return SlideTypes
+	case "archives":
+		return ArchiveTypes
+	}
+	return nil
+}
+
+// LookupType returns the named group: a built-in one with the globs added to it, or one
+// defined with --type-add
+func LookupType(name string) (TypeGroup, bool) {
+	name = strings.ToLower(name)
+	if !slices.Contains(builtinTypeNames, name) && !slices.Contains(addedNames, name) {
+		return TypeGroup{}, false
+	}
+	g := TypeGroup{Name: name}
+	for _, ext := range builtinExtensions(name) {
+		g.Globs = append(g.Globs, "*."+ext)
+	}
+	for _, glob := range addedGlobs[name] {
+		if !slices.Contains(g.Globs, glob) {
+			g.Globs = append(g.Globs, glob)
+		}
+	}
+	return g, true
+}
+
+// TypeGroups returns every group: the built-in ones, then those defined with --type-add
+func TypeGroups() []TypeGroup {
+	var groups []TypeGroup
+	for _, name := range slices.Concat(builtinTypeNames, addedNames) {
+		g, _ := LookupType(name)
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// AddType parses a --type-add spec, "NAME:GLOB[,GLOB...]", and adds the globs to the named
+// group, defining it when it is new. A bare extension ("org" or ".org") stands for "*.org".
+// Adding to "documents" or "code" makes the files searched by default (or with --code).
+func AddType(spec string) error {
+	name, list, ok := strings.Cut(spec, ":")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !ok || name == "" || strings.ContainsAny(name, "*?[./") {
+		return fmt.Errorf("invalid type %q (want NAME:GLOB[,GLOB...])", spec)
+	}
+	var globs []string
+	for _, glob := range strings.Split(list, ",") {
+		glob = strings.ToLower(strings.TrimSpace(glob))
+		if glob == "" {
+			continue
+		}
+		if !strings.ContainsAny(glob, "*?[") {
+			glob = "*." + strings.TrimPrefix(glob, ".")
+		}
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %q in type %q", glob, name)
+		}
+		globs = append(globs, glob)
+	}
+	if len(globs) == 0 {
+		return fmt.Errorf("type %q has no globs", name)
+	}
+	if !slices.Contains(builtinTypeNames, name) && !slices.Contains(addedNames, name) {
+		addedNames = append(addedNames, name)
+	}
+	addedGlobs[name] = append(addedGlobs[name], globs...)
+	return nil
+}
+
+// MapType parses a --type-map spec, "EXT=FORMAT", so files with the extension are read by
+// the extractor of FORMAT, a document, code, spreadsheet or presentation type. An extension
+// no group selects yet is added to the group of FORMAT, so it is searched along with it.
+func MapType(spec string) error {
+	ext, format, ok := strings.Cut(spec, "=")
+	ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+	format = FormatOf(strings.TrimSpace(format))
+	if !ok || ext == "" || format == "" || strings.ContainsAny(ext, "*?[/") {
+		return fmt.Errorf("invalid type mapping %q (want EXT=FORMAT)", spec)
+	}
+	group := ""
+	for _, name := range builtinTypeNames[:4] {
+		if slices.Contains(builtinExtensions(name), format) {
+			group = name
+			break
+		}
+	}
+	if group == "" {
+		return fmt.Errorf("type mapping %q: unknown format %q", spec, format)
+	}
+	if ext != format {
+		TypeAliases[ext] = format
+	}
+	for _, g := range TypeGroups() {
+		if slices.Contains(g.Globs, "*."+ext) {
+			return nil
+		}
+	}
+	addedGlobs[group] = append(addedGlobs[group], "*."+ext)
+	return nil
+}
+
+// FormatOf returns the format of an extension ("htm", ".HTM" → "html"): the extension
+// itself, lowercased and without its dot, unless TypeAliases maps it
+func FormatOf(ext string) string {
+	ext = strings.TrimPrefix(strings.ToLower(ext), ".")
+	if format, ok := TypeAliases[ext]; ok {
+		return format
+	}
+	return ext
+}
+
+// DescribeTypes describes a type selection for the TUI target line: each group with its
+// extensions ("documents (txt, md, ...)"), other entries as extensions (".pdf")
+func DescribeTypes(names []string) string {
+	var parts []string
+	for _, name := range names {
+		g, ok := LookupType(name)
+		if !ok {
+			parts = append(parts, "."+strings.TrimPrefix(strings.ToLower(name), "."))
+			continue
+		}
+		shown := make([]string, len(g.Globs))
+		for i, glob := range g.Globs {
+			shown[i] = strings.TrimPrefix(glob, "*.")
+			if strings.ContainsAny(shown[i], "*?[.") {
+				shown[i] = glob
+			}
+		}
+		parts = append(parts, g.Name+" ("+strings.Join(shown, ", ")+")")
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// resetTypes restores the built-in groups and aliases when the test ends
+func resetTypes(t *testing.T) {
+	t.Helper()
+	aliases := maps.Clone(TypeAliases)
+	t.Cleanup(func() {
+		TypeAliases = aliases
+		addedGlobs = make(map[string][]string)
+		addedNames = nil
+	})
+}
+
+func TestAddType(t *testing.T) {
+	resetTypes(t)
+	tests := []struct {
+		spec  string
+		group string
+		globs []string // the last globs of the group; nil when the spec is invalid
+	}{
+		{"notes:*.org,*.note", "notes", []string{"*.org", "*.note"}},
+		{"Notes: txt , .Memo,", "notes", []string{"*.org", "*.note", "*.txt", "*.memo"}}, // bare extensions, case and spaces
+		{"logs:app-*.log.gz", "logs", []string{"app-*.log.gz"}},
+		{"documents:*.adoc", "documents", []string{"*.asciidoc", "*.adoc"}}, // added after the built-in extensions
+		{"sheets:xlsx", "sheets", []string{"*.xlsx", "*.ods"}},              // no duplicates
+		{"notes", "", nil},
+		{":*.org", "", nil},
+		{"*.org:org", "", nil},
+		{"my.notes:org", "", nil},
+		{"notes:", "", nil},
+		{"notes: , ", "", nil},
+		{"notes:[a", "", nil},
+	}
+	for _, tt := range tests {
+		err := AddType(tt.spec)
+		if tt.globs == nil {
+			if err == nil {
+				t.Errorf("AddType(%q): want an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("AddType(%q): %v", tt.spec, err)
+			continue
+		}
+		g, ok := LookupType(tt.group)
+		if !ok || len(g.Globs) < len(tt.globs) || !slices.Equal(g.Globs[len(g.Globs)-len(tt.globs):], tt.globs) {
+			t.Errorf("AddType(%q): %s globs %q, want them to end with %q", tt.spec, tt.group, g.Globs, tt.globs)
+		}
+	}
+
+	var names []string
+	for _, g := range TypeGroups() {
+		names = append(names, g.Name)
+	}
+	if want := []string{"documents", "code", "sheets", "slides", "archives", "notes", "logs"}; !slices.Equal(names, want) {
+		t.Errorf("TypeGroups = %q, want %q", names, want)
+	}
+	if _, ok := LookupType("NOTES"); !ok {
+		t.Error("LookupType(NOTES): names ignore case")
+	}
+	if _, ok := LookupType("pdf"); ok {
+		t.Error("LookupType(pdf): an extension is not a group")
+	}
+}
+
+func TestMapType(t *testing.T) {
+	resetTypes(t)
+	tests := []struct {
+		spec   string
+		ext    string
+		format string // "" when the spec is invalid
+		group  string // the group that selects the extension afterwards
+	}{
+		{"page=html", "page", "html", "documents"},
+		{".NOTE = md", "note", "md", "documents"},
+		{"tsx=ts", "tsx", "ts", "code"},
+		{"xlsm=xlsx", "xlsm", "xlsx", "sheets"},
+		{"web=htm", "web", "html", "documents"}, // an alias of an alias resolves to its format
+		{"json=json", "json", "json", "code"},   // no alias, nor a second glob
+		{"page", "", "", ""},
+		{"=html", "", "", ""},
+		{"page=", "", "", ""},
+		{"*.page=html", "", "", ""},
+		{"page=zip", "", "", ""}, // archives have no extractor of their own
+		{"page=nope", "", "", ""},
+	}
+	for _, tt := range tests {
+		err := MapType(tt.spec)
+		if tt.format == "" {
+			if err == nil {
+				t.Errorf("MapType(%q): want an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("MapType(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := FormatOf("." + strings.ToUpper(tt.ext)); got != tt.format {
+			t.Errorf("MapType(%q): FormatOf(%s) = %q, want %q", tt.spec, tt.ext, got, tt.format)
+		}
+		g, _ := LookupType(tt.group)
+		if n := strings.Count(strings.Join(g.Globs, " ")+" ", "*."+tt.ext+" "); n != 1 {
+			t.Errorf("MapType(%q): %s selects *.%s %d times, want once", tt.spec, tt.group, tt.ext, n)
+		}
+	}
+	if _, ok := TypeAliases["json"]; ok {
+		t.Error("json=json: want no alias")
+	}
+
+	// A mapped extension some group already selects stays in that group only
+	if err := AddType("notes:*.wiki"); err != nil {
+		t.Fatal(err)
+	}
+	if err := MapType("wiki=md"); err != nil {
+		t.Fatal(err)
+	}
+	if g, _ := LookupType("documents"); slices.Contains(g.Globs, "*.wiki") {
+		t.Error("wiki=md: *.wiki added to documents, though notes selects it")
+	}
+	if FormatOf("wiki") != "md" {
+		t.Errorf("FormatOf(wiki) = %q, want md", FormatOf("wiki"))
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		ext, want string
+	}{
+		{"htm", "html"},
+		{".XHTML", "html"},
+		{"shtml", "html"},
+		{".PDF", "pdf"},
+		{"md", "md"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := FormatOf(tt.ext); got != tt.want {
+			t.Errorf("FormatOf(%q) = %q, want %q", tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTypeList(t *testing.T) {
+	resetTypes(t)
+	if err := AddType("notes:*.org,report-*.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := MapType("page=html"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{"extensions", []string{"pdf", ".DOCX"}, []string{"-g", "*.pdf", "-g", "*.docx"}},
+		{"added group", []string{"notes"}, []string{"-g", "*.org", "-g", "report-*.txt"}},
+		{"built-in group", []string{"slides"}, []string{"-g", "*.pptx", "-g", "*.odp"}},
+		{"group and extension", []string{"Sheets", "csv"}, []string{"-g", "*.xlsx", "-g", "*.ods", "-g", "*.csv"}},
+		{"none", nil, nil},
+	}
+	for _, tt := range tests {
+		if got := BuildTypeList(tt.names); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// A mapped extension joins the documents searched by default
+	docs := BuildRipgrepFileTypes(false)
+	if !slices.Contains(docs, "*.page") || slices.Contains(docs, "*.go") {
+		t.Errorf("BuildRipgrepFileTypes(false) = %q, want *.page and no code", docs)
+	}
+	if code := BuildRipgrepFileTypes(true); !slices.Contains(code, "*.go") {
+		t.Errorf("BuildRipgrepFileTypes(true) = %q, want *.go", code)
+	}
+
+	want := "notes (org, report-*.txt) + .pdf"
+	if got := DescribeTypes([]string{"notes", ".PDF"}); got != want {
+		t.Errorf("DescribeTypes = %q, want %q", got, want)
+	}
+}
@@ -5,20 +5,34 @@ import (
 	"strings"
 )
 
-// DocumentTypes defines the file extensions for document files
+// DocumentTypes defines the file extensions for document files, searched by default
+// (spreadsheets and presentations are opt-in, see SheetTypes and SlideTypes)
 var DocumentTypes = []string{
-	"txt", "md", "html", "xml", "csv", "yaml", "yml",
+	"txt", "md", "log", "rtf",
+	"html", "htm", "xhtml", "shtml",
+	"xml", "csv", "yaml", "yml",
+	"cfg", "conf", "ini",
 	"eml", "mbox", "msg",
-	"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx",
-	"odt", "ods", "odp", "rtf",
-	"log", "cfg", "conf", "ini", "sh", "bat",
+	"pdf", "doc", "docx", "odt",
+	"epub", "fb2", "mobi",
+	"sh", "bat", "cmd",
+	"tex", "rst", "asciidoc",
 }
 
-// CodeTypes defines the file extensions for programming files
+// SheetTypes are the spreadsheet formats searched only with --include-sheets
+var SheetTypes = []string{"xlsx", "ods"}
+
+// SlideTypes are the presentation formats searched only with --include-slides
+var SlideTypes = []string{"pptx", "odp"}
+
+// ArchiveTypes are the archive formats whose entries are searched (unless --no-archives);
+// "gz" covers both .tar.gz bundles and single gzip-compressed files
+var ArchiveTypes = []string{"zip", "tar", "tgz", "gz"}
+
+// CodeTypes defines the file extensions for programming files, searched with --code
 var CodeTypes = []string{
-	"js", "ts", "sql", "py", "php", "java", "cpp", "c", "json",
-	"go", "rs", "rb", "cs", "swift", "kt", "scala", "clj",
-	"h", "hpp", "cc", "cxx", "pl", "r", "m", "mm",
+	"js", "ts", "sql", "py", "php", "java", "cpp", "c", "json", "ipynb",
+	"go", "rs", "rb", "cs", "swift", "kt", "scala",
 }
 
 // IsDocumentFile checks if a file extension is a document type
@@ -112,74 +126,44 @@ func IsHiddenFile(filename string) bool {
 	return strings.HasPrefix(filename, ".")
 }
 
-// ShouldSkipDirectory determines if a directory should be skipped during traversal
-func ShouldSkipDirectory(dirName string) bool {
-	skipDirs := map[string]bool{
-		".git":          true,
-		".svn":          true,
-		".hg":           true,
-		"node_modules":  true,
-		".vscode":       true,
-		".idea":         true,
-		"__pycache__":   true,
-		".pytest_cache": true,
-		"vendor":        true,
-		"target":        true,
-		"build":         true,
-		"dist":          true,
-		".next":         true,
-		".nuxt":         true,
-		"coverage":      true,
-		"tmp":           true,
-		"temp":          true,
-		".DS_Store":     true,
-	}
-
-	return skipDirs[dirName] || strings.HasPrefix(dirName, ".")
+// SkipDirectories are directory names never entered during traversal (VCS metadata,
+// dependencies, build output and caches) unless --no-ignore or --include-dir says otherwise
+var SkipDirectories = []string{
+	".git", ".svn", ".hg",
+	"node_modules", "vendor", "__pycache__", ".pytest_cache",
+	".vscode", ".idea",
+	"target", "build", "dist", ".next", ".nuxt", "coverage",
+	"tmp", "temp",
 }
 
-// GetFileTypeDescription returns a human-readable description of file types
-func GetFileTypeDescription(includeCode bool) string {
-	if includeCode {
-		return "documents (txt, md, html, xml, csv, yaml, yml, eml, mbox, msg, pdf, doc, docx, odt, rtf, log, cfg, conf, ini, sh, bat) + code files (go, js, ts, py, php, java, cpp, c, json, rs, rb, cs, swift, kt, scala)"
-	}
-	return "documents (txt, md, html, xml, csv, yaml, yml, eml, mbox, msg, pdf, doc, docx, odt, rtf, log, cfg, conf, ini, sh, bat)"
+// ShouldSkipDirectory reports whether a directory is in SkipDirectories
+func ShouldSkipDirectory(dirName string) bool {
+	return slices.Contains(SkipDirectories, dirName)
 }
 
-// BuildRipgrepFileTypes creates ripgrep file type arguments
+// BuildRipgrepFileTypes creates ripgrep file type arguments ("-g", "*.ext", ...) for the
+// "documents" group, plus "code" when includeCode is set
 func BuildRipgrepFileTypes(includeCode bool) []string {
-	// Use glob patterns for ALL document types
-	types := []string{
-		// Text files
-		"-g", "*.txt", "-g", "*.md", "-g", "*.log", "-g", "*.rtf",
-		// HTML/Web files - all variants
-		"-g", "*.html", "-g", "*.htm", "-g", "*.xhtml", "-g", "*.shtml",
-		// XML/Data files
-		"-g", "*.xml", "-g", "*.csv", "-g", "*.yaml", "-g", "*.yml",
-		// Config files
-		"-g", "*.cfg", "-g", "*.conf", "-g", "*.ini",
-		// Email files
-		"-g", "*.eml", "-g", "*.mbox", "-g", "*.msg",
-		// Office documents
-		"-g", "*.pdf", "-g", "*.doc", "-g", "*.docx",
-		"-g", "*.odt",
-		// Scripts
-		"-g", "*.sh", "-g", "*.bat", "-g", "*.cmd",
-		// Other text formats
-		"-g", "*.tex", "-g", "*.rst", "-g", "*.asciidoc",
+	if includeCode {
+		return BuildTypeList([]string{"documents", "code"})
 	}
+	return BuildTypeList([]string{"documents"})
+}
 
-	// Add code file patterns if requested
-	if includeCode {
-		codeGlobs := []string{
-			"-g", "*.js", "-g", "*.ts", "-g", "*.sql", "-g", "*.py",
-			"-g", "*.php", "-g", "*.java", "-g", "*.cpp", "-g", "*.c",
-			"-g", "*.json", "-g", "*.go", "-g", "*.rs", "-g", "*.rb",
-			"-g", "*.cs", "-g", "*.swift", "-g", "*.kt", "-g", "*.scala",
+// BuildTypeList creates ripgrep file type arguments for an explicit selection (--type,
+// --only, or the types of a profile): each entry is a group name (see LookupType) or an
+// extension
+func BuildTypeList(names []string) []string {
+	var types []string
+	for _, name := range names {
+		if g, ok := LookupType(name); ok {
+			for _, glob := range g.Globs {
+				types = append(types, "-g", glob)
+			}
+			continue
 		}
-		types = append(types, codeGlobs...)
+		types = append(types, "-g", "*."+strings.TrimPrefix(strings.ToLower(name), "."))
 	}
-
 	return types
 }
 
@@ -0,0 +1,248 @@
+package search
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// archiveSep separates an archive path from the path of an entry inside it in a virtual
+// result path ("bundle.zip!/docs/contract.docx"); nested archives repeat it.
+const archiveSep = "!/"
+
+// Caps that keep archive walking safe against zip bombs and pathological bundles.
+// Entry and byte budgets are shared by an archive and everything nested inside it.
+const (
+	maxArchiveDepth       = 3         // archives inside archives inside archives
+	maxArchiveEntries     = 10000     // entries visited per top-level archive
+	maxArchiveBytes       = 512 << 20 // decompressed bytes read per top-level archive
+	maxArchiveEntryBytes  = 10 << 20  // bytes read per document entry (as GetFileContent)
+	maxNestedArchiveBytes = 64 << 20  // bytes buffered for one nested archive
+)
+
+// errArchiveStop ends a walk early without reporting an error
+var errArchiveStop = errors.New("archive walk stopped")
+
+// errArchiveLimit reports that an archive hit its entry or byte budget
+var errArchiveLimit = errors.New("archive limits reached")
+
+// archiveEntry is one document read out of an archive: its decompressed size, raw text
+// and, for .eml/.msg entries, the message headers
+type archiveEntry struct {
+	Size int64
+	Text string
+	Meta EmailMeta
+}
+
+// isArchive reports whether path is an archive searched entry by entry
+// (.zip, .tar, .tgz, .tar.gz, or a single gzip-compressed file)
+func isArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tgz", ".gz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// archivePath returns the virtual path of entry inside the archive at outer
+func archivePath(outer, entry string) string {
+	return outer + archiveSep + strings.TrimPrefix(entry, "/")
+}
+
+// SplitArchivePath splits a virtual archive path into the archive file on disk and the
+// entry path inside it (which may itself cross nested archives). ok is false for ordinary paths.
+func SplitArchivePath(p string) (archive, entry string, ok bool) {
+	for i := 0; ; {
+		j := strings.Index(p[i:], archiveSep)
+		if j < 0 {
+			return p, "", false
+		}
+		if isArchive(p[:i+j]) {
+			return p[:i+j], p[i+j+len(archiveSep):], true
+		}
+		i += j + len(archiveSep)
+	}
+}
+
+// archiveSource is what the readers need: zip reads at offsets, tar and gzip stream
+type archiveSource interface {
+	io.Reader
+	io.ReaderAt
+}
+
+// archiveWalker visits the document entries of an archive and of archives nested in it,
+// within the package caps. want filters entries by name; fn receives each wanted entry's
+// virtual path and (capped) bytes and returns false to stop.
+type archiveWalker struct {
+	ctx     context.Context
+	want    func(name string) bool
+	fn      func(vpath string, data []byte) bool
+	entries int
+	bytes   int64
+}
+
+// walkFile walks the archive at filePath. Reaching the caps ends the walk with errArchiveLimit.
+func (w *archiveWalker) walkFile(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	err = w.walk(filePath, f, st.Size(), 1)
+	if errors.Is(err, errArchiveStop) {
+		return nil
+	}
+	return err
+}
+
+// walk dispatches on the archive type of vpath
+func (w *archiveWalker) walk(vpath string, src archiveSource, size int64, depth int) error {
+	lower := strings.ToLower(vpath)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		zr, err := zip.NewReader(src, size)
+		if err != nil {
+			return nil // not a readable zip; nothing to search
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			err = w.entry(vpath, f.Name, rc, depth)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar.gz"):
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil
+		}
+		defer gz.Close()
+		return w.walkTar(vpath, gz, depth)
+	case strings.HasSuffix(lower, ".tar"):
+		return w.walkTar(vpath, src, depth)
+	case strings.HasSuffix(lower, ".gz"):
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil
+		}
+		defer gz.Close()
+		name := gz.Name
+		if name == "" {
+			name = strings.TrimSuffix(path.Base(lower), ".gz")
+		}
+		return w.entry(vpath, path.Base(name), gz, depth)
+	}
+	return nil
+}
+
+func (w *archiveWalker) walkTar(vpath string, r io.Reader, depth int) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return nil // truncated or corrupt: keep what was searched
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := w.entry(vpath, hdr.Name, tr, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// entry handles one regular entry: nested archives are walked (up to maxArchiveDepth),
+// wanted documents are read and passed to fn.
+func (w *archiveWalker) entry(outer, name string, r io.Reader, depth int) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	if w.entries >= maxArchiveEntries || w.bytes >= maxArchiveBytes {
+		return errArchiveLimit
+	}
+	w.entries++
+	vpath := archivePath(outer, name)
+
+	if isArchive(name) {
+		if depth >= maxArchiveDepth {
+			return nil
+		}
+		data, err := w.read(r, maxNestedArchiveBytes)
+		if err != nil {
+			return nil
+		}
+		return w.walk(vpath, bytes.NewReader(data), int64(len(data)), depth+1)
+	}
+	if w.want != nil && !w.want(name) {
+		return nil
+	}
+	data, err := w.read(r, maxArchiveEntryBytes)
+	if err != nil {
+		return nil
+	}
+	if !w.fn(vpath, data) {
+		return errArchiveStop
+	}
+	return nil
+}
+
+// read reads up to limit bytes of r, charging them to the walk's byte budget
+func (w *archiveWalker) read(r io.Reader, limit int64) ([]byte, error) {
+	if rem := maxArchiveBytes - w.bytes; rem < limit {
+		limit = rem
+	}
+	data, err := io.ReadAll(io.LimitReader(r, limit))
+	w.bytes += int64(len(data))
+	return data, err
+}
+
+// loadArchiveEntry re-reads the entry at virtual path vpath
+func loadArchiveEntry(ctx context.Context, vpath string) ([]byte, error) {
+	archive, _, ok := SplitArchivePath(vpath)
+	if !ok {
+		return nil, fmt.Errorf("%s is not inside an archive", vpath)
+	}
+	var data []byte
+	w := &archiveWalker{
+		ctx: ctx,
+		fn: func(p string, b []byte) bool {
+			if p == vpath {
+				data = b
+				return false
+			}
+			return true
+		},
+	}
+	if err := w.walkFile(archive); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%s not found in %s", vpath, archive)
+	}
+	return data, nil
+}
@@ -0,0 +1,159 @@
+package search
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// tarFile returns a tar archive holding the given entries, gzip-compressed when gz is set
+func tarFile(t *testing.T, gz bool, entries ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i+1 < len(entries); i += 2 {
+		if err := tw.WriteHeader(&tar.Header{Name: entries[i], Mode: 0o644, Size: int64(len(entries[i+1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(entries[i+1]))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !gz {
+		return buf.Bytes()
+	}
+	var out bytes.Buffer
+	zw := gzip.NewWriter(&out)
+	zw.Write(buf.Bytes())
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// walkArchive returns the virtual paths, relative to dir, an archiveWalker visits in name
+func walkArchive(t *testing.T, dir, name string) ([]string, error) {
+	t.Helper()
+	var got []string
+	w := &archiveWalker{
+		ctx: context.Background(),
+		fn: func(vpath string, data []byte) bool {
+			rel, _ := filepath.Rel(dir, vpath)
+			got = append(got, filepath.ToSlash(rel))
+			return true
+		},
+	}
+	err := w.walkFile(filepath.Join(dir, name))
+	return got, err
+}
+
+func TestArchiveWalker(t *testing.T) {
+	dir := t.TempDir()
+	n4 := zipFile(t, "too-deep.txt", "x")
+	n3 := zipFile(t, "deep.txt", "x", "n4.zip", string(n4))
+	n2 := zipFile(t, "n3.zip", string(n3))
+	files := map[string][]byte{
+		"bundle.zip": zipFile(t,
+			"a.txt", "x",
+			"docs/b.md", "x",
+			"inner.tar", string(tarFile(t, false, "c.txt", "x")),
+			"inner.tar.gz", string(tarFile(t, true, "sub/d.txt", "x")),
+			"n2.zip", string(n2),
+		),
+		"plain.tar": tarFile(t, false, "e.txt", "x"),
+		"plain.tgz": tarFile(t, true, "f.txt", "x"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"bundle.zip", []string{
+			"bundle.zip!/a.txt",
+			"bundle.zip!/docs/b.md",
+			"bundle.zip!/inner.tar!/c.txt",
+			"bundle.zip!/inner.tar.gz!/sub/d.txt",
+			"bundle.zip!/n2.zip!/n3.zip!/deep.txt", // n4.zip is past maxArchiveDepth
+		}},
+		{"plain.tar", []string{"plain.tar!/e.txt"}},
+		{"plain.tgz", []string{"plain.tgz!/f.txt"}},
+	}
+	for _, tt := range tests {
+		got, err := walkArchive(t, dir, tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: visited %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	archive, entry, ok := SplitArchivePath(filepath.Join(dir, "bundle.zip!/n2.zip!/n3.zip!/deep.txt"))
+	if !ok || archive != filepath.Join(dir, "bundle.zip") || entry != "n2.zip!/n3.zip!/deep.txt" {
+		t.Errorf("SplitArchivePath = %q, %q, %v", archive, entry, ok)
+	}
+	if _, _, ok := SplitArchivePath(filepath.Join(dir, "a!/b.txt")); ok {
+		t.Error("SplitArchivePath: a!/ after a non-archive is not a virtual path")
+	}
+}
+
+func TestArchiveWalkerEntryCap(t *testing.T) {
+	dir := t.TempDir()
+	entries := make([]string, 0, 2*(maxArchiveEntries+1))
+	for i := range maxArchiveEntries + 1 {
+		entries = append(entries, fmt.Sprintf("f%05d.txt", i), "x")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "many.zip"), zipFile(t, entries...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := walkArchive(t, dir, "many.zip")
+	if !errors.Is(err, errArchiveLimit) || len(got) != maxArchiveEntries {
+		t.Errorf("walked %d entries, err %v; want %d and %v", len(got), err, maxArchiveEntries, errArchiveLimit)
+	}
+}
+
+func TestExecuteArchiveSelection(t *testing.T) {
+	dir := t.TempDir()
+	bundle := zipFile(t, "notes.txt", "the invoice is due", "readme.md", "invoice template", "data.log", "invoice 42")
+	if err := os.WriteFile(filepath.Join(dir, "bundle.zip"), bundle, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		types []string
+		want  []string
+	}{
+		{nil, []string{"bundle.zip!/data.log", "bundle.zip!/notes.txt", "bundle.zip!/readme.md"}}, // no selection: every entry
+		{[]string{"-g", "*.zip", "-g", "*.md"}, []string{"bundle.zip!/readme.md"}},
+		{[]string{"-g", "*.zip", "-g", "*.log"}, []string{"bundle.zip!/data.log"}},
+	}
+	for _, tt := range tests {
+		se := NewSearchEngine([]string{"invoice"}, nil, tt.types, false, 2, 5000)
+		se.Roots = []string{dir}
+		se.Silent = true
+		results, err := se.Execute(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range results {
+			rel, _ := filepath.Rel(dir, r.FullPath())
+			got = append(got, filepath.ToSlash(rel))
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("types %q: results %q, want %q", tt.types, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/richardlehane/mscfb"
+
+	"find-words/config"
+	"find-words/search/pdf"
+)
+
+// attachmentSep joins a message path and the name of the attachment a result came from
+// in displayed paths ("mail.eml › invoice.pdf")
+const attachmentSep = " › "
+
+// maxAttachmentBytes caps the size of one attachment. Larger documents are skipped, since
+// cutting a container (zip, PDF, OLE) breaks it; plain text is searched up to the cap.
+const maxAttachmentBytes = 10 << 20
+
+// TextPart is one searchable part of a message: the body (Name "") or an attachment.
+// E-books are split the same way into chapters; Section marks those parts, whose Name
+// is the chapter title rather than an attachment.
+type TextPart struct {
+	Name    string
+	Text    string
+	Section bool
+}
+
+// PartsExtractor is implemented by extractors for messages that carry attachments.
+// ExtractParts returns the body first, then one part per attachment with extractable text;
+// ExtractText returns the same parts joined, so filtering and the index see attachments too.
+type PartsExtractor interface {
+	Extractor
+	ExtractParts(ctx context.Context, data []byte) ([]TextPart, error)
+}
+
+// extractParts returns the parts of data: a PartsExtractor's own split, else the whole
+// extracted text as a single body part
+func extractParts(ctx context.Context, ex Extractor, data []byte) ([]TextPart, error) {
+	if pe, ok := ex.(PartsExtractor); ok {
+		return pe.ExtractParts(ctx, data)
+	}
+	text, err := extractTextContext(ctx, ex, data)
+	if err != nil {
+		return nil, err
+	}
+	return []TextPart{{Text: text}}, nil
+}
+
+// joinParts concatenates the text of parts, one paragraph each
+func joinParts(parts []TextPart) string {
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// AttachmentPath returns how a result inside an attachment is displayed: the message path
+// followed by the attachment name. Without an attachment it is just path.
+func AttachmentPath(path, attachment string) string {
+	if attachment == "" {
+		return path
+	}
+	return path + attachmentSep + attachment
+}
+
+// attachmentText extracts the text of one attachment named name. Formats with a registered
+// extractor go through it (so attached messages and documents are searched recursively),
+// PDFs use the bounded pdfcpu path when PDFs are enabled, and anything else counts only
+// when it is plain text. ok is false for attachments with nothing to search and for
+// documents over maxAttachmentBytes.
+func attachmentText(ctx context.Context, reg *ExtractorRegistry, name string, data []byte) (string, bool) {
+	if len(data) == 0 {
+		return "", false
+	}
+	oversized := len(data) > maxAttachmentBytes
+	ext := formatExt(name)
+	if reg != nil {
+		if ex, ok := reg.GetExtractor(ext); ok {
+			if oversized {
+				return "", false
+			}
+			text, err := extractTextContext(ctx, ex, data)
+			if err != nil {
+				return "", false
+			}
+			return text, strings.TrimSpace(text) != ""
+		}
+	}
+	if ext == ".pdf" {
+		if oversized {
+			return "", false
+		}
+		return attachmentPDFText(ctx, data)
+	}
+	if oversized {
+		// Cut at a character boundary so the text stays valid UTF-8
+		cut := maxAttachmentBytes
+		for cut > 0 && !utf8.RuneStart(data[cut]) {
+			cut--
+		}
+		data = data[:cut]
+	}
+	if IsBinaryFormat(name) || !utf8.Valid(data) || strings.IndexByte(string(data), 0) >= 0 {
+		return "", false
+	}
+	return string(data), true
+}
+
+// attachmentPDFText runs the capped pdfcpu extraction over an attached PDF via a temporary
+// file, under the same global PDF token as PDFs on disk. Skipped when PDFs are disabled.
+func attachmentPDFText(ctx context.Context, data []byte) (string, bool) {
+	if !enablePDFs {
+		return "", false
+	}
+	select {
+	case pdfSem <- struct{}{}:
+	case <-time.After(50 * time.Millisecond):
+		return "", false
+	}
+	defer func() { <-pdfSem }()
+
+	f, err := os.CreateTemp("", "garp-attachment-*.pdf")
+	if err != nil {
+		return "", false
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", false
+	}
+	text, _, err := pdf.ExtractAllTextCapped(ctx, f.Name(), config.ActiveLimits.PDFMaxPages, config.ActiveLimits.PDFMaxTextBytes, nil, 0, false)
+	if err != nil {
+		return "", false
+	}
+	return text, strings.TrimSpace(text) != ""
+}
+
+// mayHaveAttachments reports whether the message file at path may carry attachments, whose
+// text a scan of the raw bytes cannot see: a MIME part with a file name in .eml/.mbox, an
+// attachment storage in .msg (name tells the format). Unreadable files report true.
+func mayHaveAttachments(path, name string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+
+	if formatExt(name) == ".msg" {
+		cf, err := mscfb.New(f)
+		if err != nil {
+			return true
+		}
+		for ent, err := cf.Next(); err == nil; ent, err = cf.Next() {
+			if len(ent.Path) == 0 && strings.HasPrefix(ent.Name, msgAttachPrefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Content-Disposition filename= or Content-Type name=, in any case, across chunk borders
+	const chunkSize = 64 * 1024
+	needle := []byte("name=")
+	buf := make([]byte, len(needle)-1+chunkSize)
+	carry := 0
+	for {
+		n, err := f.Read(buf[carry:])
+		if n > 0 {
+			window := bytes.ToLower(buf[:carry+n])
+			if bytes.Contains(window, needle) {
+				return true
+			}
+			carry = min(len(needle)-1, carry+n)
+			copy(buf, window[len(window)-carry:])
+		}
+		if err == io.EOF {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// mimeMessage builds a multipart message with a plain-text body and one base64 attachment
+// per name
+func mimeMessage(body string, attachments map[string][]byte) string {
+	var b strings.Builder
+	b.WriteString("From: ann@example.com\r\nTo: bob@example.com\r\nSubject: Quarterly\r\n")
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=\"XX\"\r\n\r\n")
+	b.WriteString("--XX\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n" + body + "\r\n")
+	for _, name := range slices.Sorted(maps.Keys(attachments)) {
+		b.WriteString("--XX\r\nContent-Type: application/octet-stream; name=\"" + name + "\"\r\n")
+		b.WriteString("Content-Disposition: attachment; filename=\"" + name + "\"\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		b.WriteString(base64.StdEncoding.EncodeToString(attachments[name]) + "\r\n")
+	}
+	b.WriteString("--XX--\r\n")
+	return b.String()
+}
+
+func TestEMLExtractParts(t *testing.T) {
+	inner := mimeMessage("forwarded budget figures", map[string][]byte{"deep.txt": []byte("nested ledger")})
+	msg := mimeMessage("See the attached files.", map[string][]byte{
+		"notes.txt":   []byte("the invoice total is due"),
+		"memo.rtf":    []byte(`{\rtf1\ansi Wire transfer\par confirmed}`),
+		"fwd.eml":     []byte(inner),
+		"logo.png":    {0x89, 'P', 'N', 'G', 0, 0, 0, 0},
+		"binary.txt":  {'a', 0, 'b', 0xff},
+		"empty.txt":   nil,
+		"unnamed.xyz": []byte("\xff\xfe\x00"),
+	})
+	e := &EMLExtractor{Registry: NewExtractorRegistry()}
+	parts, err := e.ExtractParts(context.Background(), []byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string, len(parts))
+	for _, p := range parts {
+		got[p.Name] = p.Text
+	}
+	want := map[string]string{
+		"":          "See the attached files.",
+		"notes.txt": "the invoice total is due",
+		"memo.rtf":  "Wire transfer confirmed",
+		"fwd.eml":   "forwarded budget figures nested ledger",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parts = %q, want %q", got, want)
+	}
+	if parts[0].Name != "" {
+		t.Errorf("first part is %q, want the body", parts[0].Name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := e.ExtractParts(ctx, []byte(msg)); err == nil {
+		t.Error("ExtractParts with a cancelled context: want an error")
+	}
+}
+
+func TestAttachmentText(t *testing.T) {
+	reg := NewExtractorRegistry()
+	tests := []struct {
+		name string
+		data string
+		text string
+		ok   bool
+	}{
+		{"a.txt", "plain words", "plain words", true},
+		{"a.csv", "id,amount\n1,20", "id,amount\n1,20", true},
+		{"a.txt", "", "", false},
+		{"a.txt", "bad \xff utf-8", "", false},
+		{"a.bin", "nul\x00byte", "", false},
+		{"a.rtf", `{\rtf1 hello}`, "hello", true},
+		{"a.rtf", `{\rtf1 }`, "", false},
+	}
+	for _, tt := range tests {
+		text, ok := attachmentText(context.Background(), reg, tt.name, []byte(tt.data))
+		if ok != tt.ok || (ok && strings.TrimSpace(text) != tt.text) {
+			t.Errorf("attachmentText(%q, %q) = %q, %v; want %q, %v", tt.name, tt.data, text, ok, tt.text, tt.ok)
+		}
+	}
+}
+
+func TestAttachmentTextOversized(t *testing.T) {
+	reg := NewExtractorRegistry()
+	// A docx just over the cap: cutting it would lose the zip central directory
+	filler := strings.Repeat("filler ", maxAttachmentBytes/7+1)
+	docx := zipFile(t, "[Content_Types].xml", "<Types/>", "word/document.xml", "<w:document><w:body><w:p><w:r><w:t>"+filler+"</w:t></w:r></w:p></w:body></w:document>")
+	if len(docx) > maxAttachmentBytes {
+		t.Fatalf("docx compressed to %d bytes; want it under the cap for this check", len(docx))
+	}
+	if _, ok := attachmentText(context.Background(), reg, "small.docx", docx); !ok {
+		t.Error("a docx under the cap must be extracted")
+	}
+	big := append(slices.Clone(docx), make([]byte, maxAttachmentBytes)...)
+	if text, ok := attachmentText(context.Background(), reg, "big.docx", big); ok {
+		t.Errorf("an oversized docx must be skipped, got %d bytes of text", len(text))
+	}
+	if _, ok := attachmentText(context.Background(), reg, "big.pdf", big); ok {
+		t.Error("an oversized PDF must be skipped")
+	}
+
+	// Plain text is searched up to the cap, cut at a character boundary
+	text := "x" + strings.Repeat("é", maxAttachmentBytes/2) + " tail"
+	got, ok := attachmentText(context.Background(), reg, "big.txt", []byte(text))
+	if !ok || len(got) > maxAttachmentBytes || !utf8.ValidString(got) || strings.Contains(got, "tail") {
+		t.Errorf("oversized text: ok=%v, %d bytes, valid=%v", ok, len(got), utf8.ValidString(got))
+	}
+}
+
+func TestMayHaveAttachments(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"plain.eml":    "Subject: hi\r\n\r\nno parts here",
+		"attached.eml": mimeMessage("body", map[string][]byte{"a.txt": []byte("x")}),
+		"upper.eml":    "Content-Disposition: attachment; FILENAME=\"A.PDF\"\r\n",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]bool{"plain.eml": false, "attached.eml": true, "upper.eml": true, "missing.eml": true} {
+		path := filepath.Join(dir, name)
+		if got := mayHaveAttachments(path, path); got != want {
+			t.Errorf("mayHaveAttachments(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestExecuteReportsAttachment(t *testing.T) {
+	dir := t.TempDir()
+	msg := mimeMessage("Hello Bob, see attached.", map[string][]byte{"ledger.txt": []byte("reconciliation pending")})
+	if err := os.WriteFile(filepath.Join(dir, "mail.eml"), []byte(msg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	se := NewSearchEngine([]string{"reconciliation"}, nil, nil, false, 2, 5000)
+	se.Roots = []string{dir}
+	se.Silent = true
+	results, err := se.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Attachment != "ledger.txt" {
+		t.Fatalf("results = %+v, want one from ledger.txt", results)
+	}
+	if got, want := AttachmentPath(results[0].FullPath(), results[0].Attachment), filepath.Join(dir, "mail.eml")+" › ledger.txt"; got != want {
+		t.Errorf("AttachmentPath = %q, want %q", got, want)
+	}
+}
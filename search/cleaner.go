@@ -1,7 +1,6 @@
 package search
 
 import (
-	"fmt"
 	"regexp"
 	"sort"
 	"strings"
@@ -29,6 +28,9 @@ var (
 	// Lines with too many special characters (likely markup remnants)
 	junkLineRegex = regexp.MustCompile(`^[^a-zA-Z]*$|^[{}[\]();:=<>|\\]{3,}`)
 
+	// ANSI SGR escape sequences (as emitted by HighlightTerms)
+	ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
 	// Junk divider lines with excessive =, #, -, or _
 	junkSymbolsRegex = regexp.MustCompile(`(?m)^\s*[-_=#]{5,}\s*$`)
 
@@ -91,7 +93,8 @@ func CleanContent(content string) string {
 // ExtractMeaningfulExcerpts returns targeted, per-match snippets around each term.
 // We extract tight, local windows around each match with email-aware boundaries,
 // paragraph fallbacks, and punctuation-aware sentence ends. We avoid global scans.
-func ExtractMeaningfulExcerpts(content string, searchTerms []string, maxExcerpts int) []string {
+// diacritics folds diacritics when finding the terms (--fold-diacritics).
+func ExtractMeaningfulExcerpts(content string, searchTerms []string, maxExcerpts int, diacritics bool) []string {
 	// Line-preserving clean for boundary finding: remove heavy markup/noise but keep newlines
 	prep := cssRegex.ReplaceAllString(content, "")
 	prep = jsRegex.ReplaceAllString(prep, "")
@@ -112,14 +115,14 @@ func ExtractMeaningfulExcerpts(content string, searchTerms []string, maxExcerpts
 		return []string{}
 	}
 
-	// Build regexes for each term (whole-word, case-insensitive)
-	termRE := make([]*regexp.Regexp, 0, len(searchTerms))
+	// Build matchers for each term (whole-word, case-insensitive)
+	termRE := make([]*Matcher, 0, len(searchTerms))
 	for _, t := range searchTerms {
 		tt := strings.TrimSpace(t)
 		if tt == "" {
 			continue
 		}
-		termRE = append(termRE, regexp.MustCompile(fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(tt))))
+		termRE = append(termRE, newMatcher(tt, pluralSuffixes, diacritics))
 	}
 	if len(termRE) == 0 {
 		return []string{}
@@ -528,9 +531,9 @@ type matchInfo struct {
 }
 
 // containsAnySearchTerm checks if text contains any of the search terms
-func containsAnySearchTerm(text string, searchTerms []string) bool {
+func containsAnySearchTerm(text string, searchTerms []string, diacritics bool) bool {
 	for _, term := range searchTerms {
-		if containsWholeWord(text, term) {
+		if containsWholeWord(text, term, diacritics) {
 			return true
 		}
 	}
@@ -743,25 +746,23 @@ func isJunkLine(line string) bool {
 	return false
 }
 
-// containsWholeWord checks if text contains a whole word (case insensitive, plural-aware)
-func containsWholeWord(text, word string) bool {
-	// Match base, base+s, or base+es
-	pattern := fmt.Sprintf(`\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(word))
-	regex := regexp.MustCompile(`(?i)` + pattern)
-	return regex.MatchString(text)
+// containsWholeWord checks if text contains a whole word (case insensitive, plural-aware,
+// diacritic-insensitive with diacritics)
+func containsWholeWord(text, word string, diacritics bool) bool {
+	// Match base, base+s, or base+es (phrases match their words in order)
+	return newMatcher(word, pluralSuffixes, diacritics).MatchString(text)
 }
 
-// HighlightTerms highlights search terms in text with color codes (plural-aware)
-func HighlightTerms(text string, searchTerms []string) string {
+// HighlightTerms highlights search terms in text with color codes (plural-aware,
+// diacritic-insensitive with diacritics)
+func HighlightTerms(text string, searchTerms []string, diacritics bool) string {
 	const HI = "\033[1;31m" // bold red for stronger, more visible highlighting
 	const NC = "\033[0m"
 
 	result := text
 	for _, term := range searchTerms {
-		// Highlight base, base+s, or base+es as whole words
-		pattern := fmt.Sprintf(`\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(term))
-		regex := regexp.MustCompile(`(?i)` + pattern)
-		result = regex.ReplaceAllStringFunc(result, func(match string) string {
+		// Highlight base, base+s, or base+es as whole words; a phrase is highlighted as a whole
+		result = newMatcher(term, pluralSuffixes, diacritics).ReplaceAllStringFunc(result, func(match string) string {
 			return HI + match + NC
 		})
 	}
@@ -769,6 +770,11 @@ func HighlightTerms(text string, searchTerms []string) string {
 	return result
 }
 
+// StripANSI removes terminal color sequences (e.g., highlighting) from text
+func StripANSI(text string) string {
+	return ansiEscapeRegex.ReplaceAllString(text, "")
+}
+
 // hasLetters checks if a string contains any letters
 func hasLetters(text string) bool {
 	for _, r := range text {
@@ -0,0 +1,257 @@
+package search
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// isOfficeTextDocument reports whether --doc-extras changes the text extracted from path
+func isOfficeTextDocument(path string) bool {
+	switch formatExt(path) {
+	case ".doc", ".docx", ".odt", ".ods", ".odp":
+		return true
+	}
+	return false
+}
+
+// writeOfficeText writes the text of one entry returned by officeTextEntries for an
+// Office Open XML or OpenDocument file: runs joined, tabs kept, one line per paragraph;
+// extras adds OpenDocument notes and comments (--doc-extras). Worksheets leave out their
+// shared-string cells, which the shared strings entry holds.
+// Text written before a malformed part of the XML is kept; the error is returned.
+func writeOfficeText(w io.Writer, r io.Reader, ext, name string, extras bool) error {
+	switch strings.ToLower(ext) {
+	case ".docx":
+		return writeWordML(w, r)
+	case ".xlsx":
+		if name == "xl/sharedStrings.xml" {
+			return writeSharedStrings(w, r)
+		}
+		return writeSheetText(w, r, nil)
+	case ".pptx":
+		return writeSlideText(w, r)
+	}
+	return writeODFText(w, r, extras, name == "styles.xml")
+}
+
+// officeEntryText streams the text of an office entry (see writeOfficeText) through a pipe,
+// so prefilters can scan what the extractor will see. It takes over rc and closes it when
+// decoding ends; closing the returned reader stops the decoder at its next write.
+func officeEntryText(rc io.ReadCloser, ext, name string, extras bool) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		err := writeOfficeText(pw, rc, ext, name, extras)
+		rc.Close()
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// writeWordML writes the text of a WordprocessingML part (document, header, footer,
+// footnotes, endnotes or comments). Text of a paragraph's runs is joined as written,
+// w:tab and w:br inside runs become tab and newline, and each w:p ends a line.
+// Deleted revisions (w:del, w:moveFrom) and the VML fallback copies of text boxes are skipped.
+func writeWordML(w io.Writer, r io.Reader) error {
+	bw := bufio.NewWriter(w)
+	dec := xml.NewDecoder(r)
+	inText := false
+	run := 0  // depth of w:r
+	skip := 0 // depth inside a skipped subtree
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if ferr := bw.Flush(); ferr != nil {
+				return ferr
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "del", "moveFrom", "Fallback":
+				skip = 1
+			case "r":
+				run++
+			case "t":
+				inText = true
+			case "tab":
+				if run > 0 {
+					bw.WriteByte('\t')
+				}
+			case "br", "cr":
+				if run > 0 {
+					bw.WriteByte('\n')
+				}
+			case "noBreakHyphen":
+				if run > 0 {
+					bw.WriteByte('-')
+				}
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch t.Name.Local {
+			case "r":
+				run--
+			case "t":
+				inText = false
+			case "p":
+				bw.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText && skip == 0 {
+				if _, err := bw.Write(t); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// dcNamespace holds the Dublin Core metadata elements OpenDocument uses for comment authors and dates
+const dcNamespace = "http://purl.org/dc/elements/1.1/"
+
+// writeODFText writes the text of an OpenDocument content.xml or styles.xml. Text inside
+// text:p and text:h is written as it reads (spans joined, text:s, text:tab and text:line-break
+// expanded) with one line per paragraph. Tracked deletions are skipped, and footnotes,
+// endnotes (text:note) and comments (office:annotation) unless extras is set. In styles.xml
+// only the page headers and footers carry document text.
+func writeODFText(w io.Writer, r io.Reader, extras, styles bool) error {
+	bw := bufio.NewWriter(w)
+	dec := xml.NewDecoder(r)
+	para := 0  // depth of text:p / text:h
+	skip := 0  // depth inside a skipped subtree
+	scope := 0 // depth of style:header / style:footer (styles.xml)
+	inScope := func() bool { return para > 0 && (!styles || scope > 0) }
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if ferr := bw.Flush(); ferr != nil {
+				return ferr
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			if t.Name.Space == dcNamespace {
+				skip = 1 // author and date of a comment
+				continue
+			}
+			switch t.Name.Local {
+			case "tracked-changes", "note-citation":
+				skip = 1
+			case "note", "annotation":
+				if !extras {
+					skip = 1
+				} else if inScope() {
+					bw.WriteByte(' ') // keep notes apart from the word they follow
+				}
+			case "header", "footer", "header-left", "footer-left", "header-first", "footer-first":
+				scope++
+			case "p", "h":
+				para++
+			case "tab":
+				if inScope() {
+					bw.WriteByte('\t')
+				}
+			case "line-break":
+				if inScope() {
+					bw.WriteByte('\n')
+				}
+			case "s":
+				if inScope() {
+					n := 1
+					for _, a := range t.Attr {
+						if a.Name.Local == "c" {
+							if c, err := strconv.Atoi(a.Value); err == nil && c > 0 && c < 1024 {
+								n = c
+							}
+						}
+					}
+					bw.WriteString(strings.Repeat(" ", n))
+				}
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch t.Name.Local {
+			case "note", "annotation":
+				if inScope() {
+					bw.WriteByte(' ')
+				}
+			case "header", "footer", "header-left", "footer-left", "header-first", "footer-first":
+				scope--
+			case "p", "h":
+				if inScope() {
+					bw.WriteByte('\n')
+				}
+				para--
+			}
+		case xml.CharData:
+			if skip == 0 && inScope() {
+				// Line breaks in the XML source are not part of the text
+				if _, err := bw.WriteString(strings.Map(func(r rune) rune {
+					if r == '\n' || r == '\r' || r == '\t' {
+						return ' '
+					}
+					return r
+				}, string(t))); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// extractOfficeText returns the text of every text-bearing entry of a .docx or OpenDocument
+// file in reading order, one entry after another, with the --doc-extras parts when extras
+// is set. ok is false when data is not such a file; ctx is checked between entries.
+func extractOfficeText(ctx context.Context, data []byte, ext string, extras bool) (string, bool, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", false, nil
+	}
+	entries := officeTextEntries(zr, ext, extras)
+	if len(entries) == 0 {
+		return "", false, nil
+	}
+	var text strings.Builder
+	for _, f := range entries {
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		// A damaged part still contributes the text read before the damage
+		_ = writeOfficeText(&text, rc, ext, f.Name, extras)
+		rc.Close()
+		text.WriteString("\n")
+	}
+	return strings.TrimSpace(text.String()), true, nil
+}
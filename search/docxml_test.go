@@ -0,0 +1,127 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const (
+	wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"`
+	odfNS  = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/"`
+)
+
+func TestWriteWordML(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"joined runs", `<w:p><w:r><w:t>con</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>tract</w:t></w:r></w:p>`, "contract\n"},
+		{"paragraphs", `<w:p><w:r><w:t>one</w:t></w:r></w:p><w:p><w:r><w:t>two</w:t></w:r></w:p>`, "one\ntwo\n"},
+		{"tab, break and hyphen", `<w:p><w:r><w:t>a</w:t><w:tab/><w:t>b</w:t><w:br/><w:t>c</w:t><w:noBreakHyphen/><w:t>d</w:t></w:r></w:p>`, "a\tb\nc-d\n"},
+		{"tab outside a run", `<w:p><w:pPr><w:tabs><w:tab w:val="left"/></w:tabs></w:pPr><w:r><w:t>x</w:t></w:r></w:p>`, "x\n"},
+		{"deletion skipped", `<w:p><w:r><w:t>net </w:t></w:r><w:del><w:r><w:delText>gross</w:delText><w:t>old</w:t></w:r></w:del><w:ins><w:r><w:t>total</w:t></w:r></w:ins></w:p>`, "net total\n"},
+		{"move source skipped", `<w:p><w:moveFrom><w:r><w:t>moved</w:t></w:r></w:moveFrom><w:moveTo><w:r><w:t>moved</w:t></w:r></w:moveTo></w:p>`, "moved\n"},
+		{"fallback skipped", `<w:p><w:r><mc:AlternateContent><mc:Choice><w:t>box</w:t></mc:Choice><mc:Fallback><w:t>box</w:t></mc:Fallback></mc:AlternateContent></w:r></w:p>`, "box\n"},
+		{"entities", `<w:p><w:r><w:t>R&amp;D &lt;draft&gt;</w:t></w:r></w:p>`, "R&D <draft>\n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		doc := `<w:document ` + wordNS + `><w:body>` + tt.body + `</w:body></w:document>`
+		if err := writeWordML(&b, strings.NewReader(doc)); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+
+	// Text before malformed XML is kept
+	var b strings.Builder
+	if err := writeWordML(&b, strings.NewReader(`<w:document><w:p><w:r><w:t>kept</w:t></w:r></w:p><w:p>`)); err == nil || b.String() != "kept\n" {
+		t.Errorf("truncated: got %q, %v; want the text so far and an error", b.String(), err)
+	}
+}
+
+func TestWriteODFText(t *testing.T) {
+	note := `<text:note><text:note-citation>1</text:note-citation><text:note-body><text:p>see annex</text:p></text:note-body></text:note>`
+	comment := `<office:annotation><dc:creator>Ann</dc:creator><dc:date>2024-01-01</dc:date><text:p>check this</text:p></office:annotation>`
+	tests := []struct {
+		name, body string
+		extras     bool
+		want       string
+	}{
+		{"joined spans", `<text:p>con<text:span>tract</text:span></text:p>`, false, "contract\n"},
+		{"spaces", `<text:p>a<text:s/>b<text:s text:c="3"/>c</text:p>`, false, "a b   c\n"},
+		{"tab and break", `<text:h>a<text:tab/>b<text:line-break/>c</text:h>`, false, "a\tb\nc\n"},
+		{"source line breaks", "<text:p>one\n  two</text:p>", false, "one   two\n"},
+		{"tracked deletion", `<text:tracked-changes><text:changed-region><text:deletion><text:p>gone</text:p></text:deletion></text:changed-region></text:tracked-changes><text:p>kept</text:p>`, false, "kept\n"},
+		{"note without extras", `<text:p>total` + note + ` due</text:p>`, false, "total due\n"},
+		{"note with extras", `<text:p>total` + note + ` due</text:p>`, true, "total see annex\n  due\n"},
+		{"comment without extras", `<text:p>draft` + comment + `</text:p>`, false, "draft\n"},
+		{"comment with extras", `<text:p>draft` + comment + `</text:p>`, true, "draft check this\n \n"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		doc := `<office:document-content ` + odfNS + `><office:body><office:text>` + tt.body + `</office:text></office:body></office:document-content>`
+		if err := writeODFText(&b, strings.NewReader(doc), tt.extras, false); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, b.String(), tt.want)
+		}
+	}
+
+	// In styles.xml only page headers and footers are text
+	styles := `<office:document-styles ` + odfNS + `><office:styles><style:style><text:p>style sample</text:p></style:style></office:styles>` +
+		`<office:master-styles><style:master-page><style:header><text:p>Acme Corp</text:p></style:header>` +
+		`<style:footer-left><text:p>page footer</text:p></style:footer-left></style:master-page></office:master-styles></office:document-styles>`
+	var b strings.Builder
+	if err := writeODFText(&b, strings.NewReader(styles), true, true); err != nil || b.String() != "Acme Corp\npage footer\n" {
+		t.Errorf("styles.xml: got %q, %v", b.String(), err)
+	}
+}
+
+func TestExtractOfficeTextExtras(t *testing.T) {
+	docx := zipFile(t,
+		"[Content_Types].xml", "<Types/>",
+		"word/document.xml", `<w:document `+wordNS+`><w:body><w:p><w:r><w:t>main text</w:t></w:r></w:p></w:body></w:document>`,
+		"word/header1.xml", `<w:hdr `+wordNS+`><w:p><w:r><w:t>page header</w:t></w:r></w:p></w:hdr>`,
+		"word/footnotes.xml", `<w:footnotes `+wordNS+`><w:footnote><w:p><w:r><w:t>a footnote</w:t></w:r></w:p></w:footnote></w:footnotes>`,
+		"word/comments.xml", `<w:comments `+wordNS+`><w:comment><w:p><w:r><w:t>a comment</w:t></w:r></w:p></w:comment></w:comments>`,
+	)
+	odt := zipFile(t,
+		"mimetype", "application/vnd.oasis.opendocument.text",
+		"content.xml", `<office:document-content `+odfNS+`><office:body><office:text><text:p>main text</text:p></office:text></office:body></office:document-content>`,
+		"styles.xml", `<office:document-styles `+odfNS+`><office:master-styles><style:master-page><style:header><text:p>page header</text:p></style:header></style:master-page></office:master-styles></office:document-styles>`,
+	)
+	tests := []struct {
+		name   string
+		data   []byte
+		ext    string
+		extras bool
+		want   string
+	}{
+		{"docx", docx, ".docx", false, "main text"},
+		{"docx with extras", docx, ".docx", true, "main text\n\npage header\n\na footnote\n\na comment"},
+		{"odt", odt, ".odt", false, "main text"},
+		{"odt with extras", odt, ".odt", true, "main text\n\npage header"},
+	}
+	for _, tt := range tests {
+		got, ok, err := extractOfficeText(context.Background(), tt.data, tt.ext, tt.extras)
+		if err != nil || !ok || got != tt.want {
+			t.Errorf("%s: got %q, %v, %v; want %q", tt.name, got, ok, err, tt.want)
+		}
+	}
+
+	if _, ok, _ := extractOfficeText(context.Background(), []byte("not a zip"), ".docx", false); ok {
+		t.Error("not a zip: want ok = false")
+	}
+	if _, ok, _ := extractOfficeText(context.Background(), zipFile(t, "other.xml", "<x/>"), ".docx", false); ok {
+		t.Error("no document part: want ok = false")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := extractOfficeText(ctx, docx, ".docx", false); err == nil {
+		t.Error("cancelled: want an error")
+	}
+}
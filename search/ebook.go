@@ -0,0 +1,579 @@
+package search
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxEbookBytes caps the decompressed chapter bytes read from one e-book; each chapter is
+// also capped like a document inside an archive (maxArchiveEntryBytes)
+const maxEbookBytes = 64 << 20
+
+var (
+	// The head of an (X)HTML document: its <title> is not chapter text
+	htmlHeadRegex = regexp.MustCompile(`(?is)<head[\s>].*?</head>`)
+
+	// Inline elements, which must not split words ("<span class=dropcap>T</span>he")
+	htmlInlineTagRegex = regexp.MustCompile(`(?i)</?(a|abbr|b|big|cite|code|em|font|i|kbd|q|s|small|span|strong|sub|sup|u|var)(\s[^>]*)?/?>`)
+
+	// The first h1-h3 heading, for chapters the table of contents does not name
+	htmlHeadingRegex = regexp.MustCompile(`(?is)<h[1-3][^>]*>(.*?)</h[1-3]>`)
+)
+
+// xhtmlText returns the text of an (X)HTML chapter through the HTML cleaning path: the head,
+// styles and scripts are dropped and the tags of the body stripped (see stripHTMLTags).
+// Inline tags are removed without a space so words split by markup stay whole.
+func xhtmlText(doc string) string {
+	doc = htmlHeadRegex.ReplaceAllString(doc, "")
+	doc = cssRegex.ReplaceAllString(doc, "")
+	doc = jsRegex.ReplaceAllString(doc, "")
+	doc = htmlInlineTagRegex.ReplaceAllString(doc, "")
+	return collapseSpace(stripHTMLTags(doc))
+}
+
+// xhtmlHeading returns the text of the first h1-h3 heading of doc, if any
+func xhtmlHeading(doc string) string {
+	if m := htmlHeadingRegex.FindStringSubmatch(doc); m != nil {
+		return collapseSpace(stripHTMLTags(m[1]))
+	}
+	return ""
+}
+
+// xhtmlEntryText streams the text of an (X)HTML zip entry (see xhtmlText) through a pipe, so
+// prefilters scan what the extractor sees. It takes over rc and closes it.
+func xhtmlEntryText(rc io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		b, err := io.ReadAll(io.LimitReader(rc, maxArchiveEntryBytes))
+		rc.Close()
+		if err == nil {
+			_, err = io.WriteString(pw, xhtmlText(string(b)))
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// epubChapter is one spine item of an EPUB: its XHTML entry and its table-of-contents title
+type epubChapter struct {
+	file  *zip.File
+	title string
+}
+
+// epubPackage is the part of the OPF package document that locates the chapters
+type epubPackage struct {
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc   string `xml:"toc,attr"`
+		Items []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// epubChapters returns the chapters of an EPUB in reading order: the OPF package named by
+// META-INF/container.xml lists them in its spine, and the EPUB 3 navigation document (or
+// the EPUB 2 NCX) supplies their titles. Spine files the table of contents does not name
+// continue the chapter before them. At most maxArchiveEntries chapters are returned.
+func epubChapters(zr *zip.Reader) ([]epubChapter, error) {
+	byName := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		byName[f.Name] = f
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	opfPath := ""
+	if err := decodeZipXML(byName["META-INF/container.xml"], &container); err == nil && len(container.Rootfiles) > 0 {
+		opfPath = container.Rootfiles[0].FullPath
+	} else {
+		for _, f := range zr.File {
+			if strings.EqualFold(path.Ext(f.Name), ".opf") {
+				opfPath = f.Name
+				break
+			}
+		}
+	}
+	var pkg epubPackage
+	if err := decodeZipXML(byName[opfPath], &pkg); err != nil {
+		return nil, fmt.Errorf("failed to read EPUB package %q: %w", opfPath, err)
+	}
+
+	base := path.Dir(opfPath)
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	tocPath := ""
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = zipHref(base, item.Href)
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			tocPath = hrefs[item.ID]
+		}
+	}
+	if tocPath == "" {
+		tocPath = hrefs[pkg.Spine.Toc]
+	}
+	titles := epubTOCTitles(byName[tocPath], path.Dir(tocPath))
+
+	var chapters []epubChapter
+	title := ""
+	for _, ref := range pkg.Spine.Items {
+		if len(chapters) >= maxArchiveEntries {
+			break
+		}
+		f, ok := byName[hrefs[ref.IDRef]]
+		if !ok {
+			continue
+		}
+		if t, ok := titles[f.Name]; ok {
+			title = t
+		}
+		chapters = append(chapters, epubChapter{file: f, title: title})
+	}
+	if len(chapters) == 0 {
+		return nil, errors.New("EPUB has no readable chapters")
+	}
+	return chapters, nil
+}
+
+// zipHref resolves a URL-encoded href found in an entry of directory base to an entry name
+func zipHref(base, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if u, err := url.PathUnescape(href); err == nil {
+		href = u
+	}
+	return path.Join(base, href)
+}
+
+// decodeZipXML decodes the XML entry f into v; HTML entities and unclosed tags are tolerated
+func decodeZipXML(f *zip.File, v any) error {
+	if f == nil {
+		return errors.New("missing entry")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return newLenientDecoder(io.LimitReader(rc, maxArchiveEntryBytes)).Decode(v)
+}
+
+// newLenientDecoder returns an XML decoder for the loose XML of e-books: HTML entities,
+// unclosed HTML tags and any declared character set are accepted
+func newLenientDecoder(r io.Reader) *xml.Decoder {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	}
+	return dec
+}
+
+// epubTOCTitles maps each chapter entry named by an EPUB table of contents to its first
+// title there: the links of the toc nav of an EPUB 3 navigation document, or the navPoints
+// of an EPUB 2 NCX. Hrefs are relative to dir.
+func epubTOCTitles(f *zip.File, dir string) map[string]string {
+	titles := make(map[string]string)
+	if f == nil {
+		return titles
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return titles
+	}
+	defer rc.Close()
+
+	dec := newLenientDecoder(io.LimitReader(rc, maxArchiveEntryBytes))
+	var label strings.Builder
+	inLabel := false // inside an NCX navLabel or a nav link
+	href := ""
+	navs, tocNav := 0, false // nav depth; whether the enclosing nav is the toc
+	add := func(href, title string) {
+		name := zipHref(dir, href)
+		if _, ok := titles[name]; !ok && title != "" {
+			titles[name] = title
+		}
+	}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return titles
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "nav":
+				navs++
+				if navs == 1 {
+					tocNav = false
+					for _, a := range t.Attr {
+						if a.Name.Local == "type" && strings.Contains(a.Value, "toc") {
+							tocNav = true
+						}
+					}
+				}
+			case "navLabel":
+				inLabel = true
+				label.Reset()
+			case "content":
+				// NCX: the label comes before the target
+				for _, a := range t.Attr {
+					if a.Name.Local == "src" {
+						add(a.Value, collapseSpace(label.String()))
+					}
+				}
+			case "a":
+				if navs > 0 && tocNav {
+					for _, a := range t.Attr {
+						if a.Name.Local == "href" {
+							href = a.Value
+							inLabel = true
+							label.Reset()
+						}
+					}
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "nav":
+				navs--
+			case "navLabel":
+				inLabel = false
+			case "a":
+				if href != "" {
+					add(href, collapseSpace(label.String()))
+					href, inLabel = "", false
+				}
+			}
+		case xml.CharData:
+			if inLabel {
+				label.Write(t)
+			}
+		}
+	}
+}
+
+// EPUBExtractor extracts the text of .epub e-books chapter by chapter in reading order.
+// Chapters are XHTML and go through the HTML cleaning path; each is a part named by its
+// title, so results report the chapter they matched in.
+type EPUBExtractor struct{}
+
+// ExtractText implements the Extractor interface for EPUB files
+func (e *EPUBExtractor) ExtractText(data []byte) (string, error) {
+	return e.ExtractTextContext(context.Background(), data)
+}
+
+// ExtractTextContext implements the ContextExtractor interface, checking ctx between chapters
+func (e *EPUBExtractor) ExtractTextContext(ctx context.Context, data []byte) (string, error) {
+	parts, err := e.ExtractParts(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	return joinParts(parts), nil
+}
+
+// ExtractParts implements the PartsExtractor interface: one section part per chapter with text
+func (e *EPUBExtractor) ExtractParts(ctx context.Context, data []byte) ([]TextPart, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EPUB: %w", err)
+	}
+	chapters, err := epubChapters(zr)
+	if err != nil {
+		return nil, err
+	}
+	var parts []TextPart
+	budget := int64(maxEbookBytes)
+	for _, ch := range chapters {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if budget <= 0 {
+			break
+		}
+		rc, err := ch.file.Open()
+		if err != nil {
+			continue
+		}
+		b, _ := io.ReadAll(io.LimitReader(rc, int64(min(maxArchiveEntryBytes, int(budget)))))
+		rc.Close()
+		budget -= int64(len(b))
+
+		doc := string(b)
+		text := xhtmlText(doc)
+		if text == "" {
+			continue
+		}
+		title := ch.title
+		if title == "" {
+			title = xhtmlHeading(doc)
+		}
+		parts = append(parts, TextPart{Name: title, Text: text, Section: true})
+	}
+	return parts, nil
+}
+
+// FB2Extractor extracts the text of FictionBook 2 (.fb2) e-books. Each top-level section of
+// the main body is a part named by its title; further bodies (notes, comments) are one part
+// each. Embedded binaries and the description are skipped.
+type FB2Extractor struct{}
+
+// ExtractText implements the Extractor interface for FB2 files
+func (e *FB2Extractor) ExtractText(data []byte) (string, error) {
+	parts, err := e.ExtractParts(context.Background(), data)
+	if err != nil {
+		return "", err
+	}
+	return joinParts(parts), nil
+}
+
+// ExtractParts implements the PartsExtractor interface
+func (e *FB2Extractor) ExtractParts(ctx context.Context, data []byte) ([]TextPart, error) {
+	dec := newLenientDecoder(bytes.NewReader(data))
+	var parts []TextPart
+	var text, title strings.Builder
+	bodies := 0 // bodies seen
+	inBody := false
+	sections := 0 // section depth inside the main body
+	titles := 0   // depth inside the title that names the current part
+	flush := func() {
+		if t := collapseSpace(text.String()); t != "" {
+			parts = append(parts, TextPart{Name: collapseSpace(title.String()), Text: t, Section: true})
+		}
+		text.Reset()
+		title.Reset()
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if len(parts) == 0 && text.Len() == 0 {
+				return nil, fmt.Errorf("failed to parse FB2: %w", err)
+			}
+			break // keep the text read before the damage
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "body":
+				flush()
+				bodies++
+				inBody = true
+			case "section":
+				if inBody && bodies == 1 {
+					sections++
+					if sections == 1 {
+						flush()
+					}
+				}
+			case "title":
+				if titles > 0 {
+					titles++
+				} else if inBody && title.Len() == 0 && (sections == 1 || bodies > 1) {
+					titles = 1
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "body":
+				flush()
+				inBody = false
+			case "section":
+				if inBody && bodies == 1 {
+					sections--
+					if sections == 0 {
+						flush()
+					}
+				}
+			case "title":
+				if titles > 0 {
+					titles--
+				}
+			case "p", "v", "subtitle", "text-author", "empty-line":
+				if inBody {
+					text.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if !inBody {
+				continue
+			}
+			text.Write(t)
+			if titles > 0 {
+				title.Write(t)
+				title.WriteString(" ")
+			}
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// MOBIExtractor salvages the text of .mobi e-books: the PalmDOC text records, uncompressed
+// or PalmDOC (LZ77) compressed, decoded as cp1252 or UTF-8 and stripped of their HTML.
+// DRM-protected and HUFF/CDIC compressed books are not supported.
+type MOBIExtractor struct{}
+
+// ExtractText implements the Extractor interface for MOBI files
+func (e *MOBIExtractor) ExtractText(data []byte) (string, error) {
+	be := binary.BigEndian // PalmDB is big-endian
+	if len(data) < 78 {
+		return "", errors.New("not a MOBI file")
+	}
+	n := int(be.Uint16(data[76:]))
+	if n < 2 || 78+8*n > len(data) {
+		return "", errors.New("not a MOBI file")
+	}
+	offsets := make([]int, n+1)
+	for i := 0; i < n; i++ {
+		offsets[i] = int(be.Uint32(data[78+8*i:]))
+	}
+	offsets[n] = len(data)
+	record := func(i int) []byte {
+		start, end := offsets[i], offsets[i+1]
+		if start < 0 || start > end || end > len(data) {
+			return nil
+		}
+		return data[start:end]
+	}
+
+	// Record 0: PalmDOC header, then the MOBI header
+	rec0 := record(0)
+	if len(rec0) < 16 {
+		return "", errors.New("not a MOBI file")
+	}
+	compression := be.Uint16(rec0)
+	textRecords := int(be.Uint16(rec0[8:]))
+	if be.Uint16(rec0[12:]) != 0 {
+		return "", errors.New("DRM-protected MOBI")
+	}
+	if compression != 1 && compression != 2 {
+		return "", fmt.Errorf("unsupported MOBI compression %d", compression)
+	}
+	utf8Text := false
+	var extraFlags uint16
+	if len(rec0) >= 32 && string(rec0[16:20]) == "MOBI" {
+		headerLen := int(be.Uint32(rec0[20:]))
+		utf8Text = be.Uint32(rec0[28:]) == 65001
+		if headerLen >= 0xE4 && len(rec0) >= 0xF4 {
+			extraFlags = be.Uint16(rec0[0xF2:])
+		}
+	}
+
+	var raw []byte
+	for i := 1; i <= textRecords && i < n && len(raw) < maxEbookBytes; i++ {
+		rec := mobiTrimTrailing(record(i), extraFlags)
+		if compression == 2 {
+			rec = palmDOCDecompress(rec)
+		}
+		raw = append(raw, rec...)
+	}
+
+	var doc string
+	if utf8Text {
+		doc = strings.ToValidUTF8(string(raw), "")
+	} else {
+		b, err := charmap.Windows1252.NewDecoder().Bytes(raw)
+		if err != nil {
+			return "", err
+		}
+		doc = string(b)
+	}
+	return xhtmlText(doc), nil
+}
+
+// mobiTrimTrailing drops the trailing entries MOBI appends to text records: one
+// backward-encoded size per set flag bit above bit 0, then the multibyte overlap (bit 0)
+func mobiTrimTrailing(rec []byte, flags uint16) []byte {
+	for f := flags >> 1; f != 0; f >>= 1 {
+		if f&1 == 0 {
+			continue
+		}
+		size, shift := 0, 0
+		for i := len(rec) - 1; i >= 0; i-- {
+			b := rec[i]
+			size |= int(b&0x7F) << shift
+			shift += 7
+			if b&0x80 != 0 || shift >= 28 {
+				break
+			}
+		}
+		if size > len(rec) {
+			return nil
+		}
+		rec = rec[:len(rec)-size]
+	}
+	if flags&1 != 0 && len(rec) > 0 {
+		n := int(rec[len(rec)-1]&3) + 1
+		if n > len(rec) {
+			return nil
+		}
+		rec = rec[:len(rec)-n]
+	}
+	return rec
+}
+
+// palmDOCDecompress expands a PalmDOC (LZ77) compressed record
+func palmDOCDecompress(in []byte) []byte {
+	out := make([]byte, 0, 2*len(in))
+	for i := 0; i < len(in); {
+		c := in[i]
+		i++
+		switch {
+		case c >= 0x01 && c <= 0x08:
+			// Literal run of the next c bytes
+			n := min(int(c), len(in)-i)
+			out = append(out, in[i:i+n]...)
+			i += n
+		case c >= 0x80 && c <= 0xBF:
+			// Back reference: 11-bit distance, 3-bit length (+3)
+			if i >= len(in) {
+				return out
+			}
+			pair := int(c)<<8 | int(in[i])
+			i++
+			dist, n := pair>>3&0x7FF, pair&7+3
+			if dist == 0 || dist > len(out) {
+				continue
+			}
+			for k := 0; k < n; k++ {
+				out = append(out, out[len(out)-dist])
+			}
+		case c >= 0xC0:
+			// Space followed by a character
+			out = append(out, ' ', c^0x80)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
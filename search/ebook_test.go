@@ -0,0 +1,181 @@
+package search
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+// epubFile returns an EPUB whose package lists the given spine, with its entries under OEBPS/
+func epubFile(t *testing.T, opf string, entries ...string) []byte {
+	t.Helper()
+	all := append([]string{
+		"mimetype", "application/epub+zip",
+		"META-INF/container.xml", `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf", opf,
+	}, entries...)
+	return zipFile(t, all...)
+}
+
+func chapter(heading, body string) string {
+	return `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>ignored</title></head><body>` + heading + `<p>` + body + `</p></body></html>`
+}
+
+func TestEPUBChapters(t *testing.T) {
+	items := `<item id="c3" href="text/ch3.xhtml"/>
+		<item id="c1" href="text/ch%201.xhtml"/>
+		<item id="c2" href="text/ch2.xhtml"/>
+		<item id="ncx" href="toc.ncx"/>`
+	manifest := `<manifest>` + items + `<item id="nav" href="nav.xhtml" properties="nav"/></manifest>`
+	epub2 := `<manifest>` + items + `</manifest>`
+	texts := []string{
+		"OEBPS/text/ch 1.xhtml", chapter("<h1>Opening</h1>", "first words"),
+		"OEBPS/text/ch2.xhtml", chapter("", "second words"),
+		"OEBPS/text/ch3.xhtml", chapter("", "third words"),
+	}
+	nav := `<html><body><nav epub:type="landmarks"><a href="text/ch3.xhtml">Landmark</a></nav>` +
+		`<nav epub:type="toc"><ol><li><a href="text/ch%201.xhtml">One</a></li><li><a href="text/ch3.xhtml#start">Three &amp; more</a></li></ol></nav></body></html>`
+	ncx := `<ncx><navMap><navPoint><navLabel><text>NCX One</text></navLabel><content src="text/ch%201.xhtml"/></navPoint>` +
+		`<navPoint><navLabel><text>NCX Two</text></navLabel><content src="text/ch2.xhtml"/></navPoint></navMap></ncx>`
+	spine := `<spine toc="ncx"><itemref idref="c1"/><itemref idref="c2"/><itemref idref="missing"/><itemref idref="c3"/></spine>`
+
+	tests := []struct {
+		name   string
+		data   []byte
+		titles []string
+	}{
+		// ch2 has no entry in the nav document: it continues "One"
+		{"nav", epubFile(t, `<package>`+manifest+spine+`</package>`, append(texts, "OEBPS/nav.xhtml", nav, "OEBPS/toc.ncx", ncx)...), []string{"One", "One", "Three & more"}},
+		{"ncx", epubFile(t, `<package>`+epub2+spine+`</package>`, append(texts, "OEBPS/toc.ncx", ncx)...), []string{"NCX One", "NCX Two", "NCX Two"}},
+		{"no toc", epubFile(t, `<package>`+manifest+`<spine><itemref idref="c1"/><itemref idref="c2"/><itemref idref="c3"/></spine></package>`, texts...), []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		zr, err := zip.NewReader(bytes.NewReader(tt.data), int64(len(tt.data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chapters, err := epubChapters(zr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var names, titles []string
+		for _, ch := range chapters {
+			names = append(names, ch.file.Name)
+			titles = append(titles, ch.title)
+		}
+		if want := []string{"OEBPS/text/ch 1.xhtml", "OEBPS/text/ch2.xhtml", "OEBPS/text/ch3.xhtml"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: spine %q, want %q", tt.name, names, want)
+		}
+		if !reflect.DeepEqual(titles, tt.titles) {
+			t.Errorf("%s: titles %q, want %q", tt.name, titles, tt.titles)
+		}
+	}
+
+	// Without a table of contents the first heading names a chapter
+	parts, err := (&EPUBExtractor{}).ExtractParts(context.Background(), tests[2].data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TextPart{
+		{Name: "Opening", Text: "Opening first words", Section: true},
+		{Name: "", Text: "second words", Section: true},
+		{Name: "", Text: "third words", Section: true},
+	}
+	for i := range parts {
+		parts[i].Text = collapseSpace(parts[i].Text)
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("parts = %+v, want %+v", parts, want)
+	}
+
+	bare := zipFile(t, "mimetype", "application/epub+zip")
+	zr, err := zip.NewReader(bytes.NewReader(bare), int64(len(bare)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := epubChapters(zr); err == nil {
+		t.Error("an EPUB without a package: want an error")
+	}
+}
+
+func TestFB2ExtractParts(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook xmlns="http://www.gribuser.ru/xml/fictionbook/2.0">
+<description><title-info><book-title>Not text</book-title></title-info></description>
+<body>
+  <title><p>The Book</p></title>
+  <section><title><p>Chapter</p><p>One</p></title><p>It begins&nbsp;here.</p>
+    <section><title><p>Nested</p></title><p>Inner text.</p></section>
+  </section>
+  <section><p>Untitled chapter.</p></section>
+</body>
+<body name="notes"><title><p>Notes</p></title><section><p>A note.</p></section></body>
+<binary id="cover.jpg" content-type="image/jpeg">AAAA</binary>
+</FictionBook>`
+	parts, err := (&FB2Extractor{}).ExtractParts(context.Background(), []byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TextPart{
+		{Name: "", Text: "The Book", Section: true},
+		{Name: "Chapter One", Text: "Chapter One It begins here. Nested Inner text.", Section: true}, // &nbsp; is read as a space
+		{Name: "", Text: "Untitled chapter.", Section: true},
+		{Name: "Notes", Text: "Notes A note.", Section: true},
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("parts = %+v\nwant %+v", parts, want)
+	}
+
+	if _, err := (&FB2Extractor{}).ExtractParts(context.Background(), []byte("<FictionBook><body><p>")); err == nil {
+		t.Error("no text before the damage: want an error")
+	}
+}
+
+func TestMOBITrimTrailing(t *testing.T) {
+	tests := []struct {
+		name  string
+		rec   string
+		flags uint16
+		want  string
+	}{
+		{"no flags", "hello\x83", 0, "hello\x83"},
+		{"one entry", "hello" + "zz\x83", 0b10, "hello"},
+		{"two entries", "hello" + "y\x82" + "zz\x83", 0b110, "hello"},
+		{"unset bit skipped", "hello" + "zz\x83", 0b100, "hello"},
+		{"multi-byte size", "hello" + string(make([]byte, 128)) + "\x81\x02", 0b10, "hello"},
+		{"multibyte overlap", "hello" + "X\x01", 0b01, "hello"},
+		{"both", "hello" + "X\x01" + "zz\x83", 0b11, "hello"},
+		{"size past the start", "ab\x8f", 0b10, ""},
+	}
+	for _, tt := range tests {
+		if got := string(mobiTrimTrailing([]byte(tt.rec), tt.flags)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPalmDOCDecompress(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"plain", []byte("Hello"), "Hello"},
+		{"nul kept", []byte{'a', 0x00, 'b'}, "a\x00b"},
+		{"literal run", []byte{0x03, 0xC1, 0x80, 0x05, 'x'}, "\xC1\x80\x05x"},
+		{"literal past the end", []byte{0x05, 'a', 'b'}, "ab"},
+		{"space pair", []byte{'a', 0xE2, 'c'}, "a bc"},
+		{"back reference", []byte{'a', 'b', 'c', 'd', 0x80, 0x21}, "abcdabcd"},
+		{"overlapping reference", []byte{'a', 'b', 0x80, 0x13}, "abababab"},
+		{"distance too far", []byte{'a', 0x80, 0x21, 'b'}, "ab"},
+		{"truncated pair", []byte{'a', 0x80}, "a"},
+	}
+	for _, tt := range tests {
+		if got := string(palmDOCDecompress(tt.in)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
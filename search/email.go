@@ -0,0 +1,341 @@
+package search
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/jhillyerd/enmime"
+	"github.com/richardlehane/mscfb"
+
+	"find-words/search/fold"
+)
+
+// maxEmailHeaderBytes bounds how much of an .eml is read to parse its headers
+const maxEmailHeaderBytes = 256 * 1024
+
+// EmailMeta holds the parsed headers of an email message
+type EmailMeta struct {
+	Sender     string    // From, as "Name <address>" or the bare address
+	Recipients []string  // To, then Cc
+	Subject    string    // decoded
+	Date       time.Time // zero when missing or unparseable
+	DateRaw    string    // Date header as written
+}
+
+// EmailFilter restricts a search to emails whose headers pass every set field.
+// From, To and Subject match case-insensitively anywhere in the sender, any recipient
+// and the subject; After and Before bound the message date (inclusive), and messages
+// without a readable date fail them. A nil filter or one with no field set is inactive.
+type EmailFilter struct {
+	From    string
+	To      string
+	Subject string
+	After   time.Time
+	Before  time.Time
+}
+
+// NewEmailFilter builds a filter from CLI values; after and before are dates
+// (2006-01-02, local time, whole days) or RFC 3339 timestamps. Empty values are unset.
+func NewEmailFilter(from, to, subject, after, before string) (*EmailFilter, error) {
+	f := &EmailFilter{From: from, To: to, Subject: subject}
+	var err error
+	if f.After, err = parseFilterDate(after, false); err != nil {
+		return nil, fmt.Errorf("invalid --after %q: %w", after, err)
+	}
+	if f.Before, err = parseFilterDate(before, true); err != nil {
+		return nil, fmt.Errorf("invalid --before %q: %w", before, err)
+	}
+	if !f.After.IsZero() && !f.Before.IsZero() && f.Before.Before(f.After) {
+		return nil, fmt.Errorf("--before %s is earlier than --after %s", before, after)
+	}
+	return f, nil
+}
+
+// parseFilterDate parses a CLI date; a bare day stands for its start, or for its last
+// instant when endOfDay is set (so --before includes the day it names)
+func parseFilterDate(s string, endOfDay bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("want YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+// Active reports whether the filter restricts anything
+func (f *EmailFilter) Active() bool {
+	return f != nil && (f.From != "" || f.To != "" || f.Subject != "" || !f.After.IsZero() || !f.Before.IsZero())
+}
+
+// Match reports whether an email with headers m passes the filter; diacritics folds
+// diacritics too (--fold-diacritics)
+func (f *EmailFilter) Match(m EmailMeta, diacritics bool) bool {
+	if !f.Active() {
+		return true
+	}
+	if f.From != "" && !containsFold(m.Sender, f.From, diacritics) {
+		return false
+	}
+	if f.To != "" {
+		found := false
+		for _, r := range m.Recipients {
+			if containsFold(r, f.To, diacritics) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Subject != "" && !containsFold(m.Subject, f.Subject, diacritics) {
+		return false
+	}
+	if !f.After.IsZero() && (m.Date.IsZero() || m.Date.Before(f.After)) {
+		return false
+	}
+	if !f.Before.IsZero() && (m.Date.IsZero() || m.Date.After(f.Before)) {
+		return false
+	}
+	return true
+}
+
+// containsFold reports whether s contains substr under case (and with diacritics,
+// diacritic) folding
+func containsFold(s, substr string, diacritics bool) bool {
+	return strings.Contains(fold.String(s, diacritics), fold.String(substr, diacritics))
+}
+
+// isEmailFile reports whether name is a single email message (.eml or .msg)
+func isEmailFile(name string) bool {
+	switch formatExt(name) {
+	case ".eml", ".msg":
+		return true
+	}
+	return false
+}
+
+// readEmailMeta parses the headers of the .eml or .msg file at path, whose format name
+// tells (see typeFilter.name). ok is false for other files and for files that cannot be
+// read or parsed.
+func readEmailMeta(path, name string) (EmailMeta, bool) {
+	if !isEmailFile(name) {
+		return EmailMeta{}, false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return EmailMeta{}, false
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if formatExt(name) == ".eml" {
+		r = io.LimitReader(f, maxEmailHeaderBytes)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return EmailMeta{}, false
+	}
+	return parseEmailMeta(name, data)
+}
+
+// parseEmailMeta parses the headers of an .eml or .msg message held in data; name
+// selects the format. ok is false for other formats and unparseable messages.
+func parseEmailMeta(name string, data []byte) (EmailMeta, bool) {
+	switch formatExt(name) {
+	case ".eml":
+		return parseMIMEHeaders(data)
+	case ".msg":
+		return parseMSGMeta(data)
+	}
+	return EmailMeta{}, false
+}
+
+// parseMIMEHeaders parses the header block at the start of a MIME message; the body is
+// not decoded
+func parseMIMEHeaders(data []byte) (EmailMeta, bool) {
+	end := len(data)
+	for _, sep := range []string{"\r\n\r\n", "\n\n"} {
+		if i := bytes.Index(data, []byte(sep)); i >= 0 && i < end {
+			end = i
+		}
+	}
+	header := make([]byte, 0, end+4)
+	header = append(append(header, data[:end]...), "\r\n\r\n"...)
+	env, err := enmime.ReadEnvelope(bytes.NewReader(header))
+	if err != nil {
+		return EmailMeta{}, false
+	}
+	return envelopeMeta(env), true
+}
+
+// envelopeMeta collects the headers of a parsed MIME message
+func envelopeMeta(env *enmime.Envelope) EmailMeta {
+	m := EmailMeta{
+		Subject: strings.TrimSpace(env.GetHeader("Subject")),
+		DateRaw: strings.TrimSpace(env.GetHeader("Date")),
+	}
+	if from := envelopeAddresses(env, "From"); len(from) > 0 {
+		m.Sender = from[0]
+	}
+	m.Recipients = append(envelopeAddresses(env, "To"), envelopeAddresses(env, "Cc")...)
+	if m.DateRaw != "" {
+		if t, err := mail.ParseDate(m.DateRaw); err == nil {
+			m.Date = t
+		}
+	}
+	return m
+}
+
+// envelopeAddresses returns the addresses of header key, or the raw header when it
+// does not parse as an address list
+func envelopeAddresses(env *enmime.Envelope, key string) []string {
+	list, err := env.AddressList(key)
+	if err != nil || len(list) == 0 {
+		if raw := strings.TrimSpace(env.GetHeader(key)); raw != "" {
+			return []string{raw}
+		}
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, a := range list {
+		if a.Name != "" {
+			out = append(out, a.Name+" <"+a.Address+">")
+		} else {
+			out = append(out, a.Address)
+		}
+	}
+	return out
+}
+
+// MSG property tags (MS-OXPROPS) read for headers; the last four hex digits of a
+// stream name are the property type (001F Unicode, 001E ANSI).
+const (
+	msgTransportHeaders = "__substg1.0_007D"
+	msgSubject          = "__substg1.0_0037"
+	msgSenderName       = "__substg1.0_0C1A"
+	msgSenderEmail      = "__substg1.0_0C1F"
+	msgSenderSMTP       = "__substg1.0_5D01"
+	msgDisplayTo        = "__substg1.0_0E04"
+	msgDisplayCc        = "__substg1.0_0E03"
+	msgProperties       = "__properties_version1.0"
+
+	msgClientSubmitTime = 0x00390040 // PT_SYSTIME
+	msgDeliveryTime     = 0x0E060040 // PT_SYSTIME
+)
+
+// parseMSGMeta reads the headers of an Outlook .msg: the original Internet headers when
+// the message kept them, else the sender, recipient, subject and time properties
+func parseMSGMeta(data []byte) (EmailMeta, bool) {
+	cf, err := mscfb.New(bytes.NewReader(data))
+	if err != nil {
+		return EmailMeta{}, false
+	}
+	streams := make(map[string][]byte)
+	for ent, err := cf.Next(); err == nil; ent, err = cf.Next() {
+		if len(ent.Path) != 0 {
+			continue
+		}
+		if ent.Name == msgProperties || strings.HasPrefix(ent.Name, "__substg1.0_") && !strings.HasSuffix(ent.Name, "0102") {
+			if b, _ := io.ReadAll(io.LimitReader(ent, maxEmailHeaderBytes)); len(b) > 0 {
+				streams[ent.Name] = b
+			}
+		}
+	}
+	str := func(prop string) string {
+		if b, ok := streams[prop+"001F"]; ok {
+			return msgUnicode(b)
+		}
+		return strings.TrimSpace(strings.TrimRight(string(streams[prop+"001E"]), "\x00"))
+	}
+
+	var m EmailMeta
+	if headers := str(msgTransportHeaders); headers != "" {
+		m, _ = parseMIMEHeaders([]byte(headers))
+	}
+	if m.Subject == "" {
+		m.Subject = str(msgSubject)
+	}
+	if m.Sender == "" {
+		name, addr := str(msgSenderName), str(msgSenderSMTP)
+		if addr == "" {
+			addr = str(msgSenderEmail)
+		}
+		switch {
+		case name != "" && addr != "" && name != addr:
+			m.Sender = name + " <" + addr + ">"
+		case addr != "":
+			m.Sender = addr
+		default:
+			m.Sender = name
+		}
+	}
+	if len(m.Recipients) == 0 {
+		for _, prop := range []string{msgDisplayTo, msgDisplayCc} {
+			for _, r := range strings.Split(str(prop), ";") {
+				if r = strings.TrimSpace(r); r != "" {
+					m.Recipients = append(m.Recipients, r)
+				}
+			}
+		}
+	}
+	if m.Date.IsZero() {
+		props := msgSysTimes(streams[msgProperties])
+		for _, tag := range []uint32{msgClientSubmitTime, msgDeliveryTime} {
+			if t, ok := props[tag]; ok {
+				m.Date = t
+				if m.DateRaw == "" {
+					m.DateRaw = t.Format(time.RFC1123Z)
+				}
+				break
+			}
+		}
+	}
+	return m, true
+}
+
+// msgUnicode decodes a UTF-16LE property stream
+func msgUnicode(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, binary.LittleEndian.Uint16(b[i:]))
+	}
+	return strings.TrimSpace(strings.TrimRight(string(utf16.Decode(u)), "\x00"))
+}
+
+// msgSysTimes returns the PT_SYSTIME values of a top-level __properties_version1.0 stream:
+// a 32-byte header, then 16-byte entries of tag, flags and an 8-byte FILETIME value
+func msgSysTimes(b []byte) map[uint32]time.Time {
+	const header, entry = 32, 16
+	times := make(map[uint32]time.Time)
+	for i := header; i+entry <= len(b); i += entry {
+		tag := binary.LittleEndian.Uint32(b[i:])
+		if tag&0xFFFF != 0x0040 {
+			continue
+		}
+		ft := int64(binary.LittleEndian.Uint64(b[i+8:]))
+		if ft <= 0 {
+			continue
+		}
+		// FILETIME counts 100ns intervals since 1601-01-01 UTC
+		const unixEpoch = 116444736000000000
+		ticks := ft - unixEpoch
+		times[tag] = time.Unix(ticks/1e7, ticks%1e7*100).UTC()
+	}
+	return times
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEmailMeta(t *testing.T) {
+	date := time.Date(2024, 3, 5, 14, 30, 0, 0, time.FixedZone("", 3600))
+	tests := []struct {
+		name string
+		file string
+		data string
+		want EmailMeta
+		ok   bool
+	}{
+		{"plain", "a.eml",
+			"From: ann@example.com\r\nTo: bob@example.com\r\nSubject: Invoice\r\nDate: Tue, 5 Mar 2024 14:30:00 +0100\r\n\r\nbody",
+			EmailMeta{Sender: "ann@example.com", Recipients: []string{"bob@example.com"}, Subject: "Invoice", Date: date, DateRaw: "Tue, 5 Mar 2024 14:30:00 +0100"}, true},
+		{"rfc 2047", "a.eml",
+			"From: =?UTF-8?B?SsO8cmdlbg==?= <j@example.com>\r\nSubject: =?ISO-8859-1?Q?Caf=E9_au_lait?=\r\n\r\n",
+			EmailMeta{Sender: "Jürgen <j@example.com>", Subject: "Café au lait"}, true},
+		{"address lists", "a.eml",
+			"From: \"Ann Lee\" <ann@example.com>\nTo: bob@example.com, \"Carol\" <carol@example.com>\nCc: dave@example.com\n\nbody",
+			EmailMeta{Sender: "Ann Lee <ann@example.com>", Recipients: []string{"bob@example.com", "Carol <carol@example.com>", "dave@example.com"}}, true},
+		{"unparseable list kept raw", "a.eml",
+			"To: undisclosed-recipients\r\n\r\n",
+			EmailMeta{Recipients: []string{"undisclosed-recipients"}}, true},
+		{"bad date", "a.eml",
+			"Subject: x\r\nDate: sometime last week\r\n\r\n",
+			EmailMeta{Subject: "x", DateRaw: "sometime last week"}, true},
+		{"body not parsed", "a.eml",
+			"Subject: x\r\n\r\nTo: not-a-header@example.com",
+			EmailMeta{Subject: "x"}, true},
+		{"other format", "a.txt", "Subject: x\r\n\r\n", EmailMeta{}, false},
+		{"bad msg", "a.msg", "not a compound file", EmailMeta{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseEmailMeta(tt.file, []byte(tt.data))
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if !got.Date.Equal(tt.want.Date) {
+			t.Errorf("%s: date %v, want %v", tt.name, got.Date, tt.want.Date)
+		}
+		got.Date, tt.want.Date = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEmailFilterMatch(t *testing.T) {
+	msg := EmailMeta{
+		Sender:     "Zoë Adams <zoe@example.com>",
+		Recipients: []string{"bob@example.com", "Carol <carol@corp.example>"},
+		Subject:    "Quarterly Invoice",
+		Date:       time.Date(2024, 3, 5, 12, 0, 0, 0, time.Local),
+	}
+	undated := msg
+	undated.Date = time.Time{}
+	tests := []struct {
+		name                             string
+		from, to, subject, after, before string
+		meta                             EmailMeta
+		diacritics                       bool
+		want                             bool
+	}{
+		{"no filter", "", "", "", "", "", msg, false, true},
+		{"from name", "zoë", "", "", "", "", msg, false, true},
+		{"from address", "ZOE@EXAMPLE", "", "", "", "", msg, false, true},
+		{"from without folding", "zoe adams", "", "", "", "", msg, false, false},
+		{"from folded", "zoe adams", "", "", "", "", msg, true, true},
+		{"to any recipient", "", "corp.example", "", "", "", msg, false, true},
+		{"to none", "", "dave", "", "", "", msg, false, false},
+		{"subject", "", "", "invoice", "", "", msg, false, true},
+		{"subject missing", "", "", "receipt", "", "", msg, false, false},
+		{"after", "", "", "", "2024-03-01", "", msg, false, true},
+		{"after same day", "", "", "", "2024-03-05", "", msg, false, true},
+		{"after later", "", "", "", "2024-03-06", "", msg, false, false},
+		{"before same day", "", "", "", "", "2024-03-05", msg, false, true},
+		{"before earlier", "", "", "", "", "2024-03-04", msg, false, false},
+		{"between", "", "", "", "2024-03-01", "2024-03-31", msg, false, true},
+		{"rfc 3339 after", "", "", "", "2024-03-01T00:00:00Z", "", msg, false, true},
+		{"rfc 3339 before", "", "", "", "", "2024-03-01T00:00:00Z", msg, false, false},
+		{"undated fails dates", "", "", "", "2000-01-01", "", undated, false, false},
+		{"undated without dates", "zoe", "", "", "", "", undated, false, true},
+		{"all fields", "zoe", "bob", "quarterly", "2024-01-01", "2024-12-31", msg, false, true},
+	}
+	for _, tt := range tests {
+		f, err := NewEmailFilter(tt.from, tt.to, tt.subject, tt.after, tt.before)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := f.Match(tt.meta, tt.diacritics); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	var nilFilter *EmailFilter
+	if nilFilter.Active() || !nilFilter.Match(undated, false) {
+		t.Error("a nil filter must be inactive and match everything")
+	}
+	for _, bad := range [][2]string{{"yesterday", ""}, {"", "03/05/2024"}, {"2024-03-10", "2024-03-01"}} {
+		if _, err := NewEmailFilter("", "", "", bad[0], bad[1]); err == nil {
+			t.Errorf("NewEmailFilter(after %q, before %q): want an error", bad[0], bad[1])
+		}
+	}
+}
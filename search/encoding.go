@@ -0,0 +1,190 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	stdunicode "unicode"
+	"unicode/utf8"
+
+	"github.com/gogs/chardet"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// encodingHeadBytes is how much of a text file the encoding detection looks at
+const encodingHeadBytes = 8 * 1024
+
+// multibyteEncodings are tried, in order, on text chardet takes for a single-byte charset
+// (as it does short Japanese or Chinese text): the first that decodes it strictly into the
+// scripts it is written in wins. Shift-JIS leaves out halfwidth katakana, which Chinese and
+// Korean text decodes into; EUC-KR leaves out Hanja, which Chinese text decodes into.
+var multibyteEncodings = []struct {
+	enc     encoding.Encoding
+	scripts []*stdunicode.RangeTable
+}{
+	{japanese.ShiftJIS, []*stdunicode.RangeTable{stdunicode.Han, stdunicode.Hiragana, fullwidthKatakana}},
+	{korean.EUCKR, []*stdunicode.RangeTable{stdunicode.Hangul}},
+	{simplifiedchinese.GBK, []*stdunicode.RangeTable{stdunicode.Han}},
+}
+
+// fullwidthKatakana is the Katakana script without its halfwidth forms
+var fullwidthKatakana = &stdunicode.RangeTable{
+	R16: []stdunicode.Range16{{Lo: 0x30A1, Hi: 0x30FA, Stride: 1}, {Lo: 0x30FD, Hi: 0x30FF, Stride: 1}, {Lo: 0x31F0, Hi: 0x31FF, Stride: 1}},
+}
+
+// ValidateEncoding checks an --encoding value: "auto" or an encoding name such as utf-8,
+// windows-1252, iso-8859-2, shift_jis, gb18030 or utf-16le
+func ValidateEncoding(name string) error {
+	_, err := lookupEncoding(name)
+	return err
+}
+
+// lookupEncoding returns the named encoding, by its WHATWG or IANA name (nil for "auto")
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "auto" {
+		return nil, nil
+	}
+	if enc, err := htmlindex.Get(name); err == nil {
+		return enc, nil
+	}
+	if enc, err := ianaindex.IANA.Encoding(name); err == nil && enc != nil {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", name)
+}
+
+// forcedEncoding returns the encoding --encoding imposes on text files (nil: detect it)
+func (o TextOptions) forcedEncoding() encoding.Encoding {
+	enc, _ := lookupEncoding(o.Encoding)
+	return enc
+}
+
+// detectEncoding returns the encoding of a text file from its first bytes: a byte order
+// mark, UTF-16 without one (NUL-byte pattern), UTF-8 (nil: no transcoding), or the charset
+// chardet finds most likely. When that is a
+// single-byte charset, or there is none, a multibyte encoding that decodes head strictly
+// comes first (see multibyteEncodings); Windows-1252 is the last resort. full is false
+// when head is only the start of the file.
+func detectEncoding(head []byte, full bool) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return unicode.UTF8BOM
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case isLikelyUTF16LE(head):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case isLikelyUTF16BE(head):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	}
+	if !full {
+		// The read may have cut the last character short
+		i := len(head) - 1
+		for i > 0 && len(head)-i < utf8.UTFMax && !utf8.RuneStart(head[i]) {
+			i--
+		}
+		head = head[:max(i, 0)]
+	}
+	if utf8.Valid(head) {
+		return nil
+	}
+	var guess encoding.Encoding = charmap.Windows1252
+	if res, err := chardet.NewTextDetector().DetectBest(head); err == nil {
+		if enc, err := lookupEncoding(res.Charset); err == nil && enc != nil && enc != unicode.UTF8 {
+			guess = enc
+		}
+	}
+	if _, singleByte := guess.(*charmap.Charmap); !singleByte {
+		return guess
+	}
+	for _, mb := range multibyteEncodings {
+		if decodesStrictly(mb.enc, head, full, mb.scripts) {
+			return mb.enc
+		}
+	}
+	return guess
+}
+
+// decodesStrictly reports whether head is text in enc: it decodes without invalid bytes,
+// and every character beyond ASCII is punctuation, a symbol, a space or in one of scripts.
+// A partial head may end in the middle of a character.
+func decodesStrictly(enc encoding.Encoding, head []byte, full bool, scripts []*stdunicode.RangeTable) bool {
+	out := make([]byte, 3*len(head)+utf8.UTFMax)
+	n, read, err := enc.NewDecoder().Transform(out, head, full)
+	if err != nil && (full || err != transform.ErrShortSrc || len(head)-read >= utf8.UTFMax) {
+		return false
+	}
+	multibyte := false
+	for _, r := range string(out[:n]) {
+		switch {
+		case r == utf8.RuneError:
+			return false
+		case r < utf8.RuneSelf:
+			if r < ' ' && !strings.ContainsRune("\t\n\r\f", r) {
+				return false
+			}
+		case stdunicode.In(r, scripts...):
+			multibyte = true
+		case !stdunicode.In(r, stdunicode.P, stdunicode.S, stdunicode.Zs):
+			return false
+		}
+	}
+	return multibyte
+}
+
+// textReader returns r, the content of the file at path, transcoded to UTF-8 from the
+// encoding opts impose or else the one detectEncoding finds. Binary formats are left to
+// their extractors and returned as they are.
+func textReader(path string, r io.Reader, opts TextOptions) io.Reader {
+	if IsBinaryFormat(path) {
+		return r
+	}
+	enc := opts.forcedEncoding()
+	if enc == nil {
+		br := bufio.NewReaderSize(r, encodingHeadBytes)
+		head, err := br.Peek(encodingHeadBytes)
+		if enc = detectEncoding(head, err != nil); enc == nil {
+			return br
+		}
+		r = br
+	}
+	return transform.NewReader(r, enc.NewDecoder())
+}
+
+// textStream is textReader for the streaming scans, which count what they read against a
+// budget: it returns at most limit bytes of text, so reaching the limit means the file was
+// cut. Transcoded text is capped after decoding, since its length differs from the bytes read.
+func textStream(path string, r io.Reader, limit int64, opts TextOptions) io.Reader {
+	return io.LimitReader(textReader(path, r, opts), limit)
+}
+
+// decodeText is textReader for content already in memory
+func decodeText(path string, data []byte, opts TextOptions) []byte {
+	if IsBinaryFormat(path) {
+		return data
+	}
+	enc := opts.forcedEncoding()
+	if enc == nil {
+		enc = detectEncoding(data[:min(len(data), encodingHeadBytes)], len(data) <= encodingHeadBytes)
+	}
+	if enc == nil {
+		return data
+	}
+	out, _, err := transform.Bytes(enc.NewDecoder(), data)
+	if err != nil {
+		return data
+	}
+	return out
+}
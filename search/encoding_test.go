@@ -0,0 +1,123 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("encoding %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDecodeText(t *testing.T) {
+	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	utf16be := unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"utf-8", []byte("Grüße aus Köln"), "Grüße aus Köln"},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "Grüße"...), "Grüße"},
+		{"utf-16le bom", append([]byte{0xFF, 0xFE}, encode(t, utf16le, "quarterly report")...), "quarterly report"},
+		{"utf-16be bom", append([]byte{0xFE, 0xFF}, encode(t, utf16be, "quarterly report")...), "quarterly report"},
+		{"utf-16le", encode(t, utf16le, "quarterly report"), "quarterly report"},
+		{"utf-16be", encode(t, utf16be, "quarterly report"), "quarterly report"},
+		{"windows-1252", encode(t, charmap.Windows1252, "Le café où l’été dernier nous avons mangé une crème brûlée – à côté"), "Le café où l’été dernier nous avons mangé une crème brûlée – à côté"},
+		{"short shift-jis", encode(t, japanese.ShiftJIS, "会議の議事録です"), "会議の議事録です"},
+		{"short shift-jis katakana", encode(t, japanese.ShiftJIS, "テスト"), "テスト"},
+		{"short euc-kr", encode(t, korean.EUCKR, "회의록을 확인"), "회의록을 확인"},
+		{"short gbk", encode(t, simplifiedchinese.GBK, "会议纪要，请查看"), "会议纪要，请查看"},
+	}
+	for _, tt := range tests {
+		if got := string(decodeText("notes.txt", tt.data, TextOptions{})); got != tt.want {
+			t.Errorf("%s: decodeText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDetectEncodingPartialHead(t *testing.T) {
+	// A head cut inside a character is still UTF-8, or still Shift-JIS
+	text := []byte(strings.Repeat("Grüße ", 10))
+	if enc := detectEncoding(text[:len(text)-5], false); enc != nil {
+		t.Errorf("cut UTF-8: detectEncoding = %v, want nil", enc)
+	}
+	sjis := encode(t, japanese.ShiftJIS, "会議の議事録です")
+	if enc := detectEncoding(sjis[:len(sjis)-1], false); enc != japanese.ShiftJIS {
+		t.Errorf("cut Shift-JIS: detectEncoding = %v, want Shift-JIS", enc)
+	}
+}
+
+func TestEncodingOverride(t *testing.T) {
+	opts := TextOptions{Encoding: "iso-8859-7"}
+	greek := encode(t, charmap.ISO8859_7, "Καλημέρα")
+	if got := string(decodeText("notes.txt", greek, opts)); got != "Καλημέρα" {
+		t.Errorf("--encoding iso-8859-7: decodeText = %q", got)
+	}
+	// The override applies to UTF-8 text too
+	if got := string(decodeText("notes.txt", []byte("é"), opts)); got != "Γ©" {
+		t.Errorf("--encoding iso-8859-7 on UTF-8: decodeText = %q", got)
+	}
+	if err := ValidateEncoding("no-such-encoding"); err == nil {
+		t.Error("ValidateEncoding accepted an unknown encoding")
+	}
+}
+
+func TestTextStream(t *testing.T) {
+	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	text := strings.Repeat("report ", 20)
+	for name, data := range map[string][]byte{
+		"utf-8":  []byte(text),
+		"utf-16": encode(t, utf16le, text),
+	} {
+		got, err := io.ReadAll(textStream("notes.txt", bytes.NewReader(data), 50, TextOptions{}))
+		if err != nil || string(got) != text[:50] {
+			t.Errorf("%s: textStream = %q, %v; want the first 50 bytes of text", name, got, err)
+		}
+	}
+}
+
+func TestFirstWordKeepsCutFiles(t *testing.T) {
+	utf16le := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	dir := t.TempDir()
+	files := map[string][]byte{
+		// Over the 5 MB budget once decoded: the word may lie past it
+		"long.txt":  encode(t, utf16le, strings.Repeat("filler ", 6*1024*1024/7)),
+		"short.txt": encode(t, utf16le, strings.Repeat("filler ", 1000)),
+		"match.txt": encode(t, utf16le, strings.Repeat("filler ", 1000)+"invoice"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := FindFilesWithFirstWordProgress(context.Background(), []string{dir}, []string{"invoice"}, nil, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range got {
+		got[i] = filepath.Base(got[i])
+	}
+	slices.Sort(got)
+	if want := []string{"long.txt", "match.txt"}; !slices.Equal(got, want) {
+		t.Errorf("candidates = %q, want %q", got, want)
+	}
+}
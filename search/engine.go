@@ -1,9 +1,11 @@
 package search
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
-	"regexp"
 	"slices"
 	"sort"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"find-words/config"
 	"find-words/search/pdf"
 )
 
@@ -19,16 +22,47 @@ import (
 // clamped to [240, 600] to keep the window stable.
 var ExcerptCharBudget func() int
 
-// SearchResult represents a file that matches all search criteria
+// SearchResult represents a file that matches all search criteria.
+// FilePath is relative to Root, the search root the file was found under.
+// Messages of an mbox are results of their own: FilePath is then a virtual path such as
+// "archive.mbox#msg-12" (see SplitMailboxPath) and Message holds the 1-based message number.
+// Documents inside archives are results too, as "bundle.zip!/docs/contract.docx" (see SplitArchivePath).
+// When an email matches through one of its attachments, Attachment names it (see AttachmentPath);
+// when an e-book matches within one chapter, Section holds the chapter title (for a notebook,
+// the cell: "cell 3"). Markdown files carry the fields of their YAML front matter in Meta.
+// Emails carry their parsed headers: Sender, Recipients (To and Cc) and Date (zero if unknown);
+// EmailDate keeps the Date header as written. ModTime is the modification time of the file
+// (of the mailbox or archive for a message or entry) and Score its relevance (see scoreResult).
 type SearchResult struct {
 	FilePath     string
+	Root         string
 	FileSize     int64
 	Excerpts     []string
 	CleanContent string
 	EmailDate    string
 	EmailSubject string
+	Message      int
+	Attachment   string
+	Section      string
+	Meta         FrontMatter
+	Sender       string
+	Recipients   []string
+	Date         time.Time
+	ModTime      time.Time
+	Score        float64
 }
 
+// FullPath returns the result path joined with its search root.
+func (r SearchResult) FullPath() string {
+	if r.Root == "" {
+		return r.FilePath
+	}
+	return filepath.Join(r.Root, r.FilePath)
+}
+
+// ResultFunc is an optional callback invoked once per result as soon as it is built
+type ResultFunc func(result SearchResult)
+
 // ProgressFunc is an optional callback to report progress like: processed, total, path
 type ProgressFunc func(stage string, processed, total int, path string)
 
@@ -49,18 +83,26 @@ func (cm *ConcurrencyManager) Release() {
 	<-cm.sem
 }
 
-func (cm *ConcurrencyManager) ExecuteWithTimeout(fn func(), timeout time.Duration) error {
+// ExecuteWithTimeout runs fn with a context that is cancelled once the timeout elapses
+// or the parent ctx is cancelled, so a timed-out fn is told to stop instead of leaking.
+// Returns ctx's error on cancellation or an error on timeout.
+func (cm *ConcurrencyManager) ExecuteWithTimeout(ctx context.Context, fn func(ctx context.Context), timeout time.Duration) error {
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 	done := make(chan struct{})
 
 	go func() {
+		defer close(done)
 		defer func() { _ = recover() }()
-		fn()
-		close(done)
+		fn(tctx)
 	}()
 	select {
 	case <-done:
 		return nil
-	case <-time.After(timeout):
+	case <-tctx.Done():
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		return fmt.Errorf("operation timed out")
 	}
 }
@@ -101,9 +143,29 @@ func (se *SearchEngine) pdfGovernorAllow() bool {
 	return true
 }
 
+// TextOptions are the settings that change how a search reads and matches text. They
+// hold for every file of one search; the zero value reads files as garp does without flags.
+type TextOptions struct {
+	// Terms and text are compared with diacritics folded too, so "resume" finds "résumé"
+	// (--fold-diacritics)
+	FoldDiacritics bool
+
+	// Encoding imposed on text files (--encoding): an encoding name, or "" or "auto" to
+	// detect each file's
+	Encoding string
+
+	// DOCX, ODT and DOC text also covers headers, footers, footnotes, endnotes and
+	// comments (--doc-extras)
+	DocExtras bool
+}
+
 // SearchEngine handles the multi-word search logic
 type SearchEngine struct {
-	SearchWords       []string
+	Roots       []string
+	SearchWords []string
+	// Groups holds the parsed query (see Query): every group must match, any alternative
+	// per group. nil means each of SearchWords is its own group (plain AND).
+	Groups            [][]string
 	ExcludeWords      []string
 	FileTypes         []string
 	IncludeCode       bool
@@ -114,6 +176,34 @@ type SearchEngine struct {
 	FilterWorkers     int
 	FileTimeoutBinary time.Duration
 
+	// How text is read, extracted and matched (--encoding, --doc-extras, --fold-diacritics)
+	TextOptions
+
+	// Persistent indexes (see BuildIndex) consulted for files whose size and mtime
+	// still match; other files take the normal read-and-extract path.
+	Indexes []*Index
+
+	// Which directories and files the walk visits (--hidden, --no-ignore, --exclude-dir,
+	// --include-dir and ignore files)
+	Walk WalkOptions
+
+	// Optional header filters (--from, --to, --subject, --after, --before); when active
+	// only emails (.eml, .msg, mbox messages, and such messages inside archives) can match.
+	EmailFilter *EmailFilter
+
+	// Optional front matter filter (--meta key=value); when active only Markdown files
+	// (on disk or inside archives) whose front matter passes can match.
+	MetaFilter *MetaFilter
+
+	// mbox messages and archive entries that passed filtering, keyed by virtual path,
+	// kept for buildResult
+	mailMessages   sync.Map
+	archiveEntries sync.Map
+
+	// The file selection of the current search, with the formats content sniffing found
+	// (see selection)
+	types *typeFilter
+
 	// PDF governor (defaults: pacing on, no budget)
 	pdfMinInterval   time.Duration
 	pdfBudget        int64 // 0 = unlimited
@@ -133,17 +223,25 @@ type SearchEngine struct {
 
 	// Optional progress callback (nil if unused)
 	OnProgress ProgressFunc
+
+	// Optional per-result callback (nil if unused). Execute calls it from a single
+	// goroutine while filtering is still running, in the same order results are returned.
+	OnResult ResultFunc
 }
 
+// DefaultDistance is the proximity window (in characters) unless --distance sets another
+const DefaultDistance = 5000
+
 // NewSearchEngine creates a new search engine instance
 func NewSearchEngine(searchWords, excludeWords []string, fileTypes []string, includeCode bool, heavyConcurrency int, fileTimeoutBinary int) *SearchEngine {
 	return &SearchEngine{
+		Roots:             []string{"."},
 		SearchWords:       searchWords,
 		ExcludeWords:      excludeWords,
 		FileTypes:         fileTypes,
 		IncludeCode:       includeCode,
 		Registry:          NewExtractorRegistry(),
-		Distance:          5000,
+		Distance:          DefaultDistance,
 		Silent:            false,
 		HeavyConcurrency:  heavyConcurrency,
 		FilterWorkers:     2,
@@ -166,11 +264,15 @@ func NewSearchEngineWithWorkers(searchWords, excludeWords []string, fileTypes []
 }
 
 // DiscoverCandidates finds files containing the first search word
-func (se *SearchEngine) DiscoverCandidates(fileCount int) ([]string, int, error) {
+func (se *SearchEngine) DiscoverCandidates(ctx context.Context, fileCount int) ([]string, int, error) {
 	if !se.Silent {
 		fmt.Printf("Finding files with '%s'...\n", se.SearchWords[0])
 	}
-	candidateFiles, err := FindFilesWithFirstWordProgress(se.SearchWords, se.FileTypes, se.FilterWorkers, func(processed, total int, path string) {
+	var decide func(path string, info fs.FileInfo) (bool, bool)
+	if len(se.Indexes) > 0 {
+		decide = se.indexDecide
+	}
+	candidateFiles, err := findFilesWithFirstWordProgress(ctx, se.Roots, se.Walk, se.groups(), se.selection(), se.TextOptions, se.FilterWorkers, decide, func(processed, total int, path string) {
 		if se.OnProgress != nil {
 			se.OnProgress("discovery", processed, total, path)
 		}
@@ -191,8 +293,228 @@ func (se *SearchEngine) DiscoverCandidates(fileCount int) ([]string, int, error)
 	return candidateFiles, total, nil
 }
 
+// selection returns the type filter of the current search, built from FileTypes on first
+// use; Execute starts every search with a new one
+func (se *SearchEngine) selection() typeFilter {
+	if se.types == nil {
+		types := newTypeFilter(se.FileTypes)
+		se.types = &types
+	}
+	return *se.types
+}
+
+// name returns the name the format of filePath is told from (see typeFilter.name)
+func (se *SearchEngine) name(filePath string) string {
+	return se.selection().name(filePath)
+}
+
+// splitMailbox is SplitMailboxPath for the files of the current search, which include
+// mboxes discovery placed by their content
+func (se *SearchEngine) splitMailbox(path string) (file string, n int, ok bool) {
+	i := strings.LastIndex(path, mailboxSep)
+	if i < 0 {
+		return path, 0, false
+	}
+	if _, n, ok := SplitMailboxPath(se.name(path[:i]) + path[i:]); ok {
+		return path[:i], n, true
+	}
+	return path, 0, false
+}
+
+// groups returns the query groups, deriving plain AND groups from SearchWords when unset
+func (se *SearchEngine) groups() [][]string {
+	if se.Groups != nil {
+		return se.Groups
+	}
+	return singletonGroups(se.SearchWords)
+}
+
+// indexDecide answers the full match (all words within distance, no exclude words) for
+// filePath from a fresh index entry. decided is false when no index covers the file
+// unchanged or a term cannot be looked up in the postings.
+func (se *SearchEngine) indexDecide(filePath string, info fs.FileInfo) (found bool, decided bool) {
+	name := se.name(filePath)
+	if se.DocExtras && isOfficeTextDocument(name) {
+		// Indexes hold the body text only
+		return false, false
+	}
+	if se.FoldDiacritics {
+		// Postings keep diacritics
+		return false, false
+	}
+	if se.forcedEncoding() != nil && !IsBinaryFormat(name) {
+		// Indexes hold text decoded with the detected encoding
+		return false, false
+	}
+	for _, ix := range se.Indexes {
+		entry, ok := ix.Lookup(filePath, info)
+		if !ok {
+			continue
+		}
+		found, decided = entry.MatchesGroups(se.groups(), se.Distance)
+		if !decided || !found {
+			return found, decided
+		}
+		if isMailbox(name) {
+			// Messages are matched one by one: only a whole-mailbox miss is final
+			return false, false
+		}
+		var wordExcludes []string
+		for _, exclude := range se.ExcludeWords {
+			if !strings.HasPrefix(exclude, ".") {
+				wordExcludes = append(wordExcludes, exclude)
+			}
+		}
+		excluded, decided := entry.ContainsAnyWord(wordExcludes)
+		return decided && !excluded, decided
+	}
+	return false, false
+}
+
+// matchMailbox checks every message of an mbox on its own, so the distance window never
+// spans two messages, and returns the virtual paths of the messages that match all groups
+// and contain no exclude word. ok is false when the file holds no readable messages; it is
+// then searched as a single document.
+func (se *SearchEngine) matchMailbox(ctx context.Context, filePath string, groups [][]string, mandatory, wordExcludes []string, cm *ConcurrencyManager) (units []string, ok bool) {
+	// Whole-file negatives still hold: a message can only match if the mailbox does
+	if info, err := os.Stat(filePath); err == nil && len(se.Indexes) > 0 {
+		if found, decided := se.indexDecide(filePath, info); decided && !found {
+			return nil, true
+		}
+	}
+	if len(mandatory) > 0 {
+		if found, decided := binaryPrefilter(filePath, se.name(filePath), mandatory, 1024*1024, se.TextOptions); decided && !found {
+			return nil, true
+		}
+	}
+
+	if se.MetaFilter.Active() {
+		// Messages have no front matter
+		return nil, true
+	}
+
+	n, err := readMailbox(ctx, filePath, func(n int, raw []byte) bool {
+		// One heavy slot per message, so a large mailbox does not hold one for its whole read
+		var msg mailMessage
+		var parseErr error
+		cm.Acquire()
+		err := cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+			msg, parseErr = parseMailMessage(ctx, se.Registry, n, raw)
+		}, se.FileTimeoutBinary)
+		cm.Release()
+		if err != nil || parseErr != nil {
+			// Skip an unreadable or slow message; stop only when cancelled
+			return ctx.Err() == nil
+		}
+		if !se.EmailFilter.Match(msg.Meta, se.FoldDiacritics) {
+			return true
+		}
+		text := CleanContent(msg.Text)
+		if !CheckTextMatchesGroups(text, groups, se.Distance, se.FoldDiacritics) || CheckTextContainsExcludeWords(text, wordExcludes, se.FoldDiacritics) {
+			return true
+		}
+		unit := mailboxPath(filePath, n)
+		se.mailMessages.Store(unit, msg)
+		units = append(units, unit)
+		return true
+	})
+	if err != nil && ctx.Err() == nil && !se.Silent {
+		fmt.Printf("Warning: Error reading mailbox %s: %v\n", filePath, err)
+	}
+	return units, n > 0
+}
+
+// matchArchive searches every supported document inside a zip/tar/gzip archive (and inside
+// archives nested in it) on its own and returns the virtual paths of the entries that match
+// all groups and contain no exclude word. Inner files are selected by the same file types
+// and extension excludes as files on disk.
+func (se *SearchEngine) matchArchive(ctx context.Context, filePath string, groups [][]string, wordExcludes, extExcludes []string, cm *ConcurrencyManager) []string {
+	types := se.selection()
+	// Raw-text prefilter, as for text files on disk: every mandatory word must be present
+	var prefilter []*Matcher
+	for _, t := range prefilterTerms(mandatoryTerms(groups)) {
+		prefilter = append(prefilter, newMatcher(t, wordFormSuffixes(), se.FoldDiacritics))
+	}
+	var units []string
+	w := &archiveWalker{
+		ctx: ctx,
+		want: func(name string) bool {
+			if slices.Contains(extExcludes, filepath.Ext(name)) {
+				return false
+			}
+			// A glob such as "*.log.gz" also selects the payload of a single gzip file
+			return types.allows(name) || types.allows(name+".gz")
+		},
+		fn: func(vpath string, data []byte) bool {
+			meta, isEmail := parseEmailMeta(vpath, data)
+			if se.EmailFilter.Active() && (!isEmail || !se.EmailFilter.Match(meta, se.FoldDiacritics)) {
+				return ctx.Err() == nil
+			}
+			if se.MetaFilter.Active() {
+				if !isMarkdownFile(vpath) {
+					return ctx.Err() == nil
+				}
+				if fm, ok := parseFrontMatter(data); !ok || !se.MetaFilter.Match(fm, se.FoldDiacritics) {
+					return ctx.Err() == nil
+				}
+			}
+			if !IsBinaryFormat(vpath) {
+				text := decodeText(vpath, data, se.TextOptions)
+				for _, m := range prefilter {
+					if !m.Match(text) {
+						return ctx.Err() == nil
+					}
+				}
+			}
+			content, ok := se.entryContent(ctx, vpath, data, cm)
+			if !ok {
+				return ctx.Err() == nil
+			}
+			text := CleanContent(content)
+			if CheckTextMatchesGroups(text, groups, se.Distance, se.FoldDiacritics) && !CheckTextContainsExcludeWords(text, wordExcludes, se.FoldDiacritics) {
+				se.archiveEntries.Store(vpath, archiveEntry{Size: int64(len(data)), Text: content, Meta: meta})
+				units = append(units, vpath)
+			}
+			return ctx.Err() == nil
+		},
+	}
+	if err := w.walkFile(filePath); err != nil && ctx.Err() == nil && !se.Silent {
+		fmt.Printf("Warning: Error reading archive %s: %v\n", filePath, err)
+	}
+	return units
+}
+
+// entryContent returns the text of a document read out of an archive: binary formats go
+// through their extractor (gated and timed like files on disk), text is used as-is.
+func (se *SearchEngine) entryContent(ctx context.Context, vpath string, data []byte, cm *ConcurrencyManager) (string, bool) {
+	if !IsBinaryFormat(vpath) {
+		return string(decodeText(vpath, data, se.TextOptions)), true
+	}
+	extractor, exists := se.Registry.extractorFor(formatExt(vpath), string(data))
+	if !exists {
+		return "", false
+	}
+	var text string
+	var extErr error
+	cm.Acquire()
+	err := cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+		text, extErr = extractTextContext(ctx, extractor, data)
+	}, se.FileTimeoutBinary)
+	cm.Release()
+	if err != nil || extErr != nil {
+		return "", false
+	}
+	return text, true
+}
+
 // FilterCandidates filters candidates for all words and excludes
-func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, startTime time.Time) ([]string, error) {
+func (se *SearchEngine) FilterCandidates(ctx context.Context, candidateFiles []string, total int, startTime time.Time) ([]string, error) {
+	return se.filterCandidates(ctx, candidateFiles, total, startTime, nil)
+}
+
+// filterCandidates is FilterCandidates with an optional onMatch hook, called from the
+// worker goroutines as each file passes so callers can start building results early.
+func (se *SearchEngine) filterCandidates(ctx context.Context, candidateFiles []string, total int, startTime time.Time, onMatch func(filePath string)) ([]string, error) {
 	if !se.Silent {
 		fmt.Println("Filtering for files containing ALL words...")
 	}
@@ -236,33 +558,54 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 	jobs := make(chan string, workers*4)
 	var wg sync.WaitGroup
 
+	groups := se.groups()
+	mandatory := mandatoryTerms(groups)
+
 	handleOne := func(filePath string) bool {
+		// Cancelled: drain remaining jobs without touching the files
+		if ctx.Err() != nil {
+			return false
+		}
+
 		// Check for excluded extensions
 		ext := filepath.Ext(filePath)
 		if slices.Contains(extExcludes, ext) {
 			return false
 		}
 
+		// The name the format is told from: the path, or for a sniffed file its format too
+		name := se.name(filePath)
+
+		// Unchanged indexed files are decided from their postings without reading them
+		if len(se.Indexes) > 0 {
+			if info, err := os.Stat(filePath); err == nil {
+				if found, decided := se.indexDecide(filePath, info); decided {
+					return found
+				}
+			}
+		}
+
 		// Consolidated prefilter for text files: single streaming pass on rarest-two or both terms
-		if !IsBinaryFormat(filePath) && len(se.SearchWords) >= 2 {
-			termsToCheck := se.SearchWords
-			if len(se.SearchWords) >= 3 {
-				terms := make([]string, len(se.SearchWords))
-				copy(terms, se.SearchWords)
+		// (only mandatory terms can prune: an OR group is satisfied by any alternative)
+		if literal := prefilterTerms(mandatory); !IsBinaryFormat(name) && len(literal) >= 2 {
+			termsToCheck := literal
+			if len(literal) >= 3 {
+				terms := make([]string, len(literal))
+				copy(terms, literal)
 				sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
 				termsToCheck = terms[:2]
 			}
-			found, decided := StreamContainsAllWordsDecided(filePath, termsToCheck)
+			found, decided := StreamContainsAllWordsDecided(filePath, termsToCheck, se.TextOptions)
 			if decided && !found {
 				return false
 			}
 		}
 
-		// Check if file contains all search words
+		// Check if file contains all search words (every query group)
 		hasAllWords := true
-		if len(se.SearchWords) > 1 {
-			if IsBinaryFormat(filePath) {
-				ext := filepath.Ext(filePath)
+		if len(groups) > 1 || len(groups[0]) > 1 {
+			if IsBinaryFormat(name) {
+				ext := formatExt(name)
 
 				// PDF presence-only gate (Step 2): enable guarded scan; otherwise remain disabled.
 				if strings.EqualFold(ext, ".pdf") {
@@ -296,23 +639,23 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 						matched bool
 						err     error
 					}
+					pctx, cancel := context.WithTimeout(ctx, time.Duration(config.ActiveLimits.PDFTimeoutMs)*time.Millisecond)
+					defer cancel()
 					resCh := make(chan txtRes, 1)
 					go func() {
 						defer func() { _ = recover() }()
-						t, m, e := pdf.ExtractAllTextCapped(filePath, 200, 128*1024, se.SearchWords, se.Distance)
+						t, m, e := pdf.ExtractAllTextCapped(pctx, filePath, config.ActiveLimits.PDFMaxPages, config.ActiveLimits.PDFMaxTextBytes, se.SearchWords, se.Distance, se.FoldDiacritics)
 						resCh <- txtRes{txt: t, matched: m, err: e}
 					}()
 
-					wallTimer := time.NewTimer(250 * time.Millisecond)
-					defer wallTimer.Stop()
-
 					var matched bool
+					var resText string
 					var err error
 					select {
 					case r := <-resCh:
-						matched, err = r.matched, r.err
-					case <-wallTimer.C:
-						// Timeout: undecided, do not accept based on this.
+						matched, resText, err = r.matched, r.txt, r.err
+					case <-pctx.Done():
+						// Timeout or cancellation: undecided, do not accept based on this.
 						// undecided (timeout): skipped
 						return false
 					}
@@ -321,6 +664,10 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 						// Undecided/error: do not accept based on this.
 						return false
 					}
+					// The PDF helper matches every term; OR groups need only one alternative each
+					if !matched && len(mandatory) < len(se.SearchWords) {
+						matched = CheckTextMatchesGroups(resText, groups, se.Distance, se.FoldDiacritics)
+					}
 
 					if matched {
 						hasAllWords = true
@@ -334,7 +681,10 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 					cap = int64(256 * 1024)
 				}
 				startPF := time.Now()
-				found, decided := BinaryStreamingPrefilterDecided(filePath, se.SearchWords, cap)
+				found, decided := false, false
+				if len(mandatory) > 0 {
+					found, decided = binaryPrefilter(filePath, name, mandatory, cap, se.TextOptions)
+				}
 				durPF := time.Since(startPF)
 				switch strings.ToLower(ext) {
 				case ".eml":
@@ -356,20 +706,21 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 					return false
 				} else {
 					// Extract and verify distance for multi-word binaries
-					if extractor, exists := se.Registry.GetExtractor(ext); exists {
-						content, _, err := GetFileContent(filePath)
+					if _, exists := se.Registry.GetExtractor(ext); exists {
+						content, _, err := readFileContent(filePath, name, se.TextOptions)
 						if err != nil {
 							if !se.Silent {
 								fmt.Printf("Warning: Error reading file %s: %v\n", filePath, err)
 							}
 							return false
 						}
+						extractor, _ := se.Registry.extractorFor(ext, content)
 						var extractedText string
 						var extErr error
 						startXT := time.Now()
 						cm.Acquire()
-						err = cm.ExecuteWithTimeout(func() {
-							extractedText, extErr = extractor.ExtractText([]byte(content))
+						err = cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+							extractedText, extErr = extractTextContext(ctx, extractor, []byte(content))
 						}, se.FileTimeoutBinary)
 						cm.Release()
 						durXT := time.Since(startXT)
@@ -392,7 +743,7 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 							}
 							return false
 						}
-						hasAllWords = CheckTextContainsAllWords(CleanContent(extractedText), se.SearchWords, se.Distance)
+						hasAllWords = CheckTextMatchesGroups(CleanContent(extractedText), groups, se.Distance, se.FoldDiacritics)
 					} else {
 						if !se.Silent {
 							fmt.Printf("Warning: No extractor for %s\n", ext)
@@ -402,7 +753,7 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 				}
 			} else {
 				// Text file: stream+distance
-				ok, err := CheckFileContainsAllWords(filePath, se.SearchWords, se.Distance, se.Silent)
+				ok, err := CheckFileMatchesGroups(filePath, groups, se.Distance, se.Silent, se.TextOptions)
 				if err != nil {
 					if !se.Silent {
 						fmt.Printf("Warning: Error checking file %s: %v\n", filePath, err)
@@ -414,14 +765,14 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 		} else {
 			// Single-word presence check
 			word := se.SearchWords[0]
-			if IsBinaryFormat(filePath) {
-				ext := filepath.Ext(filePath)
+			if IsBinaryFormat(name) {
+				ext := formatExt(name)
 				// Run bounded prefilter for binary types (honor PDFs to avoid unnecessary extraction)
 				cap := int64(1024 * 1024)
 				if strings.EqualFold(ext, ".eml") || strings.EqualFold(ext, ".msg") {
 					cap = int64(256 * 1024)
 				}
-				foundPF, decidedPF := BinaryStreamingPrefilterDecided(filePath, []string{word}, cap)
+				foundPF, decidedPF := binaryPrefilter(filePath, name, []string{word}, cap, se.TextOptions)
 				// Decided negative => safe skip
 				if decidedPF && !foundPF {
 					return false
@@ -443,7 +794,7 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 						case <-tokenTimer.C:
 							return false
 						}
-						foundOne, decidedOne := PDFPresenceOnlyPathCapped(filePath, []string{word}, 250, 800*time.Millisecond)
+						foundOne, decidedOne := PDFPresenceOnlyPathCapped(filePath, []string{word}, config.ActiveLimits.PDFMaxPages, time.Duration(config.ActiveLimits.PDFTimeoutMs)*time.Millisecond, se.FoldDiacritics)
 						if decidedOne && !foundOne {
 							return false
 						}
@@ -453,20 +804,20 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 					}
 				} else {
 					// Bounded extraction fallback under semaphore + timeout
-					rawContent, _, err := GetFileContent(filePath)
+					rawContent, _, err := readFileContent(filePath, name, se.TextOptions)
 					if err != nil {
 						if !se.Silent {
 							fmt.Printf("Warning: Error reading file %s: %v\n", filePath, err)
 						}
 						return false
 					}
-					if extractor, exists := se.Registry.GetExtractor(ext); exists {
+					if extractor, exists := se.Registry.extractorFor(ext, rawContent); exists {
 						var extractedText string
 						var extErr error
 						startXT := time.Now()
 						cm.Acquire()
-						err = cm.ExecuteWithTimeout(func() {
-							extractedText, extErr = extractor.ExtractText([]byte(rawContent))
+						err = cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+							extractedText, extErr = extractTextContext(ctx, extractor, []byte(rawContent))
 						}, se.FileTimeoutBinary)
 						cm.Release()
 						durXT := time.Since(startXT)
@@ -488,7 +839,7 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 							}
 							return false
 						}
-						hasAllWords = CheckTextContainsAllWords(CleanContent(extractedText), []string{word}, se.Distance)
+						hasAllWords = CheckTextContainsAllWords(CleanContent(extractedText), []string{word}, se.Distance, se.FoldDiacritics)
 					} else {
 						if !se.Silent {
 							fmt.Printf("Warning: No extractor for %s\n", ext)
@@ -497,7 +848,7 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 					}
 				}
 			} else {
-				ok, err := CheckFileContainsAllWords(filePath, []string{word}, se.Distance, se.Silent)
+				ok, err := CheckFileContainsAllWords(filePath, []string{word}, se.Distance, se.Silent, se.TextOptions)
 				if err != nil {
 					if !se.Silent {
 						fmt.Printf("Warning: Error checking file %s: %v\n", filePath, err)
@@ -514,22 +865,22 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 
 		// Check if file contains any exclude words
 		hasExcludeWords := false
-		if len(wordExcludes) > 0 && IsBinaryFormat(filePath) {
+		if len(wordExcludes) > 0 && IsBinaryFormat(name) {
 			// For binary files, extract text (gated and timed)
-			rawContent, _, err := GetFileContent(filePath)
+			rawContent, _, err := readFileContent(filePath, name, se.TextOptions)
 			if err != nil {
 				if !se.Silent {
 					fmt.Printf("Warning: Error reading file %s: %v\n", filePath, err)
 				}
 				return false
 			}
-			ext := filepath.Ext(filePath)
-			if extractor, exists := se.Registry.GetExtractor(ext); exists {
+			ext := formatExt(name)
+			if extractor, exists := se.Registry.extractorFor(ext, rawContent); exists {
 				var out string
 				var extErr error
 				cm.Acquire()
-				err := cm.ExecuteWithTimeout(func() {
-					out, extErr = extractor.ExtractText([]byte(rawContent))
+				err := cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+					out, extErr = extractTextContext(ctx, extractor, []byte(rawContent))
 				}, se.FileTimeoutBinary)
 				cm.Release()
 				if err != nil || extErr != nil {
@@ -543,7 +894,7 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 					return false
 				}
 				// Compute exclude words from extracted text (cleaned)
-				hasExcludeWords = CheckTextContainsExcludeWords(CleanContent(out), wordExcludes)
+				hasExcludeWords = CheckTextContainsExcludeWords(CleanContent(out), wordExcludes, se.FoldDiacritics)
 			} else {
 				if !se.Silent {
 					fmt.Printf("Warning: No extractor for %s\n", ext)
@@ -551,7 +902,7 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 				return false
 			}
 		} else if len(wordExcludes) > 0 {
-			ok2, err := CheckFileContainsExcludeWords(filePath, wordExcludes)
+			ok2, err := CheckFileContainsExcludeWords(filePath, wordExcludes, se.TextOptions)
 			if err != nil {
 				if !se.Silent {
 					fmt.Printf("Warning: Error checking exclude words in %s: %v\n", filePath, err)
@@ -574,13 +925,39 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 		go func() {
 			defer wg.Done()
 			for filePath := range jobs {
-				matched := handleOne(filePath)
+				// An mbox is matched message by message, each matching message being its own unit,
+				// and every document inside an archive is searched on its own
+				var units []string
+				handled := false
+				if slices.Contains(extExcludes, filepath.Ext(filePath)) {
+					handled = true
+				} else if isArchive(filePath) {
+					units, handled = se.matchArchive(ctx, filePath, groups, wordExcludes, extExcludes, cm), true
+				} else if isMailbox(se.name(filePath)) {
+					units, handled = se.matchMailbox(ctx, filePath, groups, mandatory, wordExcludes, cm)
+				}
+				if !handled && se.EmailFilter.Active() {
+					// Header filters: only emails whose headers pass are searched
+					meta, ok := readEmailMeta(filePath, se.name(filePath))
+					handled = !ok || !se.EmailFilter.Match(meta, se.FoldDiacritics)
+				}
+				if !handled && se.MetaFilter.Active() {
+					// Front matter filters: only Markdown files whose front matter passes are searched
+					fm, ok := readFrontMatter(filePath, se.TextOptions)
+					handled = !ok || !se.MetaFilter.Match(fm, se.FoldDiacritics)
+				}
+				if !handled && handleOne(filePath) {
+					units = []string{filePath}
+				}
 
 				// Append results if matched
-				if matched {
+				for _, unit := range units {
 					mu.Lock()
-					matchingFiles = append(matchingFiles, filePath)
+					matchingFiles = append(matchingFiles, unit)
 					mu.Unlock()
+					if onMatch != nil {
+						onMatch(unit)
+					}
 				}
 
 				// Atomic progress update
@@ -599,212 +976,314 @@ func (se *SearchEngine) FilterCandidates(candidateFiles []string, total int, sta
 		}()
 	}
 
-	// Enqueue jobs
+	// Enqueue jobs (stop feeding once cancelled)
+enqueue:
 	for _, p := range candidateFiles {
-		jobs <- p
+		select {
+		case jobs <- p:
+		case <-ctx.Done():
+			break enqueue
+		}
 	}
 	close(jobs)
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return matchingFiles, err
+	}
 	return matchingFiles, nil
 }
 
 // ExtractAndBuildResults extracts content and builds search results
-func (se *SearchEngine) ExtractAndBuildResults(matchingFiles []string) ([]SearchResult, error) {
+func (se *SearchEngine) ExtractAndBuildResults(ctx context.Context, matchingFiles []string) ([]SearchResult, error) {
 	results := make([]SearchResult, 0, len(matchingFiles))
 	cm := NewConcurrencyManager(se.HeavyConcurrency)
 
 	for _, filePath := range matchingFiles {
-		var content string
-		var fileSize int64
-		var err error
-		var emailDate, emailSubject string
-
-		if IsBinaryFormat(filePath) {
-			// For binary files, extract text
-			rawContent, size, err := GetFileContent(filePath)
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result, ok := se.buildResult(ctx, filePath, cm)
+		if !ok {
+			continue
+		}
+		results = append(results, result)
+		if se.OnResult != nil {
+			se.OnResult(result)
+		}
+	}
+	return results, nil
+}
+
+// buildResult extracts content for one matching file and builds its search result
+// (cleaned content, excerpts, email metadata). Returns false when the file is skipped.
+func (se *SearchEngine) buildResult(ctx context.Context, filePath string, cm *ConcurrencyManager) (SearchResult, bool) {
+	var content string
+	var fileSize int64
+	var err error
+	var meta EmailMeta
+	var message int
+	var from TextPart // the part of a message or e-book the result is shown from
+
+	name := se.name(filePath)
+	if file, n, ok := se.splitMailbox(filePath); ok {
+		// One message of an mbox: normally kept from filtering, else re-read
+		var msg mailMessage
+		if v, cached := se.mailMessages.LoadAndDelete(filePath); cached {
+			msg = v.(mailMessage)
+		} else if msg, err = loadMailMessage(ctx, se.Registry, file, n); err != nil {
+			if !se.Silent {
+				fmt.Printf("Warning: Error reading message %s: %v\n", filePath, err)
+			}
+			return SearchResult{}, false
+		}
+		content, from = se.attributePart(msg.Parts)
+		fileSize = msg.Size
+		meta, message = msg.Meta, n
+	} else if _, _, ok := SplitArchivePath(filePath); ok {
+		// One document inside an archive: normally kept from filtering, else re-read
+		if v, cached := se.archiveEntries.LoadAndDelete(filePath); cached {
+			entry := v.(archiveEntry)
+			content, fileSize, meta = entry.Text, entry.Size, entry.Meta
+		} else {
+			data, err := loadArchiveEntry(ctx, filePath)
 			if err != nil {
 				if !se.Silent {
-					fmt.Printf("Warning: Error reading file %s: %v\n", filePath, err)
+					fmt.Printf("Warning: Error reading %s: %v\n", filePath, err)
 				}
-				continue
+				return SearchResult{}, false
 			}
-			fileSize = size
-			ext := filepath.Ext(filePath)
-
-			// Best-effort email metadata for EML/MSG from raw headers (without heavy parsing)
-			if strings.EqualFold(ext, ".eml") || strings.EqualFold(ext, ".msg") {
-				if m := regexp.MustCompile(`(?mi)^Date:\s*(.+)$`).FindStringSubmatch(rawContent); m != nil {
-					emailDate = strings.TrimSpace(m[1])
-				}
-				if m := regexp.MustCompile(`(?mi)^Subject:\s*(.+)$`).FindStringSubmatch(rawContent); m != nil {
-					emailSubject = strings.TrimSpace(m[1])
-				}
-			}
-
-			if strings.EqualFold(ext, ".pdf") && enablePDFs {
-				// Try-acquire global PDF token with 50ms deadline to serialize pdfcpu usage
-				tokenTimer := time.NewTimer(50 * time.Millisecond)
-				acquired := false
-				select {
-				case pdfSem <- struct{}{}:
-					acquired = true
-				case <-tokenTimer.C:
-					// Could not acquire quickly; treat as undecided and skip quietly
-				}
-				if !acquired {
-					continue
-				}
-				defer func() { <-pdfSem }()
-				// Bounded PDF text extraction via pdfcpu helper with strict wall timeout and caps
-				var txt string
-				var perr error
-				if errTimeout := cm.ExecuteWithTimeout(func() {
-					t, _, e := pdf.ExtractAllTextCapped(filePath, 200, 128*1024, se.SearchWords, se.Distance)
-					if e != nil {
-						perr = e
-						return
-					}
-					txt = t
-				}, 250*time.Millisecond); errTimeout != nil || perr != nil {
-					// Suppress pdfcpu errors/timeouts in extraction; treat as undecided and skip quietly
-					continue
-				}
-				content = txt
-			} else if extractor, exists := se.Registry.GetExtractor(ext); exists {
-				err = cm.ExecuteWithTimeout(func() {
-					content, err = extractor.ExtractText([]byte(rawContent))
-				}, se.FileTimeoutBinary)
-				if err != nil {
-					if !se.Silent {
-						fmt.Printf("Warning: Error extracting text from %s: %v\n", filePath, err)
-					}
-					continue
-				}
-			} else {
-				if !se.Silent {
-					fmt.Printf("Warning: No extractor for %s\n", ext)
+			text, ok := se.entryContent(ctx, filePath, data, cm)
+			if !ok {
+				return SearchResult{}, false
+			}
+			content, fileSize = text, int64(len(data))
+			meta, _ = parseEmailMeta(filePath, data)
+		}
+	} else if IsBinaryFormat(name) {
+		// For binary files, extract text
+		rawContent, size, err := readFileContent(filePath, name, se.TextOptions)
+		if err != nil {
+			if !se.Silent {
+				fmt.Printf("Warning: Error reading file %s: %v\n", filePath, err)
+			}
+			return SearchResult{}, false
+		}
+		fileSize = size
+		ext := formatExt(name)
+
+		// Email headers for EML/MSG (parsed MIME headers, or MSG property streams)
+		meta, _ = parseEmailMeta(name, []byte(rawContent))
+
+		if strings.EqualFold(ext, ".pdf") && enablePDFs {
+			// Try-acquire global PDF token with 50ms deadline to serialize pdfcpu usage
+			tokenTimer := time.NewTimer(50 * time.Millisecond)
+			acquired := false
+			select {
+			case pdfSem <- struct{}{}:
+				acquired = true
+			case <-tokenTimer.C:
+				// Could not acquire quickly; treat as undecided and skip quietly
+			}
+			if !acquired {
+				return SearchResult{}, false
+			}
+			defer func() { <-pdfSem }()
+			// Bounded PDF text extraction via pdfcpu helper with strict wall timeout and caps
+			var txt string
+			var perr error
+			if errTimeout := cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+				t, _, e := pdf.ExtractAllTextCapped(ctx, filePath, config.ActiveLimits.PDFMaxPages, config.ActiveLimits.PDFMaxTextBytes, se.SearchWords, se.Distance, se.FoldDiacritics)
+				if e != nil {
+					perr = e
+					return
 				}
-				continue
+				txt = t
+			}, time.Duration(config.ActiveLimits.PDFTimeoutMs)*time.Millisecond); errTimeout != nil || perr != nil {
+				// Suppress pdfcpu errors/timeouts in extraction; treat as undecided and skip quietly
+				return SearchResult{}, false
+			}
+			content = txt
+		} else if extractor, exists := se.Registry.extractorFor(ext, rawContent); exists {
+			var parts []TextPart
+			var extErr error
+			err = cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+				parts, extErr = extractParts(ctx, extractor, []byte(rawContent))
+			}, se.FileTimeoutBinary)
+			if err == nil {
+				err = extErr
 			}
-		} else {
-			content, fileSize, err = GetFileContent(filePath)
 			if err != nil {
 				if !se.Silent {
-					fmt.Printf("Warning: Error reading file %s: %v\n", filePath, err)
+					fmt.Printf("Warning: Error extracting text from %s: %v\n", filePath, err)
 				}
-				continue
+				return SearchResult{}, false
 			}
-		}
-
-		// Clean content and extract excerpts (make excerpt window reflect distance)
-		cleanContent := CleanContent(content)
-		boundedClean := cleanContent
-		if len(boundedClean) > 64*1024 {
-			boundedClean = boundedClean[:64*1024]
-		}
-		// Compute excerpt char budget from UI width (if provided) to keep the window stable.
-		// Budget = innerWidth * 5, clamped to [240, 600]. Fallback to 400 if not provided.
-		budget := 400
-		if ExcerptCharBudget != nil {
-			if b := ExcerptCharBudget(); b > 0 {
-				budget = b
+			content, from = se.attributePart(parts)
+		} else {
+			if !se.Silent {
+				fmt.Printf("Warning: No extractor for %s\n", ext)
 			}
+			return SearchResult{}, false
 		}
-		if budget < 240 {
-			budget = 240
-		}
-		if budget > 600 {
-			budget = 600
+	} else {
+		content, fileSize, err = readFileContent(filePath, name, se.TextOptions)
+		if err != nil {
+			if !se.Silent {
+				fmt.Printf("Warning: Error reading file %s: %v\n", filePath, err)
+			}
+			return SearchResult{}, false
 		}
+	}
 
-		// Map the character budget to a context limit for excerpt generation (roughly half).
-		SetExcerptContextLimit(budget / 2)
+	var frontMatter FrontMatter
+	if isMarkdownFile(filePath) {
+		frontMatter, _ = parseFrontMatter([]byte(content))
+	}
 
-		// Single excerpt keeps the UI height stable.
-		excerpts := ExtractMeaningfulExcerpts(cleanContent, se.SearchWords, 1)
+	// Clean content and extract excerpts (make excerpt window reflect distance)
+	cleanContent := CleanContent(content)
+	boundedClean := cleanContent
+	if len(boundedClean) > 64*1024 {
+		boundedClean = boundedClean[:64*1024]
+	}
+	// Compute excerpt char budget from UI width (if provided) to keep the window stable.
+	// Budget = innerWidth * 5, clamped to [240, 600]. Fallback to 400 if not provided.
+	budget := 400
+	if ExcerptCharBudget != nil {
+		if b := ExcerptCharBudget(); b > 0 {
+			budget = b
+		}
+	}
+	if budget < 240 {
+		budget = 240
+	}
+	if budget > 600 {
+		budget = 600
+	}
 
-		// If excerpts are very short (e.g., only a single terse sentence), expand the first excerpt
-		// by pulling in neighboring sentences to provide more context. This helps fill the UI box
-		// when there is little content returned.
-		if len(excerpts) == 1 && len(excerpts[0]) < 160 {
-			// Local helper to expand context from surrounding sentences up to a target length.
-			expandShort := func(clean, ex string, terms []string, target int) string {
-				sents := splitIntoSentences(clean)
-				if len(sents) == 0 {
-					return ex
-				}
-				// Find a sentence that contains the excerpt, or the first that contains any term.
-				best := -1
-				for i, s := range sents {
-					if strings.Contains(s, ex) {
-						best = i
-						break
-					}
-					if best == -1 && containsAnySearchTerm(s, terms) {
-						best = i
-					}
+	// Map the character budget to a context limit for excerpt generation (roughly half).
+	SetExcerptContextLimit(budget / 2)
+
+	// Single excerpt keeps the UI height stable.
+	excerpts := ExtractMeaningfulExcerpts(cleanContent, se.SearchWords, 1, se.FoldDiacritics)
+
+	// If excerpts are very short (e.g., only a single terse sentence), expand the first excerpt
+	// by pulling in neighboring sentences to provide more context. This helps fill the UI box
+	// when there is little content returned.
+	if len(excerpts) == 1 && len(excerpts[0]) < 160 {
+		// Local helper to expand context from surrounding sentences up to a target length.
+		expandShort := func(clean, ex string, terms []string, target int) string {
+			sents := splitIntoSentences(clean)
+			if len(sents) == 0 {
+				return ex
+			}
+			// Find a sentence that contains the excerpt, or the first that contains any term.
+			best := -1
+			for i, s := range sents {
+				if strings.Contains(s, ex) {
+					best = i
+					break
 				}
-				if best == -1 {
-					return ex
+				if best == -1 && containsAnySearchTerm(s, terms, se.FoldDiacritics) {
+					best = i
 				}
+			}
+			if best == -1 {
+				return ex
+			}
 
-				out := []string{strings.TrimSpace(sents[best])}
-				l, r := best-1, best+1
+			out := []string{strings.TrimSpace(sents[best])}
+			l, r := best-1, best+1
 
-				// Grow context outward until we reach the target length or run out of sentences.
-				for (l >= 0 || r < len(sents)) && len(strings.Join(out, " ")) < target {
-					if l >= 0 {
-						left := strings.TrimSpace(sents[l])
-						if left != "" {
-							out = append([]string{left}, out...)
-						}
-						l--
+			// Grow context outward until we reach the target length or run out of sentences.
+			for (l >= 0 || r < len(sents)) && len(strings.Join(out, " ")) < target {
+				if l >= 0 {
+					left := strings.TrimSpace(sents[l])
+					if left != "" {
+						out = append([]string{left}, out...)
 					}
-					if r < len(sents) && len(strings.Join(out, " ")) < target {
-						right := strings.TrimSpace(sents[r])
-						if right != "" {
-							out = append(out, right)
-						}
-						r++
+					l--
+				}
+				if r < len(sents) && len(strings.Join(out, " ")) < target {
+					right := strings.TrimSpace(sents[r])
+					if right != "" {
+						out = append(out, right)
 					}
+					r++
 				}
-
-				merged := strings.Join(out, " ")
-				// Normalize whitespace
-				merged = strings.Join(strings.Fields(merged), " ")
-				return merged
 			}
 
-			expanded := expandShort(cleanContent, excerpts[0], se.SearchWords, 600)
-			if len(expanded) > len(excerpts[0]) {
-				excerpts[0] = expanded
-			}
+			merged := strings.Join(out, " ")
+			// Normalize whitespace
+			merged = strings.Join(strings.Fields(merged), " ")
+			return merged
 		}
 
-		// Highlight search terms in excerpts
-		highlightedExcerpts := make([]string, len(excerpts))
-		for i, excerpt := range excerpts {
-			highlightedExcerpts[i] = HighlightTerms(excerpt, se.SearchWords)
+		expanded := expandShort(cleanContent, excerpts[0], se.SearchWords, 600)
+		if len(expanded) > len(excerpts[0]) {
+			excerpts[0] = expanded
 		}
+	}
 
-		result := SearchResult{
-			FilePath:     filePath,
-			FileSize:     fileSize,
-			Excerpts:     highlightedExcerpts,
-			CleanContent: boundedClean,
-			EmailDate:    emailDate,
-			EmailSubject: emailSubject,
-		}
+	// Highlight search terms in excerpts
+	highlightedExcerpts := make([]string, len(excerpts))
+	for i, excerpt := range excerpts {
+		highlightedExcerpts[i] = HighlightTerms(excerpt, se.SearchWords, se.FoldDiacritics)
+	}
 
-		results = append(results, result)
+	attachment, section := from.Name, ""
+	if from.Section {
+		attachment, section = "", from.Name
 	}
-	return results, nil
+	root, relPath := se.splitRoot(filePath)
+	result := SearchResult{
+		FilePath:     relPath,
+		Root:         root,
+		FileSize:     fileSize,
+		Excerpts:     highlightedExcerpts,
+		CleanContent: boundedClean,
+		EmailDate:    meta.DateRaw,
+		EmailSubject: meta.Subject,
+		Message:      message,
+		Attachment:   attachment,
+		Section:      section,
+		Meta:         frontMatter,
+		Sender:       meta.Sender,
+		Recipients:   meta.Recipients,
+		Date:         meta.Date,
+		ModTime:      se.modTime(filePath),
+	}
+	result.Score = se.scoreResult(result, cleanContent)
+
+	return result, true
 }
 
-// Execute performs the complete search operation
-func (se *SearchEngine) Execute() ([]SearchResult, error) {
+// attributePart picks the text a message result is shown from: the body when it matches
+// the query on its own, else the first attachment that does (which is returned as from).
+// E-book chapters are picked the same way. A match that needs several parts together is
+// shown from all of them, unattributed.
+func (se *SearchEngine) attributePart(parts []TextPart) (text string, from TextPart) {
+	if len(parts) > 1 {
+		groups := se.groups()
+		for _, p := range parts {
+			if CheckTextMatchesGroups(CleanContent(p.Text), groups, se.Distance, se.FoldDiacritics) {
+				return p.Text, p
+			}
+		}
+	}
+	if len(parts) == 1 && parts[0].Section {
+		return parts[0].Text, parts[0]
+	}
+	return joinParts(parts), TextPart{}
+}
+
+// Execute performs the complete search operation. Cancelling ctx stops discovery,
+// filtering and extraction promptly; the results built so far are returned with ctx's error.
+func (se *SearchEngine) Execute(ctx context.Context) ([]SearchResult, error) {
 	startTime := time.Now()
+	se.types = nil // sniffed formats are only kept for one search
+	se.Registry.options = se.TextOptions
 
 	// Emit initial progress with unknown total (0); discovery will update it
 	if se.OnProgress != nil {
@@ -812,7 +1291,7 @@ func (se *SearchEngine) Execute() ([]SearchResult, error) {
 	}
 
 	// Step 2: Discover candidates
-	candidateFiles, total, err := se.DiscoverCandidates(0)
+	candidateFiles, total, err := se.DiscoverCandidates(ctx, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -823,10 +1302,40 @@ func (se *SearchEngine) Execute() ([]SearchResult, error) {
 		return nil, nil
 	}
 
-	// Step 3: Filter candidates
-	matchingFiles, err := se.FilterCandidates(candidateFiles, total, startTime)
+	// Step 3 + 4: Filter candidates and build results as matches come in.
+	// A single builder goroutine keeps excerpt generation sequential (it shares
+	// package-level excerpt settings) while filter workers keep scanning.
+	matched := make(chan string, 64)
+	var results []SearchResult
+	built := make(chan struct{})
+	go func() {
+		defer close(built)
+		cm := NewConcurrencyManager(se.HeavyConcurrency)
+		for filePath := range matched {
+			if ctx.Err() != nil {
+				// Keep draining so filter workers never block
+				continue
+			}
+			result, ok := se.buildResult(ctx, filePath, cm)
+			if !ok {
+				continue
+			}
+			results = append(results, result)
+			if se.OnResult != nil {
+				se.OnResult(result)
+			}
+		}
+	}()
+	matchingFiles, err := se.filterCandidates(ctx, candidateFiles, total, startTime, func(filePath string) {
+		matched <- filePath
+	})
+	close(matched)
+	<-built
+	if err == nil {
+		err = ctx.Err()
+	}
 	if err != nil {
-		return nil, err
+		return results, err
 	}
 	if len(matchingFiles) == 0 {
 		if !se.Silent {
@@ -834,15 +1343,8 @@ func (se *SearchEngine) Execute() ([]SearchResult, error) {
 		}
 		return nil, nil
 	}
-
-	// Step 4: Extract and build results
 	if !se.Silent {
-		fmt.Printf("Found %s files containing all words. Extracting content...\n", formatNumber(len(matchingFiles)))
-	}
-
-	results, err := se.ExtractAndBuildResults(matchingFiles)
-	if err != nil {
-		return nil, err
+		fmt.Printf("Found %s files containing all words.\n", formatNumber(len(matchingFiles)))
 	}
 
 	totalTime := time.Since(startTime)
@@ -881,6 +1383,26 @@ func (se *SearchEngine) Execute() ([]SearchResult, error) {
 	return results, nil
 }
 
+// splitRoot returns the search root a discovered path came from and the path relative to it.
+// The longest matching root wins so nested roots report the closest one.
+func (se *SearchEngine) splitRoot(filePath string) (string, string) {
+	roots := se.Roots
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	bestRoot, bestRel := "", filePath
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if bestRoot == "" || len(root) > len(bestRoot) {
+			bestRoot, bestRel = root, rel
+		}
+	}
+	return bestRoot, bestRel
+}
+
 // GetAbsolutePath returns the absolute path for a file
 func GetAbsolutePath(filePath string) string {
 	if filepath.IsAbs(filePath) {
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"find-words/config"
+)
+
+func TestExecuteStreamsResults(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"a.txt": "invoice one",
+		"b.txt": "invoice two",
+		"c.txt": "receipt",
+		"d.md":  "# Invoice three",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	se := NewSearchEngine([]string{"invoice"}, nil, nil, false, 2, 1000)
+	se.Roots = []string{dir}
+	se.Silent = true
+	var streamed []string
+	se.OnResult = func(r SearchResult) {
+		streamed = append(streamed, r.FullPath())
+	}
+	results, err := se.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || len(streamed) != len(results) {
+		t.Fatalf("%d results, %d streamed; want 3 of each", len(results), len(streamed))
+	}
+	for i, r := range results {
+		if streamed[i] != r.FullPath() {
+			t.Errorf("result %d: streamed %s, returned %s", i, streamed[i], r.FullPath())
+		}
+	}
+}
+
+func TestExecuteCancelled(t *testing.T) {
+	dir := t.TempDir()
+	const files = 500
+	for i := range files {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%03d.txt", i)), []byte("invoice"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newEngine := func() *SearchEngine {
+		se := NewSearchEngine([]string{"invoice"}, nil, nil, false, 2, 1000)
+		se.Roots = []string{dir}
+		se.Silent = true
+		return se
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if results, err := newEngine().Execute(ctx); !errors.Is(err, context.Canceled) || len(results) != 0 {
+		t.Errorf("cancelled before the search: %d results, err %v; want none and %v", len(results), err, context.Canceled)
+	}
+
+	// Cancelling on the first result stops the search: no result is built after it
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	se := newEngine()
+	se.OnResult = func(SearchResult) { cancel() }
+	done := make(chan struct{})
+	var results []SearchResult
+	var err error
+	go func() {
+		defer close(done)
+		results, err = se.Execute(ctx)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Execute did not return after its context was cancelled")
+	}
+	if !errors.Is(err, context.Canceled) || len(results) != 1 {
+		t.Errorf("cancelled on the first result: %d results, err %v; want 1 and %v", len(results), err, context.Canceled)
+	}
+}
+
+func TestExecutePhrase(t *testing.T) {
+	dir := t.TempDir()
+	docx := func(paragraphs ...string) string {
+		body := ""
+		for _, p := range paragraphs {
+			body += `<w:p><w:r><w:t>` + p + `</w:t></w:r></w:p>`
+		}
+		return string(zipFile(t, "[Content_Types].xml", "<Types/>", "word/document.xml", `<w:document `+wordNS+`><w:body>`+body+`</w:body></w:document>`))
+	}
+	files := map[string]string{
+		"adjacent.txt":   "wire transfer approval",
+		"lines.md":       "the wire\n   transfer\r\nneeds approval",
+		"plural.txt":     "two Wire Transfers need approval",
+		"swapped.txt":    "transfer wire approval",
+		"apart.txt":      "a wire, then a transfer, awaiting approval",
+		"unapproved.txt": "wire transfer",
+		"split.docx":     docx("wire", "transfer approval"),
+		"between.docx":   docx("wire money transfer", "approval"),
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	se := NewSearchEngine([]string{"wire transfer", "approval"}, nil, nil, false, 2, 1000)
+	se.Roots = []string{dir}
+	se.Silent = true
+	results, err := se.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, filepath.Base(r.FullPath()))
+	}
+	slices.Sort(got)
+	if want := []string{"adjacent.txt", "lines.md", "plural.txt", "split.docx"}; !slices.Equal(got, want) {
+		t.Errorf("results %q, want %q", got, want)
+	}
+
+	// The phrase is highlighted as a whole, line break included
+	want := "the \033[1;31mwire\n   transfer\033[0m needs"
+	if got := HighlightTerms("the wire\n   transfer needs", []string{"wire transfer"}, false); got != want {
+		t.Errorf("HighlightTerms = %q, want %q", got, want)
+	}
+}
+
+func TestPrefilterReadLimits(t *testing.T) {
+	defer func() { config.ActiveLimits = config.DefaultLimits }()
+	config.ActiveLimits.MediumFileBytes = 64
+	config.ActiveLimits.MediumFileRead = 32
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte("invoice "+strings.Repeat("x", 100)+" receipt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !StreamContainsWord(path, "invoice") || StreamContainsWord(path, "receipt") {
+		t.Error("StreamContainsWord must read only the configured medium-file bytes")
+	}
+	// A word past the limit is undecided, not absent
+	if found, decided := StreamContainsAllWordsDecided(path, []string{"receipt"}, TextOptions{}); found || decided {
+		t.Errorf("StreamContainsAllWordsDecided past the limit = %v, %v; want false, false", found, decided)
+	}
+	config.ActiveLimits = config.DefaultLimits
+	if !StreamContainsWord(path, "receipt") {
+		t.Error("StreamContainsWord must read the whole file under the default limits")
+	}
+}
@@ -2,13 +2,20 @@ package search
 
 import (
 	"archive/zip"
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -19,6 +26,8 @@ import (
 	"github.com/richardlehane/mscfb"
 	"golang.org/x/text/encoding/unicode"
 	"golang.org/x/text/transform"
+
+	"find-words/config"
 )
 
 // Extractor defines the interface for extracting text from binary or encoded document formats
@@ -27,6 +36,25 @@ type Extractor interface {
 	ExtractText(data []byte) (string, error)
 }
 
+// ContextExtractor is implemented by extractors whose work can run long enough
+// (many messages, many streams) to be worth abandoning when ctx is cancelled.
+type ContextExtractor interface {
+	Extractor
+	// ExtractTextContext is ExtractText that stops early and returns ctx's error once ctx is done
+	ExtractTextContext(ctx context.Context, data []byte) (string, error)
+}
+
+// extractTextContext prefers the context-aware path when the extractor offers one
+func extractTextContext(ctx context.Context, ex Extractor, data []byte) (string, error) {
+	if ce, ok := ex.(ContextExtractor); ok {
+		return ce.ExtractTextContext(ctx, data)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return ex.ExtractText(data)
+}
+
 // pdfPagesTruncated counts the number of PDF pages truncated for safety.
 var pdfPagesTruncated int64
 
@@ -40,8 +68,9 @@ const PDFPageTextCapBytes = 131072
 //   - found=false, decided=true  => conclusively absent within bounds
 //   - found=false, decided=false => cap/time bound reached; do not skip based on this
 //
+// diacritics folds diacritics too (--fold-diacritics).
 // This function is safe for use in subprocess contexts and includes panic recovery.
-func PDFPresenceOnlyPathCapped(path string, words []string, maxPages int, maxDur time.Duration) (bool, bool) {
+func PDFPresenceOnlyPathCapped(path string, words []string, maxPages int, maxDur time.Duration, diacritics bool) (bool, bool) {
 	if len(words) == 0 {
 		return true, true
 	}
@@ -76,12 +105,11 @@ func PDFPresenceOnlyPathCapped(path string, words []string, maxPages int, maxDur
 	}
 
 	// Precompile plural-aware whole-word, case-insensitive regexes
-	rs := make([]*regexp.Regexp, len(words))
+	rs := make([]*Matcher, len(words))
 	found := make([]bool, len(words))
 	remaining := len(words)
 	for i, w := range words {
-		pattern := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(w))
-		rs[i] = regexp.MustCompile(pattern)
+		rs[i] = newMatcher(w, pluralSuffixes, diacritics)
 	}
 
 	// Apply caps
@@ -148,6 +176,15 @@ func PDFPresenceOnlyPathCapped(path string, words []string, maxPages int, maxDur
 // ExtractorRegistry holds extractors for different file types
 type ExtractorRegistry struct {
 	extractors map[string]Extractor
+
+	// The options of the search extracting with the registry (set by SearchEngine.Execute)
+	options TextOptions
+}
+
+// docExtras reports whether office documents are extracted with --doc-extras; a nil
+// registry extracts without
+func (r *ExtractorRegistry) docExtras() bool {
+	return r != nil && r.options.DocExtras
 }
 
 // NewExtractorRegistry creates a new registry with built-in extractors
@@ -188,25 +225,42 @@ func (e *XMLExtractor) ExtractText(data []byte) (string, error) {
 	return strings.TrimSpace(text), nil
 }
 
-// GetExtractor returns the extractor for a given file extension (without dot)
+// GetExtractor returns the extractor for a given file extension (with or without dot),
+// following config.TypeAliases ("htm" gets the HTML extractor)
 func (r *ExtractorRegistry) GetExtractor(ext string) (Extractor, bool) {
-	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
-	extractor, exists := r.extractors[ext]
+	extractor, exists := r.extractors[config.FormatOf(ext)]
 	return extractor, exists
 }
 
+// formatExt returns the lowercased extension of path with its dot (".html"), resolving
+// config.TypeAliases so a mapped extension takes the path of its format ("" without one).
+// For a file discovery placed by its content, pass the name its typeFilter gives it.
+func formatExt(path string) string {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return ""
+	}
+	return "." + config.FormatOf(ext)
+}
+
 // registerBuiltIns registers the built-in extractors for supported formats
 func (r *ExtractorRegistry) registerBuiltIns() {
 	// Email formats
-	r.extractors["eml"] = &EMLExtractor{}
-	r.extractors["mbox"] = &MBOXExtractor{}
+	r.extractors["eml"] = &EMLExtractor{Registry: r}
+	r.extractors["mbox"] = &MBOXExtractor{Registry: r}
 
 	// Binary document formats
-	r.extractors["msg"] = &MSGExtractor{}
+	r.extractors["msg"] = &MSGExtractor{Registry: r}
 
 	// Office document formats
-	r.extractors["docx"] = &DOCXExtractor{}
-	r.extractors["odt"] = &ODTExtractor{}
+	r.extractors["docx"] = &DOCXExtractor{Registry: r}
+	r.extractors["odt"] = &ODTExtractor{Registry: r}
+
+	// Spreadsheets and presentations (only searched with --include-sheets / --include-slides)
+	r.extractors["xlsx"] = &XLSXExtractor{}
+	r.extractors["pptx"] = &PPTXExtractor{}
+	r.extractors["ods"] = &ODTExtractor{Registry: r}
+	r.extractors["odp"] = &ODTExtractor{Registry: r}
 
 	// Web formats
 	r.extractors["html"] = &HTMLExtractor{}
@@ -214,7 +268,16 @@ func (r *ExtractorRegistry) registerBuiltIns() {
 
 	// Other
 	r.extractors["rtf"] = &RTFExtractor{}
-	r.extractors["doc"] = &DOCExtractor{}
+	r.extractors["doc"] = &DOCExtractor{Registry: r}
+
+	// E-books
+	r.extractors["epub"] = &EPUBExtractor{}
+	r.extractors["fb2"] = &FB2Extractor{}
+	r.extractors["mobi"] = &MOBIExtractor{}
+
+	// Structured text (searched with --code)
+	r.extractors["ipynb"] = &IPYNBExtractor{}
+	r.extractors["json"] = &JSONExtractor{}
 
 	// PDFs DISABLED: Removed PDF extractor to prevent system hangs
 	// r.extractors["pdf"] = &PDFExtractor{}
@@ -222,48 +285,90 @@ func (r *ExtractorRegistry) registerBuiltIns() {
 
 // IsBinaryFormat checks if a file extension requires text extraction
 func IsBinaryFormat(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	switch ext {
+	switch formatExt(filename) {
 	case ".msg", ".doc", ".docx", ".odt", ".rtf", ".pdf":
 		return true
+	case ".xlsx", ".ods", ".pptx", ".odp":
+		return true
 	case ".eml", ".mbox":
 		// EML/MBOX can be text but often encoded
 		return true
+	case ".epub", ".fb2", ".mobi":
+		return true
+	case ".ipynb", ".json":
+		// Searched as their values, not their escapes and punctuation
+		return true
 	default:
 		return false
 	}
 }
 
-// EMLExtractor extracts text from .eml files (MIME messages)
-type EMLExtractor struct{}
+// EMLExtractor extracts text from .eml files (MIME messages), including the text of their
+// attachments. Registry supplies the extractors for attachment formats; without one only
+// plain-text attachments are searched.
+type EMLExtractor struct {
+	Registry *ExtractorRegistry
+}
 
 // ExtractText implements the Extractor interface for EML files
 func (e *EMLExtractor) ExtractText(data []byte) (string, error) {
+	return e.ExtractTextContext(context.Background(), data)
+}
+
+// ExtractTextContext implements the ContextExtractor interface, checking ctx between attachments
+func (e *EMLExtractor) ExtractTextContext(ctx context.Context, data []byte) (string, error) {
+	parts, err := e.ExtractParts(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	return joinParts(parts), nil
+}
+
+// ExtractParts implements the PartsExtractor interface: the message body, then each
+// attachment (and named inline part) with extractable text
+func (e *EMLExtractor) ExtractParts(ctx context.Context, data []byte) ([]TextPart, error) {
 	// Parse the MIME message
 	env, err := enmime.ReadEnvelope(bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("failed to parse EML: %w", err)
+		return nil, fmt.Errorf("failed to parse EML: %w", err)
 	}
+	return e.envelopeParts(ctx, env)
+}
 
+// envelopeParts returns the text parts of a parsed message (see ExtractParts)
+func (e *EMLExtractor) envelopeParts(ctx context.Context, env *enmime.Envelope) ([]TextPart, error) {
 	// Prefer plain text, fallback to HTML if plain text is empty
 	text := env.Text
 	if text == "" && env.HTML != "" {
 		// Strip HTML tags for plain text
 		text = stripHTMLTags(env.HTML)
 	}
+	parts := []TextPart{{Text: collapseSpace(text)}}
 
-	// Clean up excessive whitespace
-	text = strings.TrimSpace(text)
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
+	for _, att := range append(env.Attachments, env.Inlines...) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if att.FileName == "" {
+			continue
+		}
+		if text, ok := attachmentText(ctx, e.Registry, att.FileName, att.Content); ok {
+			parts = append(parts, TextPart{Name: att.FileName, Text: collapseSpace(text)})
+		}
+	}
+	return parts, nil
+}
 
-	return text, nil
+// collapseSpace trims text and collapses each run of whitespace to a single space
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
 }
 
 // stripHTMLTags removes HTML tags from text (simple implementation)
-func stripHTMLTags(html string) string {
+func stripHTMLTags(markup string) string {
 	// Remove HTML tags
 	tagRegex := regexp.MustCompile(`<[^>]*>`)
-	text := tagRegex.ReplaceAllString(html, " ")
+	text := tagRegex.ReplaceAllString(markup, " ")
 
 	// Decode HTML entities
 	entityRegex := regexp.MustCompile(`&[a-zA-Z0-9#]*;`)
@@ -280,6 +385,10 @@ func stripHTMLTags(html string) string {
 		case "&apos;":
 			return "'"
 		default:
+			// Named and numeric entities (&eacute;, &#233;, &#x2019;)
+			if s := html.UnescapeString(entity); s != entity {
+				return s
+			}
 			return " "
 		}
 	})
@@ -288,16 +397,26 @@ func stripHTMLTags(html string) string {
 }
 
 // MBOXExtractor extracts text from .mbox files (collections of MIME messages)
-type MBOXExtractor struct{}
+type MBOXExtractor struct {
+	Registry *ExtractorRegistry // passed on to the per-message EMLExtractor
+}
 
 // ExtractText implements the Extractor interface for MBOX files
 func (e *MBOXExtractor) ExtractText(data []byte) (string, error) {
+	return e.ExtractTextContext(context.Background(), data)
+}
+
+// ExtractTextContext implements the ContextExtractor interface, checking ctx between messages
+func (e *MBOXExtractor) ExtractTextContext(ctx context.Context, data []byte) (string, error) {
 	reader := mbox.NewReader(bytes.NewReader(data))
 	var text strings.Builder
 
-	emlExtractor := &EMLExtractor{}
+	emlExtractor := &EMLExtractor{Registry: e.Registry}
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		msg, err := reader.NextMessage()
 		if err != nil {
 			break
@@ -307,7 +426,7 @@ func (e *MBOXExtractor) ExtractText(data []byte) (string, error) {
 			continue
 		}
 		emlData := content
-		extracted, err := emlExtractor.ExtractText(emlData)
+		extracted, err := emlExtractor.ExtractTextContext(ctx, emlData)
 		if err != nil {
 			continue
 		}
@@ -324,15 +443,34 @@ func (e *MBOXExtractor) ExtractText(data []byte) (string, error) {
 // PDFExtractor extracts text from .pdf files
 type PDFExtractor struct{}
 
-// DOCExtractor extracts text from legacy .doc (OLE/CFB) files using conservative salvage
-type DOCExtractor struct{}
+// DOCExtractor extracts text from legacy .doc (OLE/CFB) files through their piece table
+// (see wordDocumentText), with a conservative salvage for files it cannot parse.
+// Registry tells whether headers, footers, notes and comments are included (--doc-extras).
+type DOCExtractor struct {
+	Registry *ExtractorRegistry
+}
 
 // ExtractText implements the Extractor interface for DOC files.
 // Strategy:
+// - Parse the FIB and piece table and return the document text in order
+// - Encrypted documents are an error; other files that do not parse (Word 95 and older, damaged) are salvaged:
 // - Open OLE/CFB and read a bounded amount of likely streams (WordDocument, 1Table, 0Table)
 // - Try UTF-16 decode; else ASCII salvage replacing non-printables with spaces
 // - Collapse whitespace and return a concise plain-text representation
 func (e *DOCExtractor) ExtractText(data []byte) (string, error) {
+	return e.ExtractTextContext(context.Background(), data)
+}
+
+// ExtractTextContext implements the ContextExtractor interface, checking ctx between streams
+func (e *DOCExtractor) ExtractTextContext(ctx context.Context, data []byte) (string, error) {
+	text, err := wordDocumentText(bytes.NewReader(data), e.Registry.docExtras())
+	if err == nil {
+		return text, nil
+	}
+	if errors.Is(err, errWordEncrypted) {
+		return "", err
+	}
+
 	// Open CFB from a byte reader
 	cf, err := mscfb.New(bytes.NewReader(data))
 	if err != nil {
@@ -359,6 +497,9 @@ func (e *DOCExtractor) ExtractText(data []byte) (string, error) {
 	}
 
 	for ent, err2 := cf.Next(); err2 == nil; ent, err2 = cf.Next() {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		if total >= maxTotal {
 			break
 		}
@@ -512,12 +653,11 @@ func PDFContainsAllWordsNoDistance(data []byte, words []string) bool {
 	}
 
 	// Precompile whole-word, case-insensitive regexes for each word
-	rs := make([]*regexp.Regexp, len(words))
+	rs := make([]*Matcher, len(words))
 	found := make([]bool, len(words))
 	remaining := len(words)
 	for i, w := range words {
-		pattern := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(w))
-		rs[i] = regexp.MustCompile(pattern)
+		rs[i] = newMatcher(w, pluralSuffixes, false)
 	}
 
 	// Scan page by page; mark words as we find them; early exit once all are found
@@ -596,10 +736,9 @@ func PDFHasAllWordsWithinDistanceNoExtract(data []byte, words []string, distance
 		pos       int
 		wordIndex int
 	}
-	regexes := make([]*regexp.Regexp, len(words))
+	regexes := make([]*Matcher, len(words))
 	for i, w := range words {
-		pattern := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(w))
-		regexes[i] = regexp.MustCompile(pattern)
+		regexes[i] = newMatcher(w, pluralSuffixes, false)
 	}
 
 	// Streaming sliding window across pages (bounded memory)
@@ -717,13 +856,12 @@ func PDFContainsAllWordsNoDistancePath(path string, words []string) bool {
 		return false
 	}
 
-	rs := make([]*regexp.Regexp, len(words))
+	rs := make([]*Matcher, len(words))
 	found := make([]bool, len(words))
 	remaining := len(words)
 
 	for i, w := range words {
-		pattern := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(w))
-		rs[i] = regexp.MustCompile(pattern)
+		rs[i] = newMatcher(w, pluralSuffixes, false)
 	}
 
 	for i := 1; i <= pages; i++ {
@@ -815,11 +953,10 @@ func PDFHasAllWordsWithinDistanceNoExtractPath(path string, words []string, dist
 		wordIndex int
 	}
 
-	// Precompile per-word regex
-	regexes := make([]*regexp.Regexp, len(words))
+	// Precompile per-word matchers
+	regexes := make([]*Matcher, len(words))
 	for i, w := range words {
-		pattern := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(w))
-		regexes[i] = regexp.MustCompile(pattern)
+		regexes[i] = newMatcher(w, pluralSuffixes, false)
 	}
 
 	// Streaming sliding window across pages (bounded memory)
@@ -903,24 +1040,72 @@ func PDFHasAllWordsWithinDistanceNoExtractPath(path string, words []string, dist
 }
 
 // MSGExtractor extracts text from .msg files (Outlook messages)
-type MSGExtractor struct{}
+type MSGExtractor struct {
+	Registry *ExtractorRegistry // extractors for attachment formats (see EMLExtractor)
+}
+
+// msgAttachPrefix names the storage of each attachment in an MSG compound file
+const msgAttachPrefix = "__attach_version1.0_"
 
 // ExtractText implements the Extractor interface for MSG files
 func (e *MSGExtractor) ExtractText(data []byte) (string, error) {
+	return e.ExtractTextContext(context.Background(), data)
+}
+
+// ExtractTextContext implements the ContextExtractor interface, checking ctx between streams
+func (e *MSGExtractor) ExtractTextContext(ctx context.Context, data []byte) (string, error) {
+	parts, err := e.ExtractParts(ctx, data)
+	if err != nil {
+		return "", err
+	}
+	return joinParts(parts), nil
+}
+
+// ExtractParts implements the PartsExtractor interface: subject and body, then each
+// attachment (__attach_version1.0_* storage) with extractable text
+func (e *MSGExtractor) ExtractParts(ctx context.Context, data []byte) ([]TextPart, error) {
 	// Attempt to parse the OLE compound file and extract Unicode Subject/Body first.
 	if cf, err := mscfb.New(bytes.NewReader(data)); err == nil {
+		// Top-level property streams, and the property streams of each attachment storage
 		streams := make(map[string][]byte)
+		attachStreams := make(map[string]map[string][]byte)
 		for ent, err2 := cf.Next(); err2 == nil; ent, err2 = cf.Next() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			name := ent.Name
-			// Read the entire stream content
-			b, _ := io.ReadAll(ent)
-			if len(b) > 0 {
-				streams[name] = b
+			switch {
+			case len(ent.Path) == 0:
+				// Read the entire stream content
+				b, _ := io.ReadAll(ent)
+				if len(b) > 0 {
+					streams[name] = b
+				}
+			case len(ent.Path) == 1 && strings.HasPrefix(ent.Path[0], msgAttachPrefix):
+				// Attachment properties; embedded messages (sub-storages) are not searched.
+				// Oversized attachment data is skipped whole (see maxAttachmentBytes).
+				if ent.Size > maxAttachmentBytes && strings.HasPrefix(name, "__substg1.0_3701") {
+					continue
+				}
+				b, _ := io.ReadAll(io.LimitReader(ent, maxAttachmentBytes))
+				if len(b) == 0 {
+					continue
+				}
+				if attachStreams[ent.Path[0]] == nil {
+					attachStreams[ent.Path[0]] = make(map[string][]byte)
+				}
+				attachStreams[ent.Path[0]][name] = b
 			}
 		}
+		// Storages are numbered (__attach_version1.0_#00000000, ...): keep attachment order
+		attachOrder := make([]string, 0, len(attachStreams))
+		for storage := range attachStreams {
+			attachOrder = append(attachOrder, storage)
+		}
+		sort.Strings(attachOrder)
 
 		// Helper: prefer Unicode (001F), then ANSI (001E), then binary 0102 (for HTML)
-		findStream := func(keys ...string) ([]byte, bool) {
+		findStream := func(streams map[string][]byte, keys ...string) ([]byte, bool) {
 			for _, k := range keys {
 				if v, ok := streams[k]; ok && len(v) > 0 {
 					return v, true
@@ -940,16 +1125,16 @@ func (e *MSGExtractor) ExtractText(data []byte) (string, error) {
 		var subject, body string
 
 		// PR_SUBJECT: 0037 (Unicode 001F; ANSI 001E)
-		if b, ok := findStream("__substg1.0_0037001F", "__substg1.0_0037001E"); ok {
+		if b, ok := findStream(streams, "__substg1.0_0037001F", "__substg1.0_0037001E"); ok {
 			subject = decodeMSGText(b)
 		}
 		// PR_BODY: 1000 (Unicode 001F; ANSI 001E)
-		if b, ok := findStream("__substg1.0_1000001F", "__substg1.0_1000001E"); ok {
+		if b, ok := findStream(streams, "__substg1.0_1000001F", "__substg1.0_1000001E"); ok {
 			body = decodeMSGText(b)
 		}
 		// PR_HTML: 1013 (Unicode 001F; ANSI 001E; sometimes 0102 binary); use as fallback for body
 		if body == "" {
-			if b, ok := findStream("__substg1.0_1013001F", "__substg1.0_1013001E", "__substg1.0_10130102"); ok {
+			if b, ok := findStream(streams, "__substg1.0_1013001F", "__substg1.0_1013001E", "__substg1.0_10130102"); ok {
 				html := decodeMSGText(b)
 				if html == "" {
 					html = string(b)
@@ -958,16 +1143,43 @@ func (e *MSGExtractor) ExtractText(data []byte) (string, error) {
 			}
 		}
 
+		var parts []TextPart
 		if subject != "" || body != "" {
 			out := strings.TrimSpace(strings.TrimSpace(subject) + "\n\n" + strings.TrimSpace(body))
 			out = regexp.MustCompile(`\s+`).ReplaceAllString(out, " ")
-			return out, nil
+			parts = append(parts, TextPart{Text: out})
+		}
+
+		for _, storage := range attachOrder {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			st := attachStreams[storage]
+			// PR_ATTACH_DATA_BIN: 3701; name from PR_ATTACH_LONG_FILENAME (3707), else PR_ATTACH_FILENAME (3704)
+			content, ok := findStream(st, "__substg1.0_37010102")
+			if !ok {
+				continue
+			}
+			nameData, ok := findStream(st, "__substg1.0_3707001F", "__substg1.0_3707001E", "__substg1.0_3704001F", "__substg1.0_3704001E")
+			if !ok {
+				continue
+			}
+			name := strings.TrimRight(decodeMSGText(nameData), "\x00")
+			if text, ok := attachmentText(ctx, e.Registry, name, content); ok {
+				if len(parts) == 0 {
+					parts = append(parts, TextPart{})
+				}
+				parts = append(parts, TextPart{Name: name, Text: collapseSpace(text)})
+			}
+		}
+		if len(parts) > 0 {
+			return parts, nil
 		}
 	}
 
 	// Fallback: best-effort UTF-16, then ASCII salvage (spaces for non-printables)
 	if s, ok := tryDecodeUTF16BestEffort(data); ok {
-		return strings.TrimSpace(s), nil
+		return []TextPart{{Text: strings.TrimSpace(s)}}, nil
 	}
 	buf := make([]rune, 0, len(data))
 	for _, b := range data {
@@ -978,7 +1190,7 @@ func (e *MSGExtractor) ExtractText(data []byte) (string, error) {
 		}
 	}
 	out := regexp.MustCompile(`\s+`).ReplaceAllString(string(buf), " ")
-	return strings.TrimSpace(out), nil
+	return []TextPart{{Text: strings.TrimSpace(out)}}, nil
 }
 
 // tryDecodeUTF16BestEffort attempts BOM-aware UTF-16 decoding, then heuristic LE/BE.
@@ -1052,81 +1264,379 @@ func isLikelyUTF16BE(b []byte) bool {
 	return slots > 0 && float64(zeros) >= 0.30*float64(slots)
 }
 
-// DOCXExtractor extracts text from .docx files (Office Open XML)
-type DOCXExtractor struct{}
+// DOCXExtractor extracts text from .docx files (Office Open XML): the document body with
+// one line per paragraph, plus headers, footers, footnotes, endnotes and comments with
+// --doc-extras (which Registry tells)
+type DOCXExtractor struct {
+	Registry *ExtractorRegistry
+}
 
 // ExtractText implements the Extractor interface for DOCX files
 func (e *DOCXExtractor) ExtractText(data []byte) (string, error) {
-	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	return e.ExtractTextContext(context.Background(), data)
+}
+
+// ExtractTextContext implements the ContextExtractor interface, checking ctx between parts
+func (e *DOCXExtractor) ExtractTextContext(ctx context.Context, data []byte) (string, error) {
+	text, ok, err := extractOfficeText(ctx, data, ".docx", e.Registry.docExtras())
 	if err != nil {
-		return string(data), nil
+		return "", err
+	}
+	if ok {
+		return text, nil
 	}
+	return string(data), nil
+}
+
+// ODTExtractor extracts text from .odt files (OpenDocument Text). OpenDocument spreadsheets
+// (.ods) and presentations (.odp) keep their text in the same content.xml and use it too.
+// Notes, comments and page headers/footers are included with --doc-extras (which Registry
+// tells).
+type ODTExtractor struct {
+	Registry *ExtractorRegistry
+}
 
-	for _, file := range zipReader.File {
-		if file.Name == "word/document.xml" {
-			rc, err := file.Open()
+// ExtractText implements the Extractor interface for ODT files
+func (e *ODTExtractor) ExtractText(data []byte) (string, error) {
+	return e.ExtractTextContext(context.Background(), data)
+}
+
+// ExtractTextContext implements the ContextExtractor interface, checking ctx between parts
+func (e *ODTExtractor) ExtractTextContext(ctx context.Context, data []byte) (string, error) {
+	text, ok, err := extractOfficeText(ctx, data, ".odt", e.Registry.docExtras())
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return text, nil
+	}
+	return string(data), nil
+}
+
+// XLSXExtractor extracts text from .xlsx workbooks: every worksheet in order, one line per
+// row, with shared-string cells resolved through xl/sharedStrings.xml
+type XLSXExtractor struct{}
+
+// ExtractText implements the Extractor interface for XLSX files
+func (e *XLSXExtractor) ExtractText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open XLSX: %w", err)
+	}
+
+	var shared []string
+	var sheets []*zip.File
+	for _, f := range officeTextEntries(zr, ".xlsx", false) {
+		if f.Name == "xl/sharedStrings.xml" {
+			rc, err := f.Open()
 			if err != nil {
 				continue
 			}
-			content, err := io.ReadAll(rc)
+			shared, err = readSharedStrings(rc)
 			rc.Close()
 			if err != nil {
+				return "", fmt.Errorf("failed to read XLSX shared strings: %w", err)
+			}
+			continue
+		}
+		sheets = append(sheets, f)
+	}
+
+	var text strings.Builder
+	for _, f := range sheets {
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		err = writeSheetText(&text, rc, shared)
+		rc.Close()
+		if err != nil {
+			continue
+		}
+		text.WriteString("\n")
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// readSharedStrings returns the shared string table: the concatenated <t> runs of each <si>
+func readSharedStrings(r io.Reader) ([]string, error) {
+	var shared []string
+	var cur strings.Builder
+	inText := false
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return shared, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				cur.Reset()
+			case "t":
+				inText = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				shared = append(shared, cur.String())
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				cur.Write(t)
+			}
+		}
+	}
+}
+
+// writeSheetText writes the cell values of one worksheet, cells separated by spaces and
+// rows by newlines. Shared-string cells (t="s") hold an index into shared; with no table
+// they are left out.
+func writeSheetText(w io.Writer, r io.Reader, shared []string) error {
+	bw := bufio.NewWriter(w)
+	dec := xml.NewDecoder(r)
+	cellType := ""
+	inValue := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if ferr := bw.Flush(); ferr != nil {
+				return ferr
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "c":
+				cellType = ""
+				for _, a := range t.Attr {
+					if a.Name.Local == "t" {
+						cellType = a.Value
+					}
+				}
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "row":
+				bw.WriteByte('\n')
+			}
+		case xml.CharData:
+			if !inValue {
 				continue
 			}
-			text := regexp.MustCompile(`<[^>]*>`).ReplaceAllString(string(content), " ")
-			return strings.TrimSpace(text), nil
+			val := strings.TrimSpace(string(t))
+			if cellType == "s" {
+				i, err := strconv.Atoi(val)
+				if err != nil || i < 0 || i >= len(shared) {
+					continue
+				}
+				val = shared[i]
+			}
+			if val != "" {
+				bw.WriteString(val)
+				bw.WriteByte(' ')
+			}
 		}
 	}
+}
 
-	return string(data), nil
+// writeSharedStrings writes the shared string table of a workbook, one string per line
+func writeSharedStrings(w io.Writer, r io.Reader) error {
+	shared, err := readSharedStrings(r)
+	for _, s := range shared {
+		if _, werr := io.WriteString(w, s+"\n"); werr != nil {
+			return werr
+		}
+	}
+	return err
 }
 
-// ODTExtractor extracts text from .odt files (OpenDocument Text)
-type ODTExtractor struct{}
+// PPTXExtractor extracts text from .pptx presentations: each slide followed by its speaker notes
+type PPTXExtractor struct{}
 
-// ExtractText implements the Extractor interface for ODT files
-func (e *ODTExtractor) ExtractText(data []byte) (string, error) {
-	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+// ExtractText implements the Extractor interface for PPTX files
+func (e *PPTXExtractor) ExtractText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
-		return string(data), nil
+		return "", fmt.Errorf("failed to open PPTX: %w", err)
+	}
+	var text strings.Builder
+	for _, f := range officeTextEntries(zr, ".pptx", false) {
+		rc, err := f.Open()
+		if err != nil {
+			continue
+		}
+		_ = writeSlideText(&text, rc) // text before a malformed part is kept
+		rc.Close()
+		text.WriteString("\n")
 	}
+	return strings.TrimSpace(text.String()), nil
+}
 
-	for _, file := range zipReader.File {
-		if file.Name == "content.xml" {
-			rc, err := file.Open()
-			if err != nil {
-				continue
+// writeSlideText writes the character data of a slide or notes part, entities decoded,
+// with a space at every tag so text in separate elements stays apart
+func writeSlideText(w io.Writer, r io.Reader) error {
+	bw := bufio.NewWriter(w)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if ferr := bw.Flush(); ferr != nil {
+				return ferr
 			}
-			content, err := io.ReadAll(rc)
-			rc.Close()
-			if err != nil {
-				continue
+			if err == io.EOF {
+				return nil
 			}
-			text := regexp.MustCompile(`<[^>]*>`).ReplaceAllString(string(content), " ")
-			return strings.TrimSpace(text), nil
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement, xml.EndElement:
+			bw.WriteByte(' ')
+		case xml.CharData:
+			bw.Write(t)
 		}
 	}
+}
 
-	return string(data), nil
+// officeTextEntries returns the ZIP entries holding the text of an Office Open XML,
+// OpenDocument or EPUB file, in reading order: the shared strings and then each worksheet for
+// .xlsx, each slide followed by its notes for .pptx, content.xml for OpenDocument, and the
+// spine chapters for .epub.
+// With --doc-extras, .docx adds its headers, footers, footnotes, endnotes and comments
+// and OpenDocument its styles.xml (page headers and footers).
+func officeTextEntries(zr *zip.Reader, ext string, extras bool) []*zip.File {
+	byName := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		byName[f.Name] = f
+	}
+	var entries []*zip.File
+	switch strings.ToLower(ext) {
+	case ".docx":
+		if f, ok := byName["word/document.xml"]; ok {
+			entries = append(entries, f)
+		}
+		if extras {
+			for _, part := range []string{"word/header", "word/footer"} {
+				for _, n := range numberedEntries(byName, part) {
+					entries = append(entries, byName[fmt.Sprintf("%s%d.xml", part, n)])
+				}
+			}
+			for _, name := range []string{"word/footnotes.xml", "word/endnotes.xml", "word/comments.xml"} {
+				if f, ok := byName[name]; ok {
+					entries = append(entries, f)
+				}
+			}
+		}
+	case ".odt", ".ods", ".odp":
+		if f, ok := byName["content.xml"]; ok {
+			entries = append(entries, f)
+		}
+		if f, ok := byName["styles.xml"]; ok && extras {
+			entries = append(entries, f) // page headers and footers
+		}
+	case ".xlsx":
+		if f, ok := byName["xl/sharedStrings.xml"]; ok {
+			entries = append(entries, f)
+		}
+		for _, n := range numberedEntries(byName, "xl/worksheets/sheet") {
+			entries = append(entries, byName[fmt.Sprintf("xl/worksheets/sheet%d.xml", n)])
+		}
+	case ".epub":
+		chapters, _ := epubChapters(zr)
+		for _, ch := range chapters {
+			entries = append(entries, ch.file)
+		}
+	case ".pptx":
+		// Notes are found through each slide's relationships, since their numbers need not
+		// match; notes no slide refers to come last
+		seen := make(map[*zip.File]bool)
+		for _, n := range numberedEntries(byName, "ppt/slides/slide") {
+			entries = append(entries, byName[fmt.Sprintf("ppt/slides/slide%d.xml", n)])
+			rels := byName[fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", n)]
+			if notes, ok := byName[slideNotesPath(rels)]; ok && !seen[notes] {
+				seen[notes] = true
+				entries = append(entries, notes)
+			}
+		}
+		for _, n := range numberedEntries(byName, "ppt/notesSlides/notesSlide") {
+			if notes := byName[fmt.Sprintf("ppt/notesSlides/notesSlide%d.xml", n)]; !seen[notes] {
+				entries = append(entries, notes)
+			}
+		}
+	}
+	return entries
+}
+
+// slideNotesPath returns the entry name of the notes slide a slide's relationships part
+// points to ("" when there is none)
+func slideNotesPath(rels *zip.File) string {
+	if rels == nil {
+		return ""
+	}
+	rc, err := rels.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	var doc struct {
+		Relationships []struct {
+			Type       string `xml:"Type,attr"`
+			Target     string `xml:"Target,attr"`
+			TargetMode string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if xml.NewDecoder(rc).Decode(&doc) != nil {
+		return ""
+	}
+	for _, rel := range doc.Relationships {
+		if strings.HasSuffix(rel.Type, "/notesSlide") && rel.TargetMode != "External" {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return path.Join("ppt/slides", rel.Target)
+		}
+	}
+	return ""
 }
 
-// RTFExtractor extracts text from .rtf files (Rich Text Format)
+// numberedEntries returns the sorted N of every entry named prefix+N+".xml"
+func numberedEntries(byName map[string]*zip.File, prefix string) []int {
+	var nums []int
+	for name := range byName {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSuffix(rest, ".xml")); err == nil && strings.HasSuffix(rest, ".xml") {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// RTFExtractor extracts text from .rtf files (Rich Text Format), see writeRTFText
 type RTFExtractor struct{}
 
 // ExtractText implements the Extractor interface for RTF files
 func (e *RTFExtractor) ExtractText(data []byte) (string, error) {
-	text := string(data)
-
-	// Remove RTF control words (simple regex approach)
-	rtfControlRegex := regexp.MustCompile(`\\[a-z]+\d*`)
-	text = rtfControlRegex.ReplaceAllString(text, "")
-
-	// Remove braces
-	text = strings.ReplaceAll(text, "{", "")
-	text = strings.ReplaceAll(text, "}", "")
-
-	// Clean up excessive whitespace
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
-
-	return strings.TrimSpace(text), nil
+	var text strings.Builder
+	if err := writeRTFText(&text, bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("failed to parse RTF: %w", err)
+	}
+	return strings.TrimSpace(text.String()), nil
 }
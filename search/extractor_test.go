@@ -0,0 +1,93 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testWorkbook has a shared-string table, an inline string, numbers and a second sheet
+func testWorkbook(t *testing.T) []byte {
+	return zipFile(t,
+		"[Content_Types].xml", "<Types/>",
+		"xl/sharedStrings.xml", `<sst><si><t>Invoice</t></si><si><r><t>Müller </t></r><r><t>&amp; Söhne</t></r></si></sst>`,
+		"xl/worksheets/sheet2.xml", `<worksheet><sheetData><row><c t="inlineStr"><is><t>second sheet</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet1.xml", `<worksheet><sheetData>`+
+			`<row><c t="s"><v>0</v></c><c><v>42.5</v></c></row>`+
+			`<row><c t="s"><v>1</v></c><c t="s"><v>7</v></c></row>`+
+			`</sheetData></worksheet>`,
+	)
+}
+
+// testPresentation numbers its notes apart from the slides, as after slides are reordered
+func testPresentation(t *testing.T) []byte {
+	rels := func(target string) string {
+		return `<Relationships><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slideLayout" Target="../slideLayouts/slideLayout1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="` + target + `"/></Relationships>`
+	}
+	return zipFile(t,
+		"[Content_Types].xml", "<Types/>",
+		"ppt/slides/slide1.xml", `<p:sld><a:p><a:r><a:t>Roadmap</a:t></a:r><a:r><a:t>R&amp;D</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/_rels/slide1.xml.rels", rels("../notesSlides/notesSlide2.xml"),
+		"ppt/slides/slide2.xml", `<p:sld><a:p><a:r><a:t>Budget</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/_rels/slide2.xml.rels", rels("../notesSlides/notesSlide1.xml"),
+		"ppt/notesSlides/notesSlide1.xml", `<p:notes><a:t>budget notes</a:t></p:notes>`,
+		"ppt/notesSlides/notesSlide2.xml", `<p:notes><a:t>roadmap notes</a:t></p:notes>`,
+		"ppt/notesSlides/notesSlide3.xml", `<p:notes><a:t>orphan notes</a:t></p:notes>`,
+	)
+}
+
+func TestXLSXExtractor(t *testing.T) {
+	got, err := (&XLSXExtractor{}).ExtractText(testWorkbook(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sheets in number order; an out-of-range shared index is dropped
+	if want := "Invoice 42.5 \nMüller & Söhne \n\nsecond sheet"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPPTXExtractor(t *testing.T) {
+	got, err := (&PPTXExtractor{}).ExtractText(testPresentation(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each slide is followed by the notes its relationships name
+	want := "Roadmap R&D roadmap notes Budget budget notes orphan notes"
+	if got := strings.Join(strings.Fields(got), " "); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOfficePrefilterDecodedText(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{"book.xlsx": testWorkbook(t), "deck.pptx": testPresentation(t)}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		file    string
+		words   []string
+		found   bool
+		decided bool
+	}{
+		{"book.xlsx", []string{"Söhne", "invoice"}, true, true},
+		{"book.xlsx", []string{"second"}, true, true},
+		{"book.xlsx", []string{"amp"}, false, true}, // the entity is not text
+		{"book.xlsx", []string{"receipt"}, false, true},
+		{"deck.pptx", []string{"R&D"}, true, true},
+		{"deck.pptx", []string{"orphan", "budget"}, true, true},
+		{"deck.pptx", []string{"sld"}, false, true}, // nor are tag names
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		found, decided := binaryPrefilter(path, path, tt.words, 0, TextOptions{})
+		if found != tt.found || decided != tt.decided {
+			t.Errorf("%s %q: got %v, %v; want %v, %v", tt.file, tt.words, found, decided, tt.found, tt.decided)
+		}
+	}
+}
@@ -2,12 +2,15 @@ package search
 
 import (
 	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,6 +21,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"find-words/config"
+	"find-words/search/fold"
 )
 
 // CheckTextContainsAllWords checks if extracted text contains all search words
@@ -27,61 +31,111 @@ func smartFormsEnabled() bool {
 	return strings.EqualFold(os.Getenv("GARP_SMART_FORMS"), "1")
 }
 
-func buildWordRegexLower(word string) *regexp.Regexp {
-	base := strings.ToLower(strings.TrimSpace(word))
-	if base == "" {
-		// never matches; safe fallback
-		return regexp.MustCompile(`a\A`)
-	}
-	suffix := `(?:es|s)?`
+// pluralSuffixes are the endings every search term may carry and still match
+var pluralSuffixes = []string{"es", "s"}
+
+// wordFormSuffixes lists the endings a search word may carry and still match (plurals,
+// plus the smart forms when enabled).
+func wordFormSuffixes() []string {
 	if smartFormsEnabled() {
-		suffix = `(?:es|s|ed|ing|al|tion|ation)?`
+		return []string{"es", "s", "ed", "ing", "al", "tion", "ation"}
 	}
-	pat := fmt.Sprintf(`\b(?:%s%s)\b`, regexp.QuoteMeta(base), suffix)
-	return regexp.MustCompile(pat)
+	return pluralSuffixes
 }
 
-func buildWordRegexCI(word string) *regexp.Regexp {
-	base := strings.TrimSpace(word)
-	if base == "" {
-		// never matches; safe fallback
-		return regexp.MustCompile(`a\A`)
+// longestTerm returns the longest of terms ("" when empty), a cheap proxy for the rarest
+func longestTerm(terms []string) string {
+	longest := ""
+	for _, t := range terms {
+		if len(t) > len(longest) {
+			longest = t
+		}
 	}
-	suffix := `(?:es|s)?`
-	if smartFormsEnabled() {
-		suffix = `(?:es|s|ed|ing|al|tion|ation)?`
+	return longest
+}
+
+// isRegexTerm reports whether a search term is a /regular expression/
+func isRegexTerm(term string) bool {
+	return len(term) > 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/")
+}
+
+// ValidateTerm reports a syntax error in a /regular expression/ term; other terms are always valid
+func ValidateTerm(term string) error {
+	if !isRegexTerm(term) {
+		return nil
 	}
-	pat := fmt.Sprintf(`(?i)\b(?:%s%s)\b`, regexp.QuoteMeta(base), suffix)
-	return regexp.MustCompile(pat)
+	if _, err := regexp.Compile(term[1 : len(term)-1]); err != nil {
+		return fmt.Errorf("invalid regular expression %s: %w", term, err)
+	}
+	return nil
 }
 
-func CheckTextContainsAllWords(text string, words []string, distance int) bool {
-	if len(words) == 0 {
-		return true
+// prefilterTerms splits phrases into their words for the raw-byte prefilters, where markup,
+// encodings or chunk boundaries may separate the words of a phrase. Every word of a phrase
+// must be present for the phrase to be, so this never rejects a real match. Regex terms are
+// dropped: a pattern may span a chunk boundary or match only after extraction, so they are
+// always left to the full check.
+func prefilterTerms(terms []string) []string {
+	out := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if isRegexTerm(t) {
+			continue
+		}
+		out = append(out, strings.Fields(t)...)
 	}
+	return out
+}
 
-	contentStr := strings.ToLower(text)
+func CheckTextContainsAllWords(text string, words []string, distance int, diacritics bool) bool {
+	return CheckTextMatchesGroups(text, singletonGroups(words), distance, diacritics)
+}
 
-	// Single-term case: just check presence quickly
-	if len(words) == 1 {
-		regex := buildWordRegexLower(words[0])
-		return regex.FindStringIndex(contentStr) != nil
+// CheckTextMatchesGroups checks if text satisfies every group (any one alternative per
+// group) within a distance window; an occurrence of any alternative counts towards
+// covering its group. diacritics folds diacritics too (--fold-diacritics).
+func CheckTextMatchesGroups(text string, groups [][]string, distance int, diacritics bool) bool {
+	if len(groups) == 0 {
+		return true
 	}
 
-	// Collect positions for each word
-	type match struct {
-		pos       int
-		wordIndex int
+	// Single-group case: just check presence quickly
+	if len(groups) == 1 {
+		for _, term := range groups[0] {
+			if newMatcher(term, wordFormSuffixes(), diacritics).MatchString(text) {
+				return true
+			}
+		}
+		return false
 	}
-	var matches []match
-	for i, word := range words {
-		regex := buildWordRegexLower(word)
-		indexes := regex.FindAllStringIndex(contentStr, -1)
-		for _, idx := range indexes {
-			matches = append(matches, match{pos: idx[0], wordIndex: i})
+
+	// Collect positions for each group
+	var matches []termMatch
+	for i, group := range groups {
+		groupMatched := false
+		for _, term := range group {
+			indexes := newMatcher(term, wordFormSuffixes(), diacritics).FindAllStringIndex(text, -1)
+			for _, idx := range indexes {
+				matches = append(matches, termMatch{pos: idx[0], wordIndex: i})
+			}
+			groupMatched = groupMatched || len(indexes) > 0
+		}
+		if !groupMatched {
+			return false
 		}
 	}
 
+	return matchesWithinDistance(matches, len(groups), distance)
+}
+
+// termMatch is one occurrence of search word (or group) wordIndex at byte offset pos
+type termMatch struct {
+	pos       int
+	wordIndex int
+}
+
+// matchesWithinDistance reports whether some window of at most distance characters
+// covers an occurrence of each of the required words.
+func matchesWithinDistance(matches []termMatch, required int, distance int) bool {
 	if len(matches) == 0 {
 		return false
 	}
@@ -92,7 +146,6 @@ func CheckTextContainsAllWords(text string, words []string, distance int) bool {
 	// Sliding window over matches to find a window that covers all words
 	counts := make(map[int]int)
 	covered := 0
-	required := len(words)
 	left := 0
 
 	for right := 0; right < len(matches); right++ {
@@ -121,16 +174,14 @@ func CheckTextContainsAllWords(text string, words []string, distance int) bool {
 }
 
 // CheckTextContainsExcludeWords checks if extracted text contains any exclude words
-func CheckTextContainsExcludeWords(text string, excludeWords []string) bool {
+func CheckTextContainsExcludeWords(text string, excludeWords []string, diacritics bool) bool {
 	if len(excludeWords) == 0 {
 		return false
 	}
 
-	contentStr := strings.ToLower(text)
-
 	// Check each exclude word
 	for _, word := range excludeWords {
-		if containsWholeWord(contentStr, strings.ToLower(word)) {
+		if containsWholeWord(text, word, diacritics) {
 			return true
 		}
 	}
@@ -144,35 +195,14 @@ type FileInfo struct {
 	Size int64
 }
 
-// GetDocumentFileCount returns the count of document files that will be searched (pure Go)
-func GetDocumentFileCount(fileTypes []string) (int, error) {
-	// Parse allowed extensions from patterns like "-g", "*.txt"
-	allowed := make(map[string]bool)
-	for i := 0; i < len(fileTypes); i++ {
-		if fileTypes[i] == "-g" && i+1 < len(fileTypes) {
-			i++
-			glob := fileTypes[i]
-			if strings.HasPrefix(glob, "*.") {
-				ext := strings.ToLower(glob[1:]) // ".txt"
-				allowed[ext] = true
-			}
-		}
-	}
+// GetDocumentFileCount returns the count of document files that will be searched under roots (pure Go)
+func GetDocumentFileCount(ctx context.Context, roots []string, fileTypes []string) (int, error) {
+	// Select files by the patterns ("-g", "*.txt", ...)
+	types := newTypeFilter(fileTypes)
 
 	count := 0
-	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			// Ignore permission errors; keep walking
-			return nil
-		}
-		if d.IsDir() {
-			if d.Name() != "." && config.ShouldSkipDirectory(d.Name()) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		ext := strings.ToLower(filepath.Ext(path))
-		if len(allowed) > 0 && !allowed[ext] {
+	err := walkRoots(ctx, roots, WalkOptions{}, func(path string, d fs.DirEntry) error {
+		if !types.selects(path) {
 			return nil
 		}
 		count++
@@ -184,52 +214,31 @@ func GetDocumentFileCount(fileTypes []string) (int, error) {
 	return count, nil
 }
 
-// FindFilesWithFirstWord finds all files containing the first search word (pure Go)
-func FindFilesWithFirstWord(word string, fileTypes []string) ([]string, error) {
-	// Parse allowed extensions from patterns like "-g", "*.txt"
-	allowed := make(map[string]bool)
-	for i := 0; i < len(fileTypes); i++ {
-		if fileTypes[i] == "-g" && i+1 < len(fileTypes) {
-			i++
-			glob := fileTypes[i]
-			if strings.HasPrefix(glob, "*.") {
-				ext := strings.ToLower(glob[1:]) // ".txt"
-				allowed[ext] = true
-			}
-		}
-	}
+// isHeavyFormat reports whether discovery passes the file named name on without scanning
+// its raw bytes for the first word: binary formats need their extractor and archives
+// their entries
+func isHeavyFormat(name string) bool {
+	return IsBinaryFormat(name) || isArchive(name)
+}
 
-	// Precompute lowercased search word for fast ASCII whole-word scan
-	wLower := strings.ToLower(word)
-	heavy := map[string]bool{
-		".pdf":  true,
-		".docx": true,
-		".odt":  true,
-		".msg":  true,
-		".eml":  true,
-		".mbox": true,
-	}
-	matches := make([]string, 0, 128)
-	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			// Ignore permission errors; keep walking
-			return nil
-		}
-		if d.IsDir() {
-			if d.Name() != "." && config.ShouldSkipDirectory(d.Name()) {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+// FindFilesWithFirstWord finds all files under roots containing the first search word (pure Go)
+func FindFilesWithFirstWord(ctx context.Context, roots []string, word string, fileTypes []string) ([]string, error) {
+	// Select files by the patterns ("-g", "*.txt", ...)
+	types := newTypeFilter(fileTypes)
 
-		// Filter by extension if provided
-		ext := strings.ToLower(filepath.Ext(path))
-		if len(allowed) > 0 && !allowed[ext] {
+	// Precompute the folded search word for the fast whole-word scan
+	// (for a phrase, its longest word: the phrase is verified later)
+	scanner := newWordScanner(longestTerm(prefilterTerms([]string{word})), false)
+	matches := make([]string, 0, 128)
+	err := walkRoots(ctx, roots, WalkOptions{}, func(path string, d fs.DirEntry) error {
+		// Filter by file type if provided
+		if !types.selects(path) {
 			return nil
 		}
 
 		// Fast first-word check: stream file without extraction
-		if heavy[ext] {
+		// (a regex term has no literal word to scan for; include and verify later)
+		if isHeavyFormat(types.name(path)) || len(scanner.folded) == 0 {
 			// include heavy binary types as candidates; full check later
 			matches = append(matches, path)
 			return nil
@@ -237,9 +246,8 @@ func FindFilesWithFirstWord(word string, fileTypes []string) ([]string, error) {
 
 		// Stream up to maxBytes looking for the first word
 		const chunkSize = 64 * 1024
-		const maxBytes = 5 * 1024 * 1024
 		overlap := 32
-		if l := len(wLower) - 1; l > overlap {
+		if l := scanner.overlap(); l > overlap {
 			overlap = l
 		}
 
@@ -247,11 +255,13 @@ func FindFilesWithFirstWord(word string, fileTypes []string) ([]string, error) {
 		if openErr != nil {
 			return nil
 		}
+		const maxBytes = 5 * 1024 * 1024
+		src := textStream(path, f, maxBytes, TextOptions{})
 
 		// Early path for small files: read whole file at once, avoid chunk loop
 		if st, stErr := f.Stat(); stErr == nil && st.Size() <= chunkSize {
-			data, _ := io.ReadAll(f)
-			found := asciiIndexWholeWordCI(data, []byte(wLower))
+			data, _ := io.ReadAll(src)
+			found := scanner.Match(data)
 			if found {
 				matches = append(matches, path)
 			}
@@ -272,10 +282,10 @@ func FindFilesWithFirstWord(word string, fileTypes []string) ([]string, error) {
 			if rem := maxBytes - total; rem < int64(toRead) {
 				toRead = int(rem)
 			}
-			n, rErr := f.Read(buf[:toRead])
+			n, rErr := src.Read(buf[:toRead])
 			if n > 0 {
 				combined := append(prev, buf[:n]...)
-				if asciiIndexWholeWordCI(combined, []byte(wLower)) {
+				if scanner.Match(combined) {
 					found = true
 				}
 				if n >= overlap {
@@ -314,26 +324,47 @@ func FindFilesWithFirstWord(word string, fileTypes []string) ([]string, error) {
 }
 
 // FindFilesWithFirstWordProgress is like FindFilesWithFirstWord but emits per-file discovery progress.
-func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers int, onProgress func(processed, total int, path string)) ([]string, error) {
-	// Parse allowed extensions from patterns like "-g", "*.txt"
-	allowed := make(map[string]bool)
-	for i := 0; i < len(fileTypes); i++ {
-		if fileTypes[i] == "-g" && i+1 < len(fileTypes) {
-			i++
-			glob := fileTypes[i]
-			if strings.HasPrefix(glob, "*.") {
-				ext := strings.ToLower(glob[1:]) // ".txt"
-				allowed[ext] = true
-			}
-		}
-	}
+func FindFilesWithFirstWordProgress(ctx context.Context, roots []string, words []string, fileTypes []string, workers int, onProgress func(processed, total int, path string)) ([]string, error) {
+	return findFilesWithFirstWordProgress(ctx, roots, WalkOptions{}, singletonGroups(words), newTypeFilter(fileTypes), TextOptions{}, workers, nil, onProgress)
+}
 
+// findFilesWithFirstWordProgress is FindFilesWithFirstWordProgress over query groups, walking
+// the roots with walk and selecting files with types: a file is a candidate when it contains
+// any alternative of the first group. Heavy files are prefiltered on mandatory terms only.
+// The optional decide hook is consulted before any file is read; when it returns decided,
+// its answer is used as-is.
+func findFilesWithFirstWordProgress(ctx context.Context, roots []string, walk WalkOptions, groups [][]string, types typeFilter, opts TextOptions, workers int, decide func(path string, info fs.FileInfo) (bool, bool), onProgress func(processed, total int, path string)) ([]string, error) {
 	// Emit initial progress with unknown total
 	if onProgress != nil {
 		onProgress(0, 0, "")
 	}
 
-	primaryLower := strings.ToLower(words[0])
+	// Phrases are checked word by word here; the phrase itself is verified when filtering.
+	// The first group without regex alternatives is scanned for; with none, every file is a candidate.
+	var primaries []wordScanner
+	overlap := 32
+	for _, group := range groups {
+		if slices.ContainsFunc(group, isRegexTerm) {
+			continue
+		}
+		for _, alt := range group {
+			p := newWordScanner(longestTerm(prefilterTerms([]string{alt})), opts.FoldDiacritics)
+			primaries = append(primaries, p)
+			if l := p.overlap(); l > overlap {
+				overlap = l
+			}
+		}
+		break
+	}
+	containsPrimary := func(buf []byte) bool {
+		for _, p := range primaries {
+			if p.Match(buf) {
+				return true
+			}
+		}
+		return false
+	}
+	words := prefilterTerms(mandatoryTerms(groups))
 	termsToCheck := words
 	if len(words) >= 3 {
 		terms := make([]string, len(words))
@@ -341,14 +372,6 @@ func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers
 		sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
 		termsToCheck = terms[:2]
 	}
-	heavy := map[string]bool{
-		".pdf":  true,
-		".docx": true,
-		".odt":  true,
-		".msg":  true,
-		".eml":  true,
-		".mbox": true,
-	}
 
 	// Results and synchronization
 	matches := make([]string, 0, 128)
@@ -373,25 +396,26 @@ func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers
 			defer wg.Done()
 			const chunkSize = 64 * 1024
 			const maxBytes = 5 * 1024 * 1024
-			overlap := 32
-			if l := len(primaryLower) - 1; l > overlap {
-				overlap = l
-			}
 
 			for p := range paths {
+				if ctx.Err() != nil {
+					// Cancelled: drain the feed without reading files
+					continue
+				}
 
 				f, openErr := os.Open(p)
 				if openErr != nil {
 					continue
 				}
+				src := textStream(p, f, maxBytes, opts)
 
 				// Early path for small files: read whole file at once, avoid chunk loop
 				if st, stErr := f.Stat(); stErr == nil && st.Size() <= chunkSize {
-					data, _ := io.ReadAll(f)
+					data, _ := io.ReadAll(src)
 					_ = unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
 					_ = f.Close()
 
-					found := asciiIndexWholeWordCI(data, []byte(primaryLower))
+					found := containsPrimary(data)
 					if found {
 						mu.Lock()
 						matches = append(matches, p)
@@ -413,10 +437,10 @@ func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers
 					if rem := maxBytes - readTotal; rem < int64(toRead) {
 						toRead = int(rem)
 					}
-					n, rErr := f.Read(buf[:toRead])
+					n, rErr := src.Read(buf[:toRead])
 					if n > 0 {
-						combined := append(prev, buf[:toRead]...)
-						if asciiIndexWholeWordCI(combined, []byte(primaryLower)) {
+						combined := append(prev, buf[:n]...)
+						if containsPrimary(combined) {
 							found = true
 						}
 						if n >= overlap {
@@ -441,7 +465,8 @@ func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers
 				_ = unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_DONTNEED)
 				_ = f.Close()
 
-				if found || (!found && readTotal >= maxBytes) {
+				// A file cut at the budget may hold the word further on: keep it
+				if found || readTotal >= maxBytes {
 					mu.Lock()
 					matches = append(matches, p)
 					mu.Unlock()
@@ -453,29 +478,42 @@ func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers
 	processed := 0
 
 	// Walk and stream paths to workers
-	err := filepath.WalkDir(".", func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
+	err := walkRoots(ctx, roots, walk, func(path string, d fs.DirEntry) error {
+		if !types.selects(path) {
 			return nil
 		}
-		if d.IsDir() {
-			if d.Name() != "." && config.ShouldSkipDirectory(d.Name()) {
-				return filepath.SkipDir
-			}
-			return nil
+
+		processed++
+		if onProgress != nil {
+			onProgress(processed, 0, path)
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		if len(allowed) > 0 && !allowed[ext] {
+		// Nothing literal to scan for (regex-only query): verify every file when filtering
+		if len(primaries) == 0 {
+			mu.Lock()
+			matches = append(matches, path)
+			mu.Unlock()
 			return nil
 		}
 
-		processed++
-		if onProgress != nil {
-			onProgress(processed, 0, path)
+		// Indexed and unchanged: no need to open the file
+		if decide != nil {
+			if info, infoErr := d.Info(); infoErr == nil {
+				if found, decided := decide(path, info); decided {
+					if found {
+						mu.Lock()
+						matches = append(matches, path)
+						mu.Unlock()
+					}
+					return nil
+				}
+			}
 		}
 
 		// Heavy files: conservative prefilter for non-PDF; include unless decisively absent
-		if heavy[ext] {
+		name := types.name(path)
+		if isHeavyFormat(name) {
+			ext := formatExt(name)
 			if ext == ".pdf" {
 				// PDFs are handled later under strict guardrails; include as candidate
 				mu.Lock()
@@ -492,7 +530,7 @@ func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers
 			default:
 				capBytes = 2 * 1024 * 1024
 			}
-			found, decided := BinaryStreamingPrefilterDecided(path, termsToCheck, capBytes)
+			found, decided := binaryPrefilter(path, name, termsToCheck, capBytes, opts)
 			if decided && !found {
 				return nil // safe to skip
 			}
@@ -520,8 +558,57 @@ func FindFilesWithFirstWordProgress(words []string, fileTypes []string, workers
 	return matches, nil
 }
 
+// walkRoots walks every root in order and calls fn for each regular file found.
+// Directories and files are selected by opts (see WalkOptions) below each root (the root
+// itself is always entered), permission errors are ignored, and files reachable from
+// overlapping roots are reported once. An empty roots list walks the current directory.
+// The walk stops with ctx's error as soon as ctx is cancelled.
+func walkRoots(ctx context.Context, roots []string, opts WalkOptions, fn func(path string, d fs.DirEntry) error) error {
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	var seen map[string]bool
+	if len(roots) > 1 {
+		seen = make(map[string]bool)
+	}
+	for _, root := range roots {
+		filter := newWalkFilter(root, opts)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				// Ignore permission errors; keep walking
+				return nil
+			}
+			if d.IsDir() {
+				if !filter.enterDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !filter.keepFile(path) {
+				return nil
+			}
+			if seen != nil {
+				key := GetAbsolutePath(path)
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+			return fn(path, d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // StreamContainsAllWords streams a file and returns true if all words are present (unordered, plural-aware, CI).
-func StreamContainsAllWordsDecided(filePath string, words []string) (found bool, decided bool) {
+func StreamContainsAllWordsDecided(filePath string, words []string, opts TextOptions) (found bool, decided bool) {
+	words = prefilterTerms(words)
 	if len(words) == 0 {
 		return true, true
 	}
@@ -531,15 +618,14 @@ func StreamContainsAllWordsDecided(filePath string, words []string) (found bool,
 	}
 	defer f.Close()
 
-	// Build plural-aware whole-word regexes (?i)\b(?:word(?:es|s)?)\b
-	res := make([]*regexp.Regexp, 0, len(words))
+	// Build plural-aware whole-word matchers
+	res := make([]*Matcher, 0, len(words))
 	for _, w := range words {
 		w = strings.TrimSpace(w)
 		if w == "" {
 			continue
 		}
-		pat := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(w))
-		res = append(res, regexp.MustCompile(pat))
+		res = append(res, newMatcher(w, pluralSuffixes, opts.FoldDiacritics))
 	}
 	if len(res) == 0 {
 		return true, true
@@ -549,20 +635,9 @@ func StreamContainsAllWordsDecided(filePath string, words []string) (found bool,
 	const overlap = 128
 
 	// Align with GetFileContent limits
-	stat, statErr := f.Stat()
-	var maxBytes int64
-	if statErr == nil {
-		switch {
-		case stat.Size() > 50*1024*1024:
-			maxBytes = 10 * 1024 * 1024
-		case stat.Size() > 10*1024*1024:
-			maxBytes = 5 * 1024 * 1024
-		default:
-			maxBytes = stat.Size()
-		}
-	} else {
-		maxBytes = 10 * 1024 * 1024
-	}
+	maxBytes := readLimit(f)
+
+	src := textStream(filePath, f, maxBytes, opts)
 
 	foundFlags := make([]bool, len(res))
 	remaining := len(res)
@@ -580,7 +655,7 @@ func StreamContainsAllWordsDecided(filePath string, words []string) (found bool,
 		if rem := maxBytes - total; rem < int64(toRead) {
 			toRead = int(rem)
 		}
-		n, rErr := f.Read(buf[:toRead])
+		n, rErr := src.Read(buf[:toRead])
 		if n > 0 {
 			combined := append(prev, buf[:n]...)
 			for i, re := range res {
@@ -618,7 +693,7 @@ func StreamContainsAllWordsDecided(filePath string, words []string) (found bool,
 }
 
 func StreamContainsAllWords(filePath string, words []string) bool {
-	found, _ := StreamContainsAllWordsDecided(filePath, words)
+	found, _ := StreamContainsAllWordsDecided(filePath, words, TextOptions{})
 	return found
 }
 
@@ -626,7 +701,8 @@ func StreamContainsAllWords(filePath string, words []string) bool {
 // - found = true, decided = true: conclusively found all words
 // - found = false, decided = true: conclusively not all words present
 // - found = false, decided = false: budget reached; prefilter is undecided (do not skip)
-func StreamContainsAllWordsDecidedWithCap(filePath string, words []string, capBytes int64) (bool, bool) {
+func StreamContainsAllWordsDecidedWithCap(filePath string, words []string, capBytes int64, opts TextOptions) (bool, bool) {
+	words = prefilterTerms(words)
 	if len(words) == 0 {
 		return true, true
 	}
@@ -636,14 +712,14 @@ func StreamContainsAllWordsDecidedWithCap(filePath string, words []string, capBy
 	}
 	defer f.Close()
 
-	// Build plural/smart-forms aware whole-word regexes
-	res := make([]*regexp.Regexp, 0, len(words))
+	// Build plural/smart-forms aware whole-word matchers
+	res := make([]*Matcher, 0, len(words))
 	for _, w := range words {
 		w = strings.TrimSpace(w)
 		if w == "" {
 			continue
 		}
-		res = append(res, buildWordRegexCI(w))
+		res = append(res, newMatcher(w, wordFormSuffixes(), opts.FoldDiacritics))
 	}
 	if len(res) == 0 {
 		return true, true
@@ -653,21 +729,8 @@ func StreamContainsAllWordsDecidedWithCap(filePath string, words []string, capBy
 	const overlap = 128
 
 	// Align with GetFileContent limits, then apply optional capBytes
-	stat, statErr := f.Stat()
-	var maxBytes int64
+	maxBytes := readLimit(f)
 	var capped bool
-	if statErr == nil {
-		switch {
-		case stat.Size() > 50*1024*1024:
-			maxBytes = 10 * 1024 * 1024
-		case stat.Size() > 10*1024*1024:
-			maxBytes = 5 * 1024 * 1024
-		default:
-			maxBytes = stat.Size()
-		}
-	} else {
-		maxBytes = 10 * 1024 * 1024
-	}
 	if capBytes > 0 && capBytes < maxBytes {
 		maxBytes = capBytes
 		capped = true
@@ -728,7 +791,8 @@ func StreamContainsAllWordsDecidedWithCap(filePath string, words []string, capBy
 }
 
 // BinaryStreamingPrefilterDecided performs a bounded streaming prefilter for select binary types
-// (eml, msg, mbox, rtf). It returns:
+// (eml, msg, mbox, rtf, doc, ipynb, json, and the XML entries of docx, odt, xlsx, ods, pptx, odp,
+// epub). It returns:
 //   - found = true, decided = true   => conclusively found (prefilter passes)
 //   - found = false, decided = true  => conclusively absent (prefilter fails; safe to skip)
 //   - found = false, decided = false => inconclusive (do not skip; proceed to extraction)
@@ -736,10 +800,18 @@ func StreamContainsAllWordsDecidedWithCap(filePath string, words []string, capBy
 // It uses the existing StreamContainsAllWordsDecidedWithCap checker and, for 3+ terms,
 // picks two longest terms as a rarity proxy to improve prefilter efficiency.
 func BinaryStreamingPrefilterDecided(filePath string, words []string, capBytes int64) (bool, bool) {
-	ext := strings.ToLower(filepath.Ext(filePath))
+	return binaryPrefilter(filePath, filePath, words, capBytes, TextOptions{})
+}
+
+// binaryPrefilter is BinaryStreamingPrefilterDecided for a file whose format name tells
+// (see typeFilter.name), scanned with opts
+func binaryPrefilter(filePath, name string, words []string, capBytes int64, opts TextOptions) (bool, bool) {
+	// Raw bytes: look for the words of each phrase; the phrase itself is verified after extraction
+	words = prefilterTerms(words)
+	ext := formatExt(name)
 	switch ext {
-	case ".eml", ".msg", ".mbox", ".rtf":
-		// Existing streaming prefilter for email/rtf-like formats
+	case ".eml", ".msg", ".mbox":
+		// Existing streaming prefilter for email formats
 		termsToCheck := words
 		if len(words) >= 3 {
 			terms := make([]string, len(words))
@@ -747,14 +819,120 @@ func BinaryStreamingPrefilterDecided(filePath string, words []string, capBytes i
 			sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
 			termsToCheck = terms[:2]
 		}
-		return StreamContainsAllWordsDecidedWithCap(filePath, termsToCheck, capBytes)
+		found, decided := StreamContainsAllWordsDecidedWithCap(filePath, termsToCheck, capBytes, opts)
+		if decided && !found && mayHaveAttachments(filePath, name) {
+			// The raw bytes do not show attachment text (base64, compressed formats)
+			return false, false
+		}
+		return found, decided
+
+	case ".rtf":
+		// Scan the parsed text: control words, \'hh and \u escapes and hidden destinations
+		// would otherwise hide words or fake them. The cap applies to the text.
+		f, err := os.Open(filePath)
+		if err != nil {
+			return false, false
+		}
+		res := make([]*Matcher, 0, len(words))
+		for _, w := range words {
+			if w = strings.TrimSpace(w); w != "" {
+				res = append(res, newMatcher(w, wordFormSuffixes(), opts.FoldDiacritics))
+			}
+		}
+		if len(res) == 0 {
+			f.Close()
+			return true, true
+		}
+		rc := rtfStreamText(f)
+		defer rc.Close()
 
-	case ".docx", ".odt":
-		// Conservative ZIP sniff + capped XML stream:
-		// - .docx: stream "word/document.xml"
-		// - .odt:  stream "content.xml"
+		const chunkSize = 64 * 1024
+		const overlap = 128
+		maxBytes := capBytes
+		if maxBytes <= 0 {
+			maxBytes = 5 * 1024 * 1024
+		}
+		foundFlags := make([]bool, len(res))
+		remaining := len(res)
+		var total int64
+		buf := make([]byte, chunkSize)
+		prev := make([]byte, 0, overlap)
+		for total < maxBytes {
+			toRead := chunkSize
+			if rem := maxBytes - total; rem < int64(toRead) {
+				toRead = int(rem)
+			}
+			n, rErr := rc.Read(buf[:toRead])
+			if n > 0 {
+				combined := append(prev, buf[:n]...)
+				for i, re := range res {
+					if !foundFlags[i] && re.Match(combined) {
+						foundFlags[i] = true
+						remaining--
+						if remaining == 0 {
+							return true, true
+						}
+					}
+				}
+				if len(combined) > overlap {
+					combined = combined[len(combined)-overlap:]
+				}
+				prev = append(prev[:0], combined...)
+				total += int64(n)
+			}
+			if rErr == io.EOF {
+				// Whole document scanned; conclusively absent
+				return false, true
+			}
+			if rErr != nil {
+				return false, false
+			}
+		}
+		// Budget reached; undecided
+		return false, false
+
+	case ".ipynb", ".json":
+		// Match the extracted values: escapes (\n, é) and punctuation in the raw
+		// bytes would hide words. Files over the cap are undecided.
+		maxBytes := capBytes
+		if maxBytes <= 0 {
+			maxBytes = 2 * 1024 * 1024
+		}
+		st, err := os.Stat(filePath)
+		if err != nil || st.Size() > maxBytes {
+			return false, false
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return false, false
+		}
+		var text string
+		if ext == ".ipynb" {
+			text, err = (&IPYNBExtractor{}).ExtractText(data)
+		} else {
+			text, err = (&JSONExtractor{}).ExtractText(data)
+		}
+		if err != nil {
+			return false, false
+		}
+		for _, w := range words {
+			if w = strings.TrimSpace(w); w != "" && !newMatcher(w, wordFormSuffixes(), opts.FoldDiacritics).MatchString(text) {
+				return false, true
+			}
+		}
+		return true, true
+
+	case ".docx", ".odt", ".xlsx", ".ods", ".pptx", ".odp", ".epub":
+		// Conservative ZIP sniff + capped XML stream over the text-bearing entries
+		// (see officeTextEntries), sharing one budget:
+		// - .docx: "word/document.xml"; .odt/.ods/.odp: "content.xml" (plus the --doc-extras
+		//   parts)
+		// - .xlsx: "xl/sharedStrings.xml" plus every worksheet (inline strings, numbers)
+		// - .pptx: every slide plus speaker notes
+		// All of them are streamed as extracted text rather than raw XML (see writeOfficeText).
+		// - .epub: the spine chapters, streamed as cleaned text rather than raw XHTML
 		// If we can conclusively find all words: return (true, true)
-		// If we can conclusively determine absence at EOF: return (false, true)
+		// If we can conclusively determine absence at the end of the last entry: return (false, true)
 		// Otherwise (errors, missing entries, or cap reached): return (false, false)
 		f, err := os.Open(filePath)
 		if err != nil {
@@ -772,45 +950,26 @@ func BinaryStreamingPrefilterDecided(filePath string, words []string, capBytes i
 			return false, false
 		}
 
-		var target string
-		if ext == ".docx" {
-			target = "word/document.xml"
-		} else {
-			target = "content.xml"
-		}
-
-		var xmlFile *zip.File
-		for _, file := range zr.File {
-			if file.Name == target {
-				xmlFile = file
-				break
-			}
-		}
-		if xmlFile == nil {
+		xmlFiles := officeTextEntries(zr, ext, opts.DocExtras)
+		if len(xmlFiles) == 0 {
 			// Can't locate the main document stream; undecided
 			return false, false
 		}
 
-		rc, err := xmlFile.Open()
-		if err != nil {
-			return false, false
-		}
-		defer rc.Close()
-
-		// Build plural/smart-forms aware whole-word regexes
-		res := make([]*regexp.Regexp, 0, len(words))
+		// Build plural/smart-forms aware whole-word matchers
+		res := make([]*Matcher, 0, len(words))
 		for _, w := range words {
 			w = strings.TrimSpace(w)
 			if w == "" {
 				continue
 			}
-			res = append(res, buildWordRegexCI(w))
+			res = append(res, newMatcher(w, wordFormSuffixes(), opts.FoldDiacritics))
 		}
 		if len(res) == 0 {
 			return true, true
 		}
 
-		// Stream the XML entry with a cap and overlap window
+		// Stream the XML entries with a cap and overlap window
 		const chunkSize = 64 * 1024
 		const overlap = 128
 
@@ -824,79 +983,95 @@ func BinaryStreamingPrefilterDecided(filePath string, words []string, capBytes i
 		remaining := len(res)
 
 		var total int64
-		prev := make([]byte, 0, overlap)
 		buf := make([]byte, chunkSize)
 
-		for {
-			if total >= maxBytes {
-				// Budget reached; undecided
+		for _, xmlFile := range xmlFiles {
+			rc, err := xmlFile.Open()
+			if err != nil {
 				return false, false
 			}
-			toRead := chunkSize
-			if rem := maxBytes - total; rem < int64(toRead) {
-				toRead = int(rem)
+			// Scan the text the extractor sees: words split across runs are joined and
+			// entities decoded
+			if ext == ".epub" {
+				rc = xhtmlEntryText(rc)
+			} else {
+				rc = officeEntryText(rc, ext, xmlFile.Name, opts.DocExtras)
 			}
-			n, rErr := rc.Read(buf[:toRead])
-			if n > 0 {
-				combined := append(prev, buf[:n]...)
-				for i, re := range res {
-					if !foundFlags[i] && re.Match(combined) {
-						foundFlags[i] = true
-						remaining--
-						if remaining == 0 {
-							return true, true
+			prev := make([]byte, 0, overlap)
+			for {
+				if total >= maxBytes {
+					// Budget reached; undecided
+					rc.Close()
+					return false, false
+				}
+				toRead := chunkSize
+				if rem := maxBytes - total; rem < int64(toRead) {
+					toRead = int(rem)
+				}
+				n, rErr := rc.Read(buf[:toRead])
+				if n > 0 {
+					combined := append(prev, buf[:n]...)
+					for i, re := range res {
+						if !foundFlags[i] && re.Match(combined) {
+							foundFlags[i] = true
+							remaining--
+							if remaining == 0 {
+								rc.Close()
+								return true, true
+							}
 						}
 					}
-				}
 
-				// Maintain overlap
-				if n >= overlap {
-					prev = append(prev[:0], buf[n-overlap:n]...)
-				} else {
-					if len(combined) >= overlap {
-						prev = append(prev[:0], combined[len(combined)-overlap:]...)
+					// Maintain overlap
+					if n >= overlap {
+						prev = append(prev[:0], buf[n-overlap:n]...)
 					} else {
-						prev = append(prev[:0], combined...)
+						if len(combined) >= overlap {
+							prev = append(prev[:0], combined[len(combined)-overlap:]...)
+						} else {
+							prev = append(prev[:0], combined...)
+						}
 					}
+					total += int64(n)
 				}
-				total += int64(n)
-			}
 
-			if rErr == io.EOF {
-				// End of stream; conclusively absent
-				return false, true
-			}
-			if rErr != nil {
-				// I/O/read error on entry: undecided
-				return false, false
+				if rErr == io.EOF {
+					// End of this entry; move on to the next
+					break
+				}
+				if rErr != nil {
+					// I/O/read error on entry: undecided
+					rc.Close()
+					return false, false
+				}
 			}
+			rc.Close()
 		}
+		// Every entry streamed to the end; conclusively absent
+		return false, true
 
 	case ".doc":
-		// Conservative OLE (.doc) prefilter:
-		// - Open the compound file and stream a few likely text-bearing streams (WordDocument, 1Table, 0Table)
-		// - Salvage text best-effort (UTF-16 if possible, else ASCII with whitespace normalization)
-		// - If all words are conclusively found within a capped budget: (true, true)
-		// - Otherwise: (false, false) — undecided (never mark as conclusively absent)
+		// OLE (.doc) prefilter:
+		// - Parse the piece table (see wordDocumentText) and match the document text, up to
+		//   the cap: found (true, true), absent from the whole text (false, true)
+		// - Files that do not parse (Word 95 and older, damaged): stream a few likely
+		//   text-bearing streams (WordDocument, 1Table, 0Table), salvage text best-effort
+		//   (UTF-16 if possible, else ASCII with whitespace normalization), and only ever
+		//   conclude found (true, true); otherwise (false, false) — undecided
 		f, err := os.Open(filePath)
 		if err != nil {
 			return false, false
 		}
 		defer f.Close()
 
-		cf, err := mscfb.New(f)
-		if err != nil {
-			return false, false
-		}
-
-		// Build plural/smart-forms aware whole-word regexes
-		res := make([]*regexp.Regexp, 0, len(words))
+		// Build plural/smart-forms aware whole-word matchers
+		res := make([]*Matcher, 0, len(words))
 		for _, w := range words {
 			w = strings.TrimSpace(w)
 			if w == "" {
 				continue
 			}
-			res = append(res, buildWordRegexCI(w))
+			res = append(res, newMatcher(w, wordFormSuffixes(), opts.FoldDiacritics))
 		}
 		if len(res) == 0 {
 			return true, true
@@ -907,6 +1082,26 @@ func BinaryStreamingPrefilterDecided(filePath string, words []string, capBytes i
 		if maxBytes <= 0 {
 			maxBytes = 2 * 1024 * 1024 // 2MB default cap
 		}
+
+		if text, err := wordDocumentText(f, opts.DocExtras); err == nil {
+			capped := int64(len(text)) > maxBytes
+			if capped {
+				text = text[:maxBytes]
+			}
+			for _, re := range res {
+				if !re.MatchString(text) {
+					return false, !capped
+				}
+			}
+			return true, true
+		} else if errors.Is(err, errWordEncrypted) {
+			return false, true
+		}
+
+		cf, err := mscfb.New(f)
+		if err != nil {
+			return false, false
+		}
 		var total int64
 
 		// Prioritized streams commonly containing main/body text
@@ -980,22 +1175,30 @@ func BinaryStreamingPrefilterDecided(filePath string, words []string, capBytes i
 }
 
 // CheckFileContainsAllWords checks if a file contains all search words
-func CheckFileContainsAllWords(filePath string, words []string, distance int, silent bool) (bool, error) {
-	// Fast prefilter: require presence of all words before full distance check
-	if !StreamContainsAllWords(filePath, words) {
-		return false, nil
+func CheckFileContainsAllWords(filePath string, words []string, distance int, silent bool, opts TextOptions) (bool, error) {
+	return CheckFileMatchesGroups(filePath, singletonGroups(words), distance, silent, opts)
+}
+
+// CheckFileMatchesGroups checks if a file, read and matched with opts, satisfies every
+// group within the distance window
+func CheckFileMatchesGroups(filePath string, groups [][]string, distance int, silent bool, opts TextOptions) (bool, error) {
+	// Fast prefilter: require presence of all mandatory words before full distance check
+	if mandatory := mandatoryTerms(groups); len(mandatory) > 0 {
+		if found, _ := StreamContainsAllWordsDecided(filePath, mandatory, opts); !found {
+			return false, nil
+		}
 	}
 
-	content, _, err := GetFileContent(filePath)
+	content, _, err := readFileContent(filePath, filePath, opts)
 	if err != nil {
 		return false, err
 	}
 	// Clean the content so matching aligns with excerpt generation
-	return CheckTextContainsAllWords(CleanContent(content), words, distance), nil
+	return CheckTextMatchesGroups(CleanContent(content), groups, distance, opts.FoldDiacritics), nil
 }
 
 // CheckFileContainsExcludeWords checks if a file contains any exclude words
-func CheckFileContainsExcludeWords(filePath string, excludeWords []string) (bool, error) {
+func CheckFileContainsExcludeWords(filePath string, excludeWords []string, opts TextOptions) (bool, error) {
 	if len(excludeWords) == 0 {
 		return false, nil
 	}
@@ -1014,24 +1217,26 @@ func CheckFileContainsExcludeWords(filePath string, excludeWords []string) (bool
 
 	var reader io.Reader = file
 
-	// Limit read size for large files
-	if stat.Size() > 50*1024*1024 { // 50MB
-		reader = io.LimitReader(file, 10*1024*1024) // Read first 10MB
-	} else if stat.Size() > 10*1024*1024 { // 10MB
-		reader = io.LimitReader(file, 5*1024*1024) // Read first 5MB
+	// Limit read size for large files (first 10MB above 50MB, first 5MB above 10MB by default)
+	limits := config.ActiveLimits
+	if stat.Size() > limits.LargeFileBytes {
+		reader = io.LimitReader(file, limits.LargeFileRead)
+	} else if stat.Size() > limits.MediumFileBytes {
+		reader = io.LimitReader(file, limits.MediumFileRead)
 	}
 
 	// Read content
+	reader = textReader(filePath, reader, opts)
 	content, err := io.ReadAll(reader)
 	if err != nil {
 		return false, err
 	}
 
-	contentStr := strings.ToLower(string(content))
+	contentStr := string(content)
 
 	// Check each exclude word
 	for _, word := range excludeWords {
-		if containsWholeWord(contentStr, strings.ToLower(word)) {
+		if containsWholeWord(contentStr, word, opts.FoldDiacritics) {
 			return true, nil
 		}
 	}
@@ -1039,8 +1244,31 @@ func CheckFileContainsExcludeWords(filePath string, excludeWords []string) (bool
 	return false, nil
 }
 
+// readLimit returns how many bytes of f GetFileContent reads under the active limits
+// (the large-file read when the size is unknown)
+func readLimit(f *os.File) int64 {
+	limits := config.ActiveLimits
+	stat, err := f.Stat()
+	switch {
+	case err != nil:
+		return limits.LargeFileRead
+	case stat.Size() > limits.LargeFileBytes:
+		return limits.LargeFileRead
+	case stat.Size() > limits.MediumFileBytes:
+		return limits.MediumFileRead
+	default:
+		return stat.Size()
+	}
+}
+
 // GetFileContent reads and returns file content with size limits
 func GetFileContent(filePath string) (string, int64, error) {
+	return readFileContent(filePath, filePath, TextOptions{})
+}
+
+// readFileContent is GetFileContent for a file whose format name tells (see
+// typeFilter.name), read with opts
+func readFileContent(filePath, name string, opts TextOptions) (string, int64, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", 0, err
@@ -1055,14 +1283,16 @@ func GetFileContent(filePath string) (string, int64, error) {
 
 	var reader io.Reader = file
 
-	// Limit read size for large files
-	if stat.Size() > 50*1024*1024 { // 50MB
-		reader = io.LimitReader(file, 10*1024*1024) // Read first 10MB
-	} else if stat.Size() > 10*1024*1024 { // 10MB
-		reader = io.LimitReader(file, 5*1024*1024) // Read first 5MB
+	// Limit read size for large files (first 10MB above 50MB, first 5MB above 10MB by default)
+	limits := config.ActiveLimits
+	if stat.Size() > limits.LargeFileBytes {
+		reader = io.LimitReader(file, limits.LargeFileRead)
+	} else if stat.Size() > limits.MediumFileBytes {
+		reader = io.LimitReader(file, limits.MediumFileRead)
 	}
 
 	// Read content
+	reader = textReader(name, reader, opts)
 	content, err := io.ReadAll(reader)
 	if err != nil {
 		return "", 0, err
@@ -1087,8 +1317,7 @@ func FormatFileSize(size int64) string {
 
 // StreamContainsWord checks if a file contains a given word using streaming read
 func StreamContainsWord(filePath string, word string) bool {
-	pattern := fmt.Sprintf(`(?i)\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(word))
-	re := regexp.MustCompile(pattern)
+	re := newMatcher(word, pluralSuffixes, false)
 
 	f, err := os.Open(filePath)
 	if err != nil {
@@ -1100,21 +1329,8 @@ func StreamContainsWord(filePath string, word string) bool {
 	const overlap = 128
 
 	// Compute maxBytes consistent with GetFileContent limits
-	stat, statErr := f.Stat()
-	var maxBytes int64
-	if statErr == nil {
-		switch {
-		case stat.Size() > 50*1024*1024:
-			maxBytes = 10 * 1024 * 1024
-		case stat.Size() > 10*1024*1024:
-			maxBytes = 5 * 1024 * 1024
-		default:
-			maxBytes = stat.Size()
-		}
-	} else {
-		// Fallback to previous hard cap if stat fails
-		maxBytes = 10 * 1024 * 1024
-	}
+	maxBytes := readLimit(f)
+	src := textStream(filePath, f, maxBytes, TextOptions{})
 	var total int64
 	prev := make([]byte, 0, overlap)
 	buf := make([]byte, chunkSize)
@@ -1126,7 +1342,7 @@ func StreamContainsWord(filePath string, word string) bool {
 		if rem := maxBytes - total; rem < int64(toRead) {
 			toRead = int(rem)
 		}
-		n, rErr := f.Read(buf[:toRead])
+		n, rErr := src.Read(buf[:toRead])
 		if n > 0 {
 			combined := append(prev, buf[:n]...)
 			if re.Match(combined) {
@@ -1161,6 +1377,32 @@ func pdfIsolatedScan(filePath string, words []string) (bool, bool) {
 	return false, false
 }
 
+// wordScanner is the discovery-time check for one literal word: the fast ASCII scan for its
+// folded form, backed by the Unicode-aware matcher for buffers holding non-ASCII bytes
+// (accented letters, other scripts, ß).
+type wordScanner struct {
+	folded []byte
+	m      *Matcher
+}
+
+func newWordScanner(word string, diacritics bool) wordScanner {
+	return wordScanner{folded: []byte(fold.String(word, diacritics)), m: newMatcher(word, pluralSuffixes, diacritics)}
+}
+
+// Match reports whether buf contains the word (whole word, plural-aware, case-folded)
+func (w wordScanner) Match(buf []byte) bool {
+	if len(w.folded) == 0 {
+		return false
+	}
+	return asciiIndexWholeWordCI(buf, w.folded) || (!fold.IsASCII(buf) && w.m.Match(buf))
+}
+
+// overlap is the chunk overlap needed to see the word across a read boundary; folding may
+// shrink the word (é -> e), so allow for the original being up to twice as long.
+func (w wordScanner) overlap() int {
+	return 2*len(w.folded) + 2
+}
+
 func asciiIndexWholeWordCI(buf []byte, wordLower []byte) bool {
 	if len(wordLower) == 0 || len(buf) < len(wordLower) {
 		return false
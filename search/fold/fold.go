@@ -0,0 +1,161 @@
+// Package fold normalizes text for matching: full Unicode case folding (so "Straße"
+// and "STRASSE" compare equal) and, optionally, diacritic removal (so "résumé" matches
+// "resume"). It also defines the Unicode word characters used for whole-word boundaries.
+package fold
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Per-rune fold results; folding is context-free, so caching by rune is exact.
+var (
+	foldCache      sync.Map // rune -> string
+	foldCacheMarks sync.Map // rune -> string (diacritics removed)
+)
+
+// String returns s case-folded, with diacritics removed when diacritics is true.
+func String(s string, diacritics bool) string {
+	if isASCII(s) {
+		return asciiLower(s)
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			b.WriteByte(asciiLowerByte(byte(r)))
+			continue
+		}
+		b.WriteString(foldRune(r, diacritics))
+	}
+	return b.String()
+}
+
+// StringOffsets is String plus a map back to s: offsets[i] is the byte offset in s of the
+// rune that produced folded byte i, and offsets[len(folded)] == len(s).
+func StringOffsets(s string, diacritics bool) (string, []int) {
+	offsets := make([]int, 0, len(s)+1)
+	if isASCII(s) {
+		for i := 0; i <= len(s); i++ {
+			offsets = append(offsets, i)
+		}
+		return asciiLower(s), offsets
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i, r := range s {
+		if r < utf8.RuneSelf {
+			b.WriteByte(asciiLowerByte(byte(r)))
+			offsets = append(offsets, i)
+			continue
+		}
+		f := foldRune(r, diacritics)
+		b.WriteString(f)
+		for range len(f) {
+			offsets = append(offsets, i)
+		}
+	}
+	offsets = append(offsets, len(s))
+	return b.String(), offsets
+}
+
+// IsWordRune reports whether r is part of a word: a letter, digit, combining mark or '_'.
+func IsWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// IsSelfDelimiting reports whether r is a word character of a script written without
+// spaces (Han, Hiragana, Katakana, Hangul): as in UAX #29, a word boundary falls on both
+// sides of it, so "会議" is a whole word in "会議の議事録です".
+func IsSelfDelimiting(r rune) bool {
+	return r >= 0x1100 && unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// joins reports whether adjacent runes a and b belong to the same word
+func joins(a, b rune) bool {
+	return IsWordRune(a) && IsWordRune(b) && !IsSelfDelimiting(a) && !IsSelfDelimiting(b)
+}
+
+// WholeWordAt reports whether s[start:end] is not embedded in a larger word, i.e. the runes
+// just outside it do not continue the word at its edges. A side whose edge rune inside the
+// match is not a word rune itself (e.g. the "+" of "c++") needs no boundary, and neither
+// does one next to a self-delimiting rune (see IsSelfDelimiting).
+func WholeWordAt[T string | []byte](s T, start, end int) bool {
+	if start > 0 && start < len(s) {
+		first, _ := utf8.DecodeRuneInString(string(s[start:min(len(s), start+utf8.UTFMax)]))
+		before, _ := utf8.DecodeLastRuneInString(string(s[max(0, start-utf8.UTFMax):start]))
+		if joins(before, first) {
+			return false
+		}
+	}
+	if end > 0 && end < len(s) {
+		last, _ := utf8.DecodeLastRuneInString(string(s[max(0, end-utf8.UTFMax):end]))
+		after, _ := utf8.DecodeRuneInString(string(s[end:min(len(s), end+utf8.UTFMax)]))
+		if joins(last, after) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsASCII reports whether b holds only ASCII bytes (folding then reduces to lowercasing).
+func IsASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func foldRune(r rune, diacritics bool) string {
+	cache := &foldCache
+	if diacritics {
+		cache = &foldCacheMarks
+	}
+	if v, ok := cache.Load(r); ok {
+		return v.(string)
+	}
+	f := cases.Fold().String(string(r))
+	if diacritics {
+		var b strings.Builder
+		for _, dr := range norm.NFD.String(f) {
+			if !unicode.Is(unicode.Mn, dr) {
+				b.WriteRune(dr)
+			}
+		}
+		f = b.String()
+	}
+	cache.Store(r, f)
+	return f
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func asciiLower(s string) string {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 'A' && c <= 'Z' {
+			return strings.ToLower(s)
+		}
+	}
+	return s
+}
+
+func asciiLowerByte(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,89 @@
+package fold
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		diacritics bool
+		equal      bool
+	}{
+		{"Straße", "STRASSE", false, true},
+		{"ΣΟΦΙΑ", "σοφία", false, false}, // the accent stays without diacritic folding
+		{"ΣΟΦΙΑ", "σοφία", true, true},
+		{"résumé", "RESUME", false, false},
+		{"résumé", "RESUME", true, true},
+		{"Müller", "muller", true, true},
+		{"Invoice", "invoice", false, true},
+	}
+	for _, tt := range tests {
+		got := String(tt.a, tt.diacritics) == String(tt.b, tt.diacritics)
+		if got != tt.equal {
+			t.Errorf("String(%q) == String(%q) with diacritics=%v: got %v, want %v", tt.a, tt.b, tt.diacritics, got, tt.equal)
+		}
+	}
+}
+
+func TestStringOffsets(t *testing.T) {
+	folded, offsets := StringOffsets("Straße x", false)
+	if folded != "strasse x" {
+		t.Fatalf("folded = %q", folded)
+	}
+	if len(offsets) != len(folded)+1 || offsets[len(folded)] != len("Straße x") {
+		t.Fatalf("offsets = %v", offsets)
+	}
+	// Both bytes of "ss" map back to the "ß"
+	if offsets[4] != 4 || offsets[5] != 4 || offsets[6] != len("Straß") {
+		t.Errorf("offsets = %v", offsets)
+	}
+}
+
+func TestWholeWordAt(t *testing.T) {
+	tests := []struct {
+		text, term string
+		want       bool
+	}{
+		{"the cat sat", "cat", true},
+		{"concatenate", "cat", false},
+		{"cat_food", "cat", false},
+		{"über alles", "über", true},
+		{"darüber", "über", false},
+		{"Привет, мир", "мир", true},
+		{"примирение", "мир", false},
+		{"I write c++ daily", "c++", true},
+		{"c++11", "c++", true}, // the edge rune "+" needs no boundary
+		{"abc++", "c++", false},
+		{"会議の議事録です", "会議", true},
+		{"会議の議事録です", "議事録", true},
+		{"定例会議", "会議", true},
+		{"カタカナのテスト", "テスト", true},
+		{"회의록을 확인", "회의", true},
+		{"Go言語", "言語", true},
+		{"Go言語", "Go", true},
+	}
+	for _, tt := range tests {
+		start := strings.Index(tt.text, tt.term)
+		if start < 0 {
+			t.Fatalf("%q not in %q", tt.term, tt.text)
+		}
+		if got := WholeWordAt(tt.text, start, start+len(tt.term)); got != tt.want {
+			t.Errorf("WholeWordAt(%q, %q) = %v, want %v", tt.text, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestIsSelfDelimiting(t *testing.T) {
+	for _, r := range "会議のテスト회의" {
+		if !IsSelfDelimiting(r) {
+			t.Errorf("IsSelfDelimiting(%q) = false", r)
+		}
+	}
+	for _, r := range "aZ9_éßжα" {
+		if IsSelfDelimiting(r) {
+			t.Errorf("IsSelfDelimiting(%q) = true", r)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"find-words/search/fold"
+)
+
+// maxFrontMatterBytes bounds how much of a Markdown file is read for its front matter
+const maxFrontMatterBytes = 64 * 1024
+
+// MetaField is one field of a Markdown file's YAML front matter. Values are strings; List
+// marks YAML sequences (tags: [go, search]). Nested maps become dotted keys ("author.name").
+type MetaField struct {
+	Key    string
+	Values []string
+	List   bool
+}
+
+// FrontMatter holds the fields of a front matter block in document order
+type FrontMatter []MetaField
+
+// MetaCondition is one --meta key=value condition
+type MetaCondition struct {
+	Key   string
+	Value string
+}
+
+// MetaFilter restricts a search to Markdown files whose front matter passes every condition.
+// Keys match case-insensitively; a list field passes when one of its items equals the value
+// and any other field when it contains the value, both ignoring case. Files without front
+// matter fail. A nil filter or one without conditions is inactive.
+type MetaFilter struct {
+	Conditions []MetaCondition
+}
+
+// NewMetaFilter builds a filter from --meta values of the form key=value
+func NewMetaFilter(specs []string) (*MetaFilter, error) {
+	f := &MetaFilter{}
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid --meta %q: want key=value", spec)
+		}
+		f.Conditions = append(f.Conditions, MetaCondition{Key: key, Value: value})
+	}
+	return f, nil
+}
+
+// Active reports whether the filter restricts anything
+func (f *MetaFilter) Active() bool {
+	return f != nil && len(f.Conditions) > 0
+}
+
+// Match reports whether a file with front matter fm passes the filter; diacritics folds
+// diacritics too (--fold-diacritics)
+func (f *MetaFilter) Match(fm FrontMatter, diacritics bool) bool {
+	if !f.Active() {
+		return true
+	}
+	for _, c := range f.Conditions {
+		want := fold.String(c.Value, diacritics)
+		found := false
+		for _, field := range fm {
+			if !strings.EqualFold(field.Key, c.Key) {
+				continue
+			}
+			for _, v := range field.Values {
+				v = fold.String(v, diacritics)
+				if field.List && v == want || !field.List && strings.Contains(v, want) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// isMarkdownFile reports whether name is a Markdown document
+func isMarkdownFile(name string) bool {
+	switch formatExt(name) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// readFrontMatter parses the front matter of the Markdown file at path, read with opts.
+// ok is false for other files, unreadable files and files without front matter.
+func readFrontMatter(path string, opts TextOptions) (FrontMatter, bool) {
+	if !isMarkdownFile(path) {
+		return nil, false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(textReader(path, io.LimitReader(f, maxFrontMatterBytes), opts))
+	if err != nil {
+		return nil, false
+	}
+	return parseFrontMatter(data)
+}
+
+// parseFrontMatter parses the YAML block that opens a Markdown document between a "---"
+// line and a closing "---" (or "...") line. ok is false when there is none or it is not YAML.
+func parseFrontMatter(data []byte) (FrontMatter, bool) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	line, rest, _ := bytes.Cut(data, []byte("\n"))
+	if string(bytes.TrimRight(line, " \r")) != "---" {
+		return nil, false
+	}
+	var block []byte
+	closed := false
+	for len(rest) > 0 {
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+		if l := string(bytes.TrimRight(line, " \r")); l == "---" || l == "..." {
+			closed = true
+			break
+		}
+		block = append(append(block, line...), '\n')
+	}
+	if !closed {
+		return nil, false
+	}
+	var ms yaml.MapSlice
+	if err := yaml.Unmarshal(block, &ms); err != nil {
+		return nil, false
+	}
+	return appendMetaFields(nil, "", ms), true
+}
+
+// appendMetaFields flattens a YAML mapping into fields, prefixing nested keys
+func appendMetaFields(fields FrontMatter, prefix string, ms yaml.MapSlice) FrontMatter {
+	for _, item := range ms {
+		key := prefix + fmt.Sprint(item.Key)
+		switch v := item.Value.(type) {
+		case nil:
+		case yaml.MapSlice:
+			fields = appendMetaFields(fields, key+".", v)
+		case []interface{}:
+			field := MetaField{Key: key, List: true}
+			for _, e := range v {
+				if s, ok := metaScalar(e); ok {
+					field.Values = append(field.Values, s)
+				}
+			}
+			fields = append(fields, field)
+		default:
+			if s, ok := metaScalar(v); ok {
+				fields = append(fields, MetaField{Key: key, Values: []string{s}})
+			}
+		}
+	}
+	return fields
+}
+
+// metaScalar formats a YAML scalar; dates are written as YAML spells them
+func metaScalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case nil, yaml.MapSlice, []interface{}:
+		return "", false
+	case time.Time:
+		if v.Equal(v.Truncate(24 * time.Hour)) {
+			return v.Format("2006-01-02"), true
+		}
+		return v.Format(time.RFC3339), true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
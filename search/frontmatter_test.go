@@ -0,0 +1,86 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want FrontMatter
+		ok   bool
+	}{
+		{"fields in order", "---\ntitle: Notes\ntags: [go, search]\ndraft: false\n---\n# Body",
+			FrontMatter{{Key: "title", Values: []string{"Notes"}}, {Key: "tags", Values: []string{"go", "search"}, List: true}, {Key: "draft", Values: []string{"false"}}}, true},
+		{"nested keys", "---\nauthor:\n  name: Ann\n  team:\n    lead: Bob\n---\n",
+			FrontMatter{{Key: "author.name", Values: []string{"Ann"}}, {Key: "author.team.lead", Values: []string{"Bob"}}}, true},
+		{"block list and nulls", "---\ntags:\n  - a\n  - 2\n  - {x: y}\nempty:\n---\n",
+			FrontMatter{{Key: "tags", Values: []string{"a", "2"}, List: true}}, true},
+		{"dates", "---\ndate: 2024-03-05\nupdated: 2024-03-05T10:30:00Z\n---\n",
+			FrontMatter{{Key: "date", Values: []string{"2024-03-05"}}, {Key: "updated", Values: []string{"2024-03-05T10:30:00Z"}}}, true},
+		{"dots close, bom and crlf", "\xef\xbb\xbf---\r\ntitle: x\r\n...\r\nbody",
+			FrontMatter{{Key: "title", Values: []string{"x"}}}, true},
+		{"empty block", "---\n---\n", nil, true},
+		{"not at the start", "\n---\ntitle: x\n---\n", nil, false},
+		{"unclosed", "---\ntitle: x\n", nil, false},
+		{"not yaml", "---\n: : [\n---\n", nil, false},
+		{"no front matter", "# Title\n", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseFrontMatter([]byte(tt.data))
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, %v; want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMetaFilterMatch(t *testing.T) {
+	fm := FrontMatter{
+		{Key: "title", Values: []string{"Café Notes"}},
+		{Key: "tags", Values: []string{"go", "search-engine"}, List: true},
+		{Key: "author.name", Values: []string{"Ann Lee"}},
+		{Key: "date", Values: []string{"2024-03-05"}},
+	}
+	tests := []struct {
+		name       string
+		specs      []string
+		diacritics bool
+		want       bool
+	}{
+		{"no conditions", nil, false, true},
+		{"substring", []string{"title=notes"}, false, true},
+		{"key ignores case", []string{"TITLE=café"}, false, true},
+		{"diacritics kept", []string{"title=cafe"}, false, false},
+		{"diacritics folded", []string{"title=cafe"}, true, true},
+		{"list item", []string{"tags=GO"}, false, true},
+		{"list needs a whole item", []string{"tags=search"}, false, false},
+		{"dotted key", []string{"author.name=lee"}, false, true},
+		{"parent key", []string{"author=ann"}, false, false},
+		{"date", []string{"date=2024-03"}, false, true},
+		{"every condition", []string{"tags=go", "title=notes"}, false, true},
+		{"one fails", []string{"tags=go", "title=draft"}, false, false},
+		{"missing key", []string{"status=done"}, false, false},
+	}
+	for _, tt := range tests {
+		f, err := NewMetaFilter(tt.specs)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := f.Match(fm, tt.diacritics); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	f, _ := NewMetaFilter([]string{"tags=go"})
+	if f.Match(nil, false) {
+		t.Error("a file without front matter must fail an active filter")
+	}
+	for _, spec := range []string{"tags", "=go", "tags= "} {
+		if _, err := NewMetaFilter([]string{spec}); err == nil {
+			t.Errorf("NewMetaFilter(%q): want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package search
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"find-words/config"
+)
+
+// ignoreFiles are read in every directory the walk enters, and in the directories above a
+// root up to the top of its git repository, in increasing precedence: a rule in
+// .garpignore overrides one in .ignore, which overrides one in .gitignore
+var ignoreFiles = []string{".gitignore", ".ignore", ".garpignore"}
+
+// WalkOptions controls which directories and files the walk visits. The zero value is the
+// default: dot-directories and config.SkipDirectories are pruned, and .gitignore, .ignore
+// and .garpignore rules are honoured.
+type WalkOptions struct {
+	Hidden      bool     // enter dot-directories (--hidden)
+	NoIgnore    bool     // ignore ignore files and config.SkipDirectories (--no-ignore)
+	ExcludeDirs []string // never enter directories matching these globs (--exclude-dir)
+	IncludeDirs []string // always enter directories matching these globs (--include-dir)
+}
+
+// ignorePattern is one gitignore-style glob compiled to a regular expression
+type ignorePattern struct {
+	re       *regexp.Regexp
+	anchored bool // contains a slash: matched against the path relative to its base, else the name
+	dirOnly  bool // trailing slash: matches directories only
+}
+
+// newIgnorePattern compiles a gitignore-style glob: * and ? stay within a path segment,
+// [...] is a character class, ** spans segments, a leading or inner slash anchors the
+// pattern to its base directory and a trailing slash restricts it to directories.
+func newIgnorePattern(glob string) (ignorePattern, bool) {
+	p := ignorePattern{}
+	if strings.HasSuffix(glob, "/") {
+		p.dirOnly = true
+		glob = strings.TrimRight(glob, "/")
+	}
+	if strings.Contains(glob, "/") {
+		p.anchored = true
+		glob = strings.TrimPrefix(glob, "/")
+	}
+	if glob == "" {
+		return p, false
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				switch {
+				case i+2 == len(glob):
+					b.WriteString(".*") // a/** : everything inside
+				case glob[i+2] == '/':
+					b.WriteString("(?:.*/)?") // **/a and a/**/b : any number of segments
+					i++
+				default:
+					b.WriteString("[^/]*")
+				}
+				i++
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				c = glob[i]
+			}
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return p, false
+	}
+	p.re = re
+	return p, true
+}
+
+// match reports whether the pattern matches rel, a slash-separated path relative to the
+// pattern's base directory
+func (p ignorePattern) match(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.anchored {
+		return p.re.MatchString(rel)
+	}
+	return p.re.MatchString(rel[strings.LastIndexByte(rel, '/')+1:])
+}
+
+// ignoreRule is one line of an ignore file
+type ignoreRule struct {
+	pattern ignorePattern
+	negate  bool // "!pattern": re-include what an earlier rule excluded
+}
+
+// ignoreDir holds the rules read from the ignore files of one directory, linked to those
+// of its parent so deeper rules override shallower ones
+type ignoreDir struct {
+	parent *ignoreDir
+	dir    string
+	rules  []ignoreRule
+}
+
+// readIgnoreRules parses the ignore files of dir (missing files are skipped)
+func readIgnoreRules(dir string) []ignoreRule {
+	var rules []ignoreRule
+	for _, name := range ignoreFiles {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			line := strings.TrimRight(sc.Text(), "\r")
+			if !strings.HasSuffix(line, `\ `) {
+				line = strings.TrimRight(line, " \t")
+			}
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			rule := ignoreRule{}
+			if strings.HasPrefix(line, "!") {
+				rule.negate = true
+				line = line[1:]
+			} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+				line = line[1:]
+			}
+			if p, ok := newIgnorePattern(line); ok {
+				rule.pattern = p
+				rules = append(rules, rule)
+			}
+		}
+		f.Close()
+	}
+	return rules
+}
+
+// ignored reports whether path is excluded by the rules of d and its ancestors. The last
+// matching rule decides, deeper directories and later lines winning, as in git.
+func (d *ignoreDir) ignored(path string, isDir bool) bool {
+	abs := ""
+	for ; d != nil; d = d.parent {
+		target := path
+		if filepath.IsAbs(d.dir) && !filepath.IsAbs(path) {
+			// Directories above a relative root are kept absolute
+			if abs == "" {
+				abs, _ = filepath.Abs(path)
+			}
+			target = abs
+		}
+		rel, err := filepath.Rel(d.dir, target)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for i := len(d.rules) - 1; i >= 0; i-- {
+			if d.rules[i].pattern.match(rel, isDir) {
+				return !d.rules[i].negate
+			}
+		}
+	}
+	return false
+}
+
+// parentIgnoreDir returns the rules of the ignore files in the directories above root, up
+// to the top of the git repository holding it; nil when root is outside a repository or at
+// its top
+func parentIgnoreDir(root string) *ignoreDir {
+	dir, err := filepath.Abs(root)
+	if err != nil || isRepoTop(dir) {
+		return nil
+	}
+	var dirs []string
+	for !isRepoTop(dir) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+	var d *ignoreDir
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if rules := readIgnoreRules(dirs[i]); len(rules) > 0 {
+			d = &ignoreDir{parent: d, dir: dirs[i], rules: rules}
+		}
+	}
+	return d
+}
+
+// isRepoTop reports whether dir is the top of a git repository (holds .git)
+func isRepoTop(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// walkFilter applies WalkOptions and ignore files during one root's walk. Directories are
+// visited before their contents, so each one's rules are loaded as the walk enters it.
+type walkFilter struct {
+	opts    WalkOptions
+	root    string
+	exclude []ignorePattern
+	include []ignorePattern
+	dirs    map[string]*ignoreDir
+}
+
+func newWalkFilter(root string, opts WalkOptions) *walkFilter {
+	w := &walkFilter{opts: opts, root: root, dirs: make(map[string]*ignoreDir)}
+	for _, g := range opts.ExcludeDirs {
+		if p, ok := newIgnorePattern(strings.TrimSuffix(g, "/")); ok {
+			w.exclude = append(w.exclude, p)
+		}
+	}
+	for _, g := range opts.IncludeDirs {
+		if p, ok := newIgnorePattern(strings.TrimSuffix(g, "/")); ok {
+			w.include = append(w.include, p)
+		}
+	}
+	return w
+}
+
+// enterDir reports whether the walk should descend into dir, and loads its ignore rules
+// when it does. The root is always entered. --exclude-dir wins over --include-dir, which
+// wins over hidden directories, config.SkipDirectories and ignore rules.
+func (w *walkFilter) enterDir(dir string) bool {
+	parent := w.dirs[filepath.Dir(filepath.Clean(dir))]
+	if dir == w.root && !w.opts.NoIgnore {
+		parent = parentIgnoreDir(dir)
+	}
+	if dir != w.root {
+		rel := w.rel(dir)
+		if matchesAny(w.exclude, rel) {
+			return false
+		}
+		if !matchesAny(w.include, rel) {
+			name := filepath.Base(dir)
+			if !w.opts.Hidden && config.IsHiddenFile(name) {
+				return false
+			}
+			if !w.opts.NoIgnore && (config.ShouldSkipDirectory(name) || parent.ignored(dir, true)) {
+				return false
+			}
+		}
+	}
+	if !w.opts.NoIgnore {
+		if rules := readIgnoreRules(dir); len(rules) > 0 {
+			parent = &ignoreDir{parent: parent, dir: dir, rules: rules}
+		}
+	}
+	w.dirs[filepath.Clean(dir)] = parent
+	return true
+}
+
+// keepFile reports whether a file in an entered directory passes the ignore rules
+func (w *walkFilter) keepFile(path string) bool {
+	return w.opts.NoIgnore || !w.dirs[filepath.Dir(path)].ignored(path, false)
+}
+
+// rel returns path relative to the walk root, slash-separated
+func (w *walkFilter) rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchesAny reports whether a --exclude-dir/--include-dir glob matches the directory at
+// rel (relative to the root): globs with a slash match the path, others the name
+func matchesAny(patterns []ignorePattern, rel string) bool {
+	for _, p := range patterns {
+		if p.match(rel, true) {
+			return true
+		}
+	}
+	return false
+}
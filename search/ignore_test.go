@@ -0,0 +1,168 @@
+package search
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnorePattern(t *testing.T) {
+	tests := []struct {
+		glob, rel string
+		isDir     bool
+		want      bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true}, // no slash: matches the name at any depth
+		{"*.log", "debug.log.txt", false, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false}, // trailing slash: directories only
+		{"/todo.txt", "todo.txt", false, true},
+		{"/todo.txt", "docs/todo.txt", false, false}, // leading slash: anchored
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/sub/a.md", false, false}, // * stays within a segment
+		{"docs/**/*.md", "docs/a.md", false, true},
+		{"docs/**/*.md", "docs/x/y/a.md", false, true},
+		{"**/cache", "a/b/cache", true, true},
+		{"out/**", "out/x/y", false, true},
+		{"file?.txt", "file1.txt", false, true},
+		{"file?.txt", "file10.txt", false, false},
+		{"[abc].txt", "b.txt", false, true},
+		{"[!abc].txt", "b.txt", false, false},
+		{"[!abc].txt", "d.txt", false, true},
+		{`\*.txt`, "*.txt", false, true},
+		{`\*.txt`, "a.txt", false, false},
+		{"a+b(1).txt", "a+b(1).txt", false, true},
+	}
+	for _, tt := range tests {
+		p, ok := newIgnorePattern(tt.glob)
+		if !ok {
+			t.Errorf("newIgnorePattern(%q) failed", tt.glob)
+			continue
+		}
+		if got := p.match(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("%q.match(%q, dir=%v) = %v, want %v", tt.glob, tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+	for _, glob := range []string{"", "/", "//"} {
+		if _, ok := newIgnorePattern(glob); ok {
+			t.Errorf("newIgnorePattern(%q) succeeded, want no pattern", glob)
+		}
+	}
+}
+
+func TestWalkRootsIgnore(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":             "*.log\nbuild/\n# comment\n/root-only.txt\n!keep.log\n",
+		".garpignore":            "secret.txt\n",
+		"a.txt":                  "",
+		"app.log":                "",
+		"keep.log":               "",
+		"root-only.txt":          "",
+		"secret.txt":             "",
+		"build/out.txt":          "",
+		"docs/root-only.txt":     "",
+		"docs/.ignore":           "!app.log\ndraft*\n",
+		"docs/app.log":           "",
+		"docs/draft1.txt":        "",
+		"docs/b.txt":             "",
+		"node_modules/m/x.txt":   "",
+		".hidden/h.txt":          "",
+		"vendorized/vendor/v.go": "",
+	}
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	walk := func(opts WalkOptions) []string {
+		var got []string
+		err := walkRoots(context.Background(), []string{dir}, opts, func(path string, d fs.DirEntry) error {
+			rel, _ := filepath.Rel(dir, path)
+			if !slices.Contains(ignoreFiles, filepath.Base(rel)) {
+				got = append(got, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(got)
+		return got
+	}
+
+	tests := []struct {
+		name string
+		opts WalkOptions
+		want []string
+	}{
+		{"defaults", WalkOptions{}, []string{"a.txt", "docs/app.log", "docs/b.txt", "docs/root-only.txt", "keep.log"}},
+		{"hidden", WalkOptions{Hidden: true}, []string{".hidden/h.txt", "a.txt", "docs/app.log", "docs/b.txt", "docs/root-only.txt", "keep.log"}},
+		{"exclude dir", WalkOptions{ExcludeDirs: []string{"docs"}}, []string{"a.txt", "keep.log"}},
+		{"include dir", WalkOptions{IncludeDirs: []string{"build", "node_modules"}}, []string{"a.txt", "build/out.txt", "docs/app.log", "docs/b.txt", "docs/root-only.txt", "keep.log", "node_modules/m/x.txt"}},
+		{"no ignore", WalkOptions{NoIgnore: true}, []string{
+			"a.txt", "app.log", "build/out.txt", "docs/app.log", "docs/b.txt", "docs/draft1.txt", "docs/root-only.txt",
+			"keep.log", "node_modules/m/x.txt", "root-only.txt", "secret.txt", "vendorized/vendor/v.go",
+		}},
+	}
+	for _, tt := range tests {
+		if got := walk(tt.opts); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: walked %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// A root inside a git repository honours the ignore files above it, up to the
+	// repository top; those above the repository do not apply
+	for name, text := range map[string]string{
+		"outer/.gitignore":            "*.txt\n",
+		"outer/repo/.git/HEAD":        "",
+		"outer/repo/.gitignore":       "*.tmp\n/sub/skip.md\n",
+		"outer/repo/sub/.ignore":      "!keep.tmp\n",
+		"outer/repo/sub/a.txt":        "",
+		"outer/repo/sub/b.tmp":        "",
+		"outer/repo/sub/keep.tmp":     "",
+		"outer/repo/sub/skip.md":      "",
+		"outer/repo/sub/deeper/c.tmp": "",
+		"outer/repo/sub/deeper/d.md":  "",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sub := filepath.Join(dir, "outer", "repo", "sub")
+	t.Chdir(filepath.Join(sub, "deeper"))
+	for _, opts := range []WalkOptions{{}, {NoIgnore: true}} {
+		var got []string
+		// A relative root, as when garp runs without --root
+		err := walkRoots(context.Background(), []string{".."}, opts, func(path string, d fs.DirEntry) error {
+			rel, _ := filepath.Rel("..", path)
+			if !slices.Contains(ignoreFiles, filepath.Base(rel)) {
+				got = append(got, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(got)
+		want := []string{"a.txt", "deeper/d.md", "keep.tmp"}
+		if opts.NoIgnore {
+			want = []string{"a.txt", "b.tmp", "deeper/c.tmp", "deeper/d.md", "keep.tmp", "skip.md"}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("root in a repository (no ignore %v): walked %q, want %q", opts.NoIgnore, got, want)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"find-words/search/fold"
+)
+
+// indexVersion is bumped whenever the on-disk layout, tokenization or extracted text changes;
+// indexes written by another version are ignored and must be rebuilt.
+const indexVersion = 11
+
+// IndexEntry is the indexed state of one file: the fingerprint it was built from
+// and the byte offsets of every case-folded term in its cleaned text.
+type IndexEntry struct {
+	Size     int64
+	ModTime  int64 // UnixNano
+	Postings map[string][]int32
+}
+
+// Index is a persistent inverted index over the files under one search root.
+// It is stored as a gob file under the user's cache directory (see IndexPath).
+type Index struct {
+	Version int
+	Root    string // absolute
+	Built   time.Time
+	Files   map[string]*IndexEntry // keyed by absolute path
+}
+
+// IndexStats summarizes how an index compares to the files currently on disk
+type IndexStats struct {
+	Fresh   int // indexed and unchanged
+	Changed int // indexed but size or mtime differ
+	Added   int // on disk but not indexed
+	Removed int // indexed but gone, or no longer selected by the file types and walk rules
+	Failed  int // could not be read or extracted while building
+}
+
+// IndexOptions controls which files BuildIndex visits and how it extracts them
+type IndexOptions struct {
+	FileTypes   []string // ripgrep-style globs as used by the search ("-g", "*.txt", ...)
+	Registry    *ExtractorRegistry
+	Workers     int
+	FileTimeout time.Duration
+	Walk        WalkOptions // directories and ignore rules, as for the search
+
+	// Optional progress callback: processed files so far and the current path
+	OnProgress func(processed int, path string)
+}
+
+// IndexPath returns the cache file that holds the index for root
+func IndexPath(root string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(GetAbsolutePath(root)))
+	return filepath.Join(cacheDir, "garp", "index", hex.EncodeToString(sum[:8])+".gob"), nil
+}
+
+// LoadIndex reads the index for root. The error wraps fs.ErrNotExist when root was never indexed.
+func LoadIndex(root string) (*Index, error) {
+	path, err := IndexPath(root)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ix Index
+	if err := gob.NewDecoder(f).Decode(&ix); err != nil {
+		return nil, fmt.Errorf("corrupt index %s: %w", path, err)
+	}
+	if ix.Version != indexVersion {
+		return nil, fmt.Errorf("index %s has version %d, want %d: %w", path, ix.Version, indexVersion, fs.ErrNotExist)
+	}
+	return &ix, nil
+}
+
+// Save writes the index to its cache file, replacing any previous one atomically
+func (ix *Index) Save() error {
+	path, err := IndexPath(ix.Root)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := gob.NewEncoder(tmp).Encode(ix); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Lookup returns the entry for path if it was indexed with the same size and mtime as info
+func (ix *Index) Lookup(path string, info fs.FileInfo) (*IndexEntry, bool) {
+	e, ok := ix.Files[GetAbsolutePath(path)]
+	if !ok || e.Size != info.Size() || e.ModTime != info.ModTime().UnixNano() {
+		return nil, false
+	}
+	return e, true
+}
+
+// BuildIndex indexes every searchable file under root. Entries from prev (may be nil)
+// whose fingerprint still matches are reused as-is, so passing the previous index
+// turns a full build into an incremental update.
+func BuildIndex(ctx context.Context, root string, prev *Index, opts IndexOptions) (*Index, IndexStats, error) {
+	ix := &Index{
+		Version: indexVersion,
+		Root:    GetAbsolutePath(root),
+		Files:   make(map[string]*IndexEntry),
+	}
+	var stats IndexStats
+	var mu sync.Mutex
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	timeout := opts.FileTimeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	registry := opts.Registry
+	if registry == nil {
+		registry = NewExtractorRegistry()
+	}
+	cm := NewConcurrencyManager(workers)
+
+	type job struct {
+		path string
+		name string // see typeFilter.name
+		info fs.FileInfo
+	}
+	jobs := make(chan job, workers*4)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				text, err := indexFileText(ctx, j.path, j.name, registry, cm, timeout)
+				mu.Lock()
+				if err != nil {
+					stats.Failed++
+				} else {
+					ix.Files[GetAbsolutePath(j.path)] = &IndexEntry{
+						Size:     j.info.Size(),
+						ModTime:  j.info.ModTime().UnixNano(),
+						Postings: tokenizePostings(text),
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	types := newTypeFilter(opts.FileTypes)
+	processed := 0
+	seen := make(map[string]bool)
+	err := walkRoots(ctx, []string{root}, opts.Walk, func(path string, d fs.DirEntry) error {
+		// Archives are always searched entry by entry, never from the index
+		if !types.selects(path) || isArchive(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		processed++
+		if opts.OnProgress != nil {
+			opts.OnProgress(processed, path)
+		}
+
+		abs := GetAbsolutePath(path)
+		seen[abs] = true
+		if prev != nil {
+			if e, ok := prev.Lookup(abs, info); ok {
+				mu.Lock()
+				ix.Files[abs] = e
+				stats.Fresh++
+				mu.Unlock()
+				return nil
+			}
+			if _, ok := prev.Files[abs]; ok {
+				stats.Changed++
+			} else {
+				stats.Added++
+			}
+		} else {
+			stats.Added++
+		}
+		jobs <- job{path: path, name: types.name(path), info: info}
+		return nil
+	})
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return nil, stats, err
+	}
+
+	// Entries the walk no longer reaches are dropped: deleted files as well as files that
+	// the file types or ignore rules now leave out
+	if prev != nil {
+		for abs := range prev.Files {
+			if !seen[abs] {
+				stats.Removed++
+			}
+		}
+	}
+	ix.Built = time.Now()
+	return ix, stats, nil
+}
+
+// Status compares the index with the files currently under its root, selected by fileTypes
+// and walk as for BuildIndex, without extracting anything
+func (ix *Index) Status(ctx context.Context, fileTypes []string, walk WalkOptions) (IndexStats, error) {
+	var stats IndexStats
+	seen := make(map[string]bool, len(ix.Files))
+	types := newTypeFilter(fileTypes)
+	err := walkRoots(ctx, []string{ix.Root}, walk, func(path string, d fs.DirEntry) error {
+		if !types.selects(path) || isArchive(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		abs := GetAbsolutePath(path)
+		seen[abs] = true
+		if _, ok := ix.Lookup(abs, info); ok {
+			stats.Fresh++
+		} else if _, ok := ix.Files[abs]; ok {
+			stats.Changed++
+		} else {
+			stats.Added++
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	for abs := range ix.Files {
+		if !seen[abs] {
+			stats.Removed++
+		}
+	}
+	return stats, nil
+}
+
+// MatchesAllWords reports whether the indexed text contains every word within distance,
+// with the same whole-word, plural-aware semantics as CheckTextContainsAllWords.
+// decided is false when a word cannot be answered from the postings (e.g. it spans several tokens,
+// or diacritic folding is on: terms are indexed with their accents).
+func (e *IndexEntry) MatchesAllWords(words []string, distance int) (found bool, decided bool) {
+	return e.MatchesGroups(singletonGroups(words), distance)
+}
+
+// MatchesGroups is MatchesAllWords for query groups (see CheckTextMatchesGroups)
+func (e *IndexEntry) MatchesGroups(groups [][]string, distance int) (found bool, decided bool) {
+	var matches []termMatch
+	for i, group := range groups {
+		groupMatched := false
+		for _, term := range group {
+			positions, ok := e.wordPositions(term, wordFormSuffixes())
+			if !ok {
+				return false, false
+			}
+			for _, pos := range positions {
+				matches = append(matches, termMatch{pos: int(pos), wordIndex: i})
+			}
+			groupMatched = groupMatched || len(positions) > 0
+		}
+		if !groupMatched {
+			return false, true
+		}
+	}
+	if len(groups) <= 1 {
+		return true, true
+	}
+	return matchesWithinDistance(matches, len(groups), distance), true
+}
+
+// ContainsAnyWord reports whether the indexed text contains any of the exclude words
+// (whole word, plural-aware, like CheckTextContainsExcludeWords).
+func (e *IndexEntry) ContainsAnyWord(words []string) (found bool, decided bool) {
+	for _, word := range words {
+		positions, ok := e.wordPositions(word, []string{"es", "s"})
+		if !ok {
+			return false, false
+		}
+		if len(positions) > 0 {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// wordPositions collects the postings of word and its suffixed forms.
+// Returns false when word is not a single index token.
+func (e *IndexEntry) wordPositions(word string, suffixes []string) ([]int32, bool) {
+	base := fold.String(strings.TrimSpace(word), false)
+	if base == "" || !isIndexToken(base) {
+		return nil, false
+	}
+	positions := append([]int32(nil), e.Postings[base]...)
+	for _, suf := range suffixes {
+		positions = append(positions, e.Postings[base+suf]...)
+	}
+	return positions, true
+}
+
+// indexFileText reads path and returns its cleaned text, extracting binary formats
+// under the concurrency manager and timeout exactly as the search does. name tells the
+// format (see typeFilter.name).
+func indexFileText(ctx context.Context, path, name string, registry *ExtractorRegistry, cm *ConcurrencyManager, timeout time.Duration) (string, error) {
+	// Indexes hold text read without options: indexDecide passes over them when the
+	// search's options would read it differently
+	content, _, err := readFileContent(path, name, TextOptions{})
+	if err != nil {
+		return "", err
+	}
+	if !IsBinaryFormat(name) {
+		return CleanContent(content), nil
+	}
+	ext := formatExt(name)
+	extractor, ok := registry.extractorFor(ext, content)
+	if !ok {
+		return "", fmt.Errorf("no extractor for %s", ext)
+	}
+	var text string
+	var extErr error
+	cm.Acquire()
+	err = cm.ExecuteWithTimeout(ctx, func(ctx context.Context) {
+		text, extErr = extractTextContext(ctx, extractor, []byte(content))
+	}, timeout)
+	cm.Release()
+	if err == nil {
+		err = extErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return CleanContent(text), nil
+}
+
+// tokenizePostings splits case-folded text into runs of word characters (letters, digits,
+// marks and '_' in any script, as the matcher's word boundaries see them) and records the
+// offset of each run in the original text. Han, kana and Hangul characters are tokens of
+// their own (see fold.IsSelfDelimiting).
+func tokenizePostings(text string) map[string][]int32 {
+	folded, offsets := fold.StringOffsets(text, false)
+	postings := make(map[string][]int32)
+	start := -1
+	for i, r := range folded + " " {
+		if i < len(folded) && fold.IsWordRune(r) && !fold.IsSelfDelimiting(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tok := folded[start:i]
+			postings[tok] = append(postings[tok], int32(offsets[start]))
+			start = -1
+		}
+		if i < len(folded) && fold.IsSelfDelimiting(r) {
+			tok := folded[i : i+utf8.RuneLen(r)]
+			postings[tok] = append(postings[tok], int32(offsets[i]))
+		}
+	}
+	return postings
+}
+
+// isIndexToken reports whether s is one token of tokenizePostings; a word of several Han,
+// kana or Hangul characters spans several tokens and is answered from the text instead
+func isIndexToken(s string) bool {
+	for _, r := range s {
+		if !fold.IsWordRune(r) || fold.IsSelfDelimiting(r) && utf8.RuneCountInString(s) > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// typeFilter selects files by the ripgrep-style globs of a file type list ("-g", "*.txt",
+// "-g", "*.txt.gz", ...): "*.ext" globs by extension, other globs against the lowercased
+// base name. A filter built from no globs allows every file.
+type typeFilter struct {
+	exts    map[string]bool // ".txt"
+	globs   []string
+	known   map[string]bool // the extensions of every type group (see selects)
+	sniffs  bool            // some format content sniffing tells is selected
+	sniffed *sync.Map       // path → format of the files selects placed by their content
+}
+
+func newTypeFilter(fileTypes []string) typeFilter {
+	f := typeFilter{exts: make(map[string]bool), known: knownExtensions(), sniffed: new(sync.Map)}
+	for i := 0; i < len(fileTypes); i++ {
+		if fileTypes[i] != "-g" || i+1 >= len(fileTypes) {
+			continue
+		}
+		i++
+		glob := strings.ToLower(fileTypes[i])
+		if ext, ok := globExtension(glob); ok {
+			f.exts[ext] = true
+		} else {
+			f.globs = append(f.globs, glob)
+		}
+	}
+	f.sniffs = slices.ContainsFunc(sniffedFormats, func(format string) bool { return f.allows("file" + format) })
+	return f
+}
+
+// globExtension returns the extension a "*.ext" glob selects (".ext"); ok is false for
+// other globs ("*.txt.gz", "report-*.pdf")
+func globExtension(glob string) (string, bool) {
+	ext, ok := strings.CutPrefix(glob, "*")
+	if !ok || !strings.HasPrefix(ext, ".") || strings.ContainsAny(ext[1:], "*?[\\.") {
+		return "", false
+	}
+	return ext, true
+}
+
+// allows reports whether the file at path is selected by its name
+func (f typeFilter) allows(path string) bool {
+	if len(f.exts) == 0 && len(f.globs) == 0 {
+		return true
+	}
+	name := strings.ToLower(filepath.Base(path))
+	if f.exts[filepath.Ext(name)] {
+		return true
+	}
+	for _, glob := range f.globs {
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildIndexRemoved(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{"a.txt": "alpha", "b.md": "beta", "c.txt": "gamma"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	prev, stats, err := BuildIndex(ctx, dir, nil, IndexOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Added != 3 || len(prev.Files) != 3 {
+		t.Fatalf("first build: stats = %+v, %d files", stats, len(prev.Files))
+	}
+
+	// c.txt is deleted and b.md is no longer selected: both leave the index
+	if err := os.Remove(filepath.Join(dir, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	ix, stats, err := BuildIndex(ctx, dir, prev, IndexOptions{FileTypes: []string{"-g", "*.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (IndexStats{Fresh: 1, Removed: 2}); stats != want {
+		t.Errorf("rebuild: stats = %+v, want %+v", stats, want)
+	}
+	if len(ix.Files) != 1 {
+		t.Errorf("rebuild: %d files, want 1", len(ix.Files))
+	}
+}
+
+func TestTypeFilterAllows(t *testing.T) {
+	f := newTypeFilter([]string{"-g", "*.TXT", "-g", "*.tar.gz", "-g", "report-*.pdf", "-g"})
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"notes/a.txt", true},
+		{"A.Txt", true}, // names ignore case
+		{"a.txt.bak", false},
+		{"logs.tar.gz", true},
+		{"single.gz", false},
+		{"report-2024.PDF", true},
+		{"invoice.pdf", false},
+		{"reports/x.pdf", false}, // globs match the base name only
+		{"README", false},
+	}
+	for _, tt := range tests {
+		if got := f.allows(tt.path); got != tt.want {
+			t.Errorf("allows(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if ext, ok := globExtension("*.txt.gz"); ok {
+		t.Errorf("globExtension(*.txt.gz) = %q, want none", ext)
+	}
+	if all := newTypeFilter(nil); !all.allows("anything.bin") {
+		t.Error("a filter without globs must allow every file")
+	}
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-mbox"
+	"github.com/jhillyerd/enmime"
+)
+
+// mailboxSep separates an mbox path from the message number in a virtual result path
+const mailboxSep = "#msg-"
+
+// mailMessage is one message of an mbox: its 1-based position, raw size, headers and text.
+// Parts holds the body and attachment texts that Text joins.
+type mailMessage struct {
+	Index int
+	Size  int64
+	Meta  EmailMeta
+	Text  string
+	Parts []TextPart
+}
+
+// isMailbox reports whether path is an mbox searched message by message
+func isMailbox(path string) bool {
+	return formatExt(path) == ".mbox"
+}
+
+// mailboxPath returns the virtual path of message n inside an mbox ("archive.mbox#msg-12")
+func mailboxPath(file string, n int) string {
+	return file + mailboxSep + strconv.Itoa(n)
+}
+
+// SplitMailboxPath splits a virtual mbox message path into the mbox file and the 1-based
+// message number. ok is false for ordinary paths.
+func SplitMailboxPath(path string) (file string, n int, ok bool) {
+	i := strings.LastIndex(path, mailboxSep)
+	if i < 0 || !isMailbox(path[:i]) {
+		return path, 0, false
+	}
+	n, err := strconv.Atoi(path[i+len(mailboxSep):])
+	if err != nil || n < 1 {
+		return path, 0, false
+	}
+	return path[:i], n, true
+}
+
+// readMailbox streams the messages of the mbox at path, calling fn with each message's
+// 1-based number and raw bytes until fn returns false. The whole file is read (no size cap),
+// one message at a time. Returns the number of messages seen.
+func readMailbox(ctx context.Context, path string, fn func(n int, raw []byte) bool) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	reader := mbox.NewReader(f)
+	n := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		msg, err := reader.NextMessage()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		raw, err := io.ReadAll(msg)
+		if err != nil {
+			return n, err
+		}
+		n++
+		if !fn(n, raw) {
+			return n, nil
+		}
+	}
+}
+
+// parseMailMessage extracts the text (body and attachments) and headers of one raw
+// mbox message; reg supplies the attachment extractors
+func parseMailMessage(ctx context.Context, reg *ExtractorRegistry, n int, raw []byte) (mailMessage, error) {
+	env, err := enmime.ReadEnvelope(bytes.NewReader(raw))
+	if err != nil {
+		return mailMessage{}, fmt.Errorf("failed to parse message %d: %w", n, err)
+	}
+	parts, err := (&EMLExtractor{Registry: reg}).envelopeParts(ctx, env)
+	if err != nil {
+		return mailMessage{}, err
+	}
+	return mailMessage{
+		Index: n,
+		Size:  int64(len(raw)),
+		Meta:  envelopeMeta(env),
+		Text:  joinParts(parts),
+		Parts: parts,
+	}, nil
+}
+
+// loadMailMessage re-reads message n of the mbox at file
+func loadMailMessage(ctx context.Context, reg *ExtractorRegistry, file string, n int) (mailMessage, error) {
+	var raw []byte
+	if _, err := readMailbox(ctx, file, func(i int, b []byte) bool {
+		if i == n {
+			raw = b
+			return false
+		}
+		return true
+	}); err != nil {
+		return mailMessage{}, err
+	}
+	if raw == nil {
+		return mailMessage{}, fmt.Errorf("%s has no message %d", file, n)
+	}
+	return parseMailMessage(ctx, reg, n, raw)
+}
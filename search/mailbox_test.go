@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testMailbox = "From ann@example.com Mon Jan  1 00:00:00 2024\n" +
+	"From: ann@example.com\nSubject: first\n\nThe invoice is attached.\n" +
+	">From the desk of Ann\n\n" +
+	"From bob@example.com Tue Jan  2 00:00:00 2024\n" +
+	"From: bob@example.com\nSubject: second\n\nNothing to report.\n\n" +
+	"From carol@example.com Wed Jan  3 00:00:00 2024\n" +
+	"From: carol@example.com\nSubject: third\n\nThe invoice was paid.\n"
+
+func writeMailbox(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.mbox")
+	if err := os.WriteFile(path, []byte(testMailbox), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadMailbox(t *testing.T) {
+	path := writeMailbox(t)
+	var msgs []string
+	n, err := readMailbox(context.Background(), path, func(n int, raw []byte) bool {
+		if n != len(msgs)+1 {
+			t.Errorf("message %d numbered %d", len(msgs)+1, n)
+		}
+		msgs = append(msgs, strings.ReplaceAll(string(raw), "\r\n", "\n"))
+		return true
+	})
+	if err != nil || n != 3 || len(msgs) != 3 {
+		t.Fatalf("readMailbox = %d, %v with %d messages; want 3", n, err, len(msgs))
+	}
+	for i, subject := range []string{"first", "second", "third"} {
+		if !strings.Contains(msgs[i], "Subject: "+subject+"\n") || strings.HasPrefix(msgs[i], "From ") {
+			t.Errorf("message %d = %q, want the %s message without its From line", i+1, msgs[i], subject)
+		}
+	}
+	// An escaped From line in a body is unescaped
+	if !strings.Contains(msgs[0], "\nFrom the desk of Ann") || strings.Contains(msgs[0], ">From") {
+		t.Errorf("message 1 = %q, want the >From line unescaped", msgs[0])
+	}
+
+	// Returning false stops the read
+	n, err = readMailbox(context.Background(), path, func(n int, raw []byte) bool { return n < 2 })
+	if err != nil || n != 2 {
+		t.Errorf("stopped read = %d, %v; want 2", n, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := readMailbox(ctx, path, func(int, []byte) bool { return true }); err == nil {
+		t.Error("readMailbox with a cancelled context: want an error")
+	}
+}
+
+func TestExecuteMailboxMessages(t *testing.T) {
+	path := writeMailbox(t)
+	se := NewSearchEngine([]string{"invoice"}, nil, nil, false, 2, 5000)
+	se.Roots = []string{filepath.Dir(path)}
+	se.Silent = true
+	results, err := se.Execute(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.FullPath())
+		file, n, ok := SplitMailboxPath(r.FullPath())
+		if !ok || file != path || n != r.Message {
+			t.Errorf("SplitMailboxPath(%s) = %s, %d, %v; want message %d of %s", r.FullPath(), file, n, ok, r.Message, path)
+		}
+	}
+	slices.Sort(got)
+	if want := []string{path + "#msg-1", path + "#msg-3"}; !slices.Equal(got, want) {
+		t.Errorf("results = %q, want %q", got, want)
+	}
+
+	for _, p := range []string{path, path + "#msg-0", path + "#msg-x", filepath.Join("dir", "a.txt#msg-1")} {
+		if _, _, ok := SplitMailboxPath(p); ok {
+			t.Errorf("SplitMailboxPath(%s): want not a message path", p)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"find-words/search/fold"
+)
+
+// Matcher finds one search term in text with Unicode-aware semantics: the term and the
+// text are compared under full case folding (and diacritic folding with --fold-diacritics), and a
+// match must not be embedded in a larger word of letters, digits or marks in any script.
+// It offers the subset of the regexp API the search uses; offsets refer to the original text.
+//
+// Pure-ASCII input is matched as-is with a case-insensitive regex; only text with non-ASCII
+// bytes pays for folding. /Regular expression/ terms are matched case-insensitively against
+// the original text, without folding or word boundaries.
+type Matcher struct {
+	re         *regexp.Regexp
+	boundary   bool // whole-word check (off for regex terms)
+	fold       bool // fold non-ASCII input before matching (off for regex terms)
+	diacritics bool // fold diacritics too ("resume" finds "résumé")
+}
+
+// TermMatcher returns the plural-aware matcher for a search term (a phrase as a whole),
+// as used for excerpts and highlighting; diacritics is --fold-diacritics.
+func TermMatcher(term string, diacritics bool) *Matcher {
+	return newMatcher(term, pluralSuffixes, diacritics)
+}
+
+// newMatcher builds the matcher for term, which may also carry one of suffixes, folding
+// diacritics when diacritics is set. A phrase matches its words in order across any run
+// of whitespace; the suffixes apply to its last word. An empty term never matches.
+func newMatcher(term string, suffixes []string, diacritics bool) *Matcher {
+	if isRegexTerm(term) {
+		return &Matcher{re: regexp.MustCompile(`(?i)(?:` + term[1:len(term)-1] + `)`)}
+	}
+	words := strings.Fields(fold.String(term, diacritics))
+	if len(words) == 0 {
+		return &Matcher{re: regexp.MustCompile(`a\A`)}
+	}
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	pattern := `(?i)` + strings.Join(words, `\s+`)
+	if len(suffixes) > 0 {
+		pattern += `(?:` + strings.Join(suffixes, "|") + `)?`
+	}
+	return &Matcher{re: regexp.MustCompile(pattern), boundary: true, fold: true, diacritics: diacritics}
+}
+
+// MatchString reports whether s contains the term
+func (m *Matcher) MatchString(s string) bool {
+	return m.FindStringIndex(s) != nil
+}
+
+// Match reports whether b contains the term
+func (m *Matcher) Match(b []byte) bool {
+	if m.fold && !fold.IsASCII(b) {
+		return m.MatchString(string(b))
+	}
+	for from := 0; from <= len(b); {
+		loc := m.re.FindIndex(b[from:])
+		if loc == nil {
+			return false
+		}
+		start, end := from+loc[0], from+loc[1]
+		if !m.boundary || fold.WholeWordAt(b, start, end) {
+			return true
+		}
+		from = nextRune(b, start)
+	}
+	return false
+}
+
+// FindStringIndex returns the location of the first match in s, or nil
+func (m *Matcher) FindStringIndex(s string) []int {
+	if locs := m.FindAllStringIndex(s, 1); len(locs) > 0 {
+		return locs[0]
+	}
+	return nil
+}
+
+// FindAllStringIndex returns the locations of up to n matches in s (all when n < 0)
+func (m *Matcher) FindAllStringIndex(s string, n int) [][]int {
+	text, offsets := s, []int(nil)
+	if m.fold && !isASCIIString(s) {
+		text, offsets = fold.StringOffsets(s, m.diacritics)
+	}
+	var locs [][]int
+	for from := 0; from <= len(text) && (n < 0 || len(locs) < n); {
+		loc := m.re.FindStringIndex(text[from:])
+		if loc == nil {
+			break
+		}
+		start, end := from+loc[0], from+loc[1]
+		if m.boundary && !fold.WholeWordAt(text, start, end) {
+			from = nextRune(text, start)
+			continue
+		}
+		if offsets != nil {
+			// A match ending inside a multi-byte expansion (e.g. the first "s" of ß -> "ss") ends after that rune
+			oe := end
+			for oe > start && oe < len(text) && offsets[oe] == offsets[oe-1] {
+				oe++
+			}
+			locs = append(locs, []int{offsets[start], offsets[oe]})
+		} else {
+			locs = append(locs, []int{start, end})
+		}
+		if end > start {
+			from = end
+		} else {
+			from = nextRune(text, end)
+		}
+	}
+	return locs
+}
+
+// ReplaceAllStringFunc replaces every match in s with the return value of repl
+func (m *Matcher) ReplaceAllStringFunc(s string, repl func(string) string) string {
+	locs := m.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		if loc[0] < last {
+			continue
+		}
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(repl(s[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// nextRune returns the offset just past the rune starting at i (at least i+1)
+func nextRune[T string | []byte](s T, i int) int {
+	if i >= len(s) {
+		return i + 1
+	}
+	_, size := utf8.DecodeRuneInString(string(s[i:min(len(s), i+utf8.UTFMax)]))
+	return i + size
+}
+
+func isASCIIString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
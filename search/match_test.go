@@ -0,0 +1,89 @@
+package search
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTermMatcher(t *testing.T) {
+	tests := []struct {
+		term, text string
+		diacritics bool
+		want       bool
+	}{
+		{"STRASSE", "Die Straße ist lang", false, true},
+		{"straße", "DIE STRASSE", false, true},
+		{"c++", "I write C++ daily", false, true},
+		{"über", "darüber hinaus", false, false},
+		{"会議", "会議の議事録です", false, true},
+		{"議事録", "会議の議事録です", false, true},
+		{"テスト", "カタカナのテスト", false, true},
+		{"invoice", "invoices are due", false, true}, // plural-aware
+		{"cat", "concatenate", false, false},
+		{"resume", "my résumé", false, false},
+		{"resume", "my résumé", true, true},
+		{"Müller", "Herr MULLER", true, true},
+	}
+	for _, tt := range tests {
+		if got := TermMatcher(tt.term, tt.diacritics).MatchString(tt.text); got != tt.want {
+			t.Errorf("TermMatcher(%q, %v).MatchString(%q) = %v, want %v", tt.term, tt.diacritics, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizePostingsCJK(t *testing.T) {
+	postings := tokenizePostings("会議の議事録 Meeting notes")
+	for tok, want := range map[string][]int32{"会": {0}, "議": {3, 9}, "の": {6}, "meeting": {19}, "notes": {27}} {
+		if got := postings[tok]; !slices.Equal(got, want) {
+			t.Errorf("postings[%q] = %v, want %v", tok, got, want)
+		}
+	}
+	// A word of several CJK characters spans tokens: the index cannot answer it
+	if isIndexToken("会議") || !isIndexToken("会") || !isIndexToken("meeting") {
+		t.Error("isIndexToken: CJK words of several characters must not be single tokens")
+	}
+}
+
+func TestRegexTerms(t *testing.T) {
+	tests := []struct {
+		term, text string
+		want       bool
+	}{
+		{"/inv-\\d{4}/", "see INV-2024 attached", true},
+		{"/inv-\\d{4}/", "see INV-24 attached", false},
+		{"/cat/", "concatenate", true}, // no word boundaries
+		{"/colou?r/", "Colour and color", true},
+		{"/\\D+/", "123", false}, // escapes keep their case
+		{"/^total/", "Total: 5", true},
+		{"/straße/", "STRASSE", false}, // no case folding beyond (?i)
+		{"/a b/", "a  b", false},
+	}
+	for _, tt := range tests {
+		if got := TermMatcher(tt.term, false).MatchString(tt.text); got != tt.want {
+			t.Errorf("TermMatcher(%q).MatchString(%q) = %v, want %v", tt.term, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTerm(t *testing.T) {
+	for term, valid := range map[string]bool{
+		"invoice":     true,
+		"/inv\\d+/":   true,
+		"/[/":         false,
+		"/(a/":        false,
+		"//":          true, // too short to be a regex: a plain term
+		"/unclosed":   true,
+		"[not regex]": true,
+	} {
+		if err := ValidateTerm(term); (err == nil) != valid {
+			t.Errorf("ValidateTerm(%q) = %v, want valid=%v", term, err, valid)
+		}
+	}
+}
+
+func TestPrefilterTerms(t *testing.T) {
+	got := prefilterTerms([]string{"invoice", "/inv\\d+/", "wire transfer"})
+	if want := []string{"invoice", "wire", "transfer"}; !slices.Equal(got, want) {
+		t.Errorf("prefilterTerms = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// notebookText is a multiline notebook string, stored either as one string or as a list of lines
+type notebookText string
+
+// UnmarshalJSON accepts both forms of a multiline string
+func (t *notebookText) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*t = notebookText(s)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err != nil {
+		return err
+	}
+	*t = notebookText(strings.Join(lines, ""))
+	return nil
+}
+
+// notebook is the part of a Jupyter notebook (nbformat 4) that holds text
+type notebook struct {
+	Cells []struct {
+		CellType string       `json:"cell_type"`
+		Source   notebookText `json:"source"`
+		Outputs  []struct {
+			OutputType string                     `json:"output_type"`
+			Text       notebookText               `json:"text"` // stream
+			Data       map[string]json.RawMessage `json:"data"` // execute_result, display_data
+			EName      string                     `json:"ename"`
+			EValue     string                     `json:"evalue"`
+		} `json:"outputs"`
+	} `json:"cells"`
+}
+
+// IPYNBExtractor extracts the text of Jupyter notebooks: each cell is a part holding its
+// source (markdown, code or raw) followed by its text outputs (streams, text/plain and
+// text/markdown results, error messages), named by its 1-based index ("cell 3") so results
+// report the cell they matched in. Images and other rich outputs are skipped.
+type IPYNBExtractor struct{}
+
+// ExtractText implements the Extractor interface for notebooks
+func (e *IPYNBExtractor) ExtractText(data []byte) (string, error) {
+	parts, err := e.ExtractParts(context.Background(), data)
+	if err != nil {
+		return "", err
+	}
+	return joinParts(parts), nil
+}
+
+// ExtractParts implements the PartsExtractor interface: one section part per cell with text
+func (e *IPYNBExtractor) ExtractParts(ctx context.Context, data []byte) ([]TextPart, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+	var parts []TextPart
+	for i, cell := range nb.Cells {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var text strings.Builder
+		text.WriteString(string(cell.Source))
+		for _, out := range cell.Outputs {
+			var s string
+			switch out.OutputType {
+			case "stream":
+				s = string(out.Text)
+			case "execute_result", "display_data":
+				for _, mime := range []string{"text/markdown", "text/plain"} {
+					var t notebookText
+					if raw, ok := out.Data[mime]; ok && json.Unmarshal(raw, &t) == nil {
+						s = string(t)
+						break
+					}
+				}
+			case "error":
+				s = out.EName + ": " + out.EValue
+			}
+			if s = strings.TrimSpace(s); s != "" {
+				text.WriteString("\n")
+				text.WriteString(s)
+			}
+		}
+		if t := strings.TrimSpace(text.String()); t != "" {
+			parts = append(parts, TextPart{Name: fmt.Sprintf("cell %d", i+1), Text: t, Section: true})
+		}
+	}
+	return parts, nil
+}
+
+// JSONExtractor extracts the text of .json files: one line per scalar value, as
+// "key: value" with the key of the object member holding it (array items inherit the key
+// of their array). Strings are unescaped and punctuation left out; nulls are skipped.
+// Files that are not valid JSON are returned as they are.
+type JSONExtractor struct{}
+
+// ExtractText implements the Extractor interface for JSON files
+func (e *JSONExtractor) ExtractText(data []byte) (string, error) {
+	var text strings.Builder
+	if err := writeJSONText(&text, data); err != nil {
+		return string(data), nil
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// jsonFrame is an open object or array while walking JSON tokens
+type jsonFrame struct {
+	object  bool
+	key     string // member key in an object; the array's own key in an array
+	wantKey bool   // object: the next token is a member key
+}
+
+// writeJSONText writes the scalar values of a JSON document in document order (see JSONExtractor)
+func writeJSONText(w *strings.Builder, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var stack []jsonFrame
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return io.ErrUnexpectedEOF // an object or array left open
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = &stack[len(stack)-1]
+		}
+		if top != nil && top.object && top.wantKey {
+			if key, ok := tok.(string); ok {
+				top.key, top.wantKey = key, false
+				continue
+			}
+		}
+		key := ""
+		if top != nil {
+			key = top.key
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, jsonFrame{object: true, key: key, wantKey: true})
+				continue
+			case '[':
+				stack = append(stack, jsonFrame{key: key})
+				continue
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		case nil:
+		default:
+			if key != "" {
+				w.WriteString(key)
+				w.WriteString(": ")
+			}
+			fmt.Fprint(w, t)
+			w.WriteString("\n")
+		}
+		// A value is complete: an enclosing object expects its next key
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].wantKey = true
+		}
+	}
+}
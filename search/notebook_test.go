@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIPYNBExtractParts(t *testing.T) {
+	nb := `{"cells": [
+		{"cell_type": "markdown", "source": ["# Budget\n", "Quarterly **figures**"]},
+		{"cell_type": "code", "source": "print(total)", "outputs": [
+			{"output_type": "stream", "name": "stdout", "text": ["42\n", "done\n"]},
+			{"output_type": "execute_result", "data": {"text/plain": "'ok'", "image/png": "iVBORw0"}},
+			{"output_type": "display_data", "data": {"text/markdown": ["*rich*"], "text/plain": "plain"}},
+			{"output_type": "error", "ename": "KeyError", "evalue": "'invoice'", "traceback": ["ignored"]}
+		]},
+		{"cell_type": "code", "source": [], "outputs": []},
+		{"cell_type": "raw", "source": "raw text"}
+	], "metadata": {"kernelspec": {"name": "python3"}}, "nbformat": 4}`
+	parts, err := (&IPYNBExtractor{}).ExtractParts(context.Background(), []byte(nb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TextPart{
+		{Name: "cell 1", Text: "# Budget\nQuarterly **figures**", Section: true},
+		{Name: "cell 2", Text: "print(total)\n42\ndone\n'ok'\n*rich*\nKeyError: 'invoice'", Section: true},
+		{Name: "cell 4", Text: "raw text", Section: true}, // empty cells keep their number
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Errorf("parts = %+v\nwant %+v", parts, want)
+	}
+
+	for _, bad := range []string{`not json`, `{"cells": [{"source": 42}]}`} {
+		if _, err := (&IPYNBExtractor{}).ExtractText([]byte(bad)); err == nil {
+			t.Errorf("ExtractText(%s): want an error", bad)
+		}
+	}
+}
+
+func TestJSONExtractor(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"object", `{"name": "Ann \"A\" Lee", "age": 41, "active": true, "note": null}`, "name: Ann \"A\" Lee\nage: 41\nactive: true"},
+		{"array items inherit the key", `{"tags": ["go", "search"], "ids": [1, 2.5]}`, "tags: go\ntags: search\nids: 1\nids: 2.5"},
+		{"nested", `{"a": {"b": "x", "c": [{"d": "y"}, "z"]}, "e": "w"}`, "b: x\nd: y\nc: z\ne: w"},
+		{"top-level array", `["one", {"k": "v"}, 3]`, "one\nk: v\n3"},
+		{"escapes", `{"text": "line\nbreak café"}`, "text: line\nbreak café"},
+		{"big number kept", `{"n": 12345678901234567890}`, "n: 12345678901234567890"},
+		{"invalid kept as is", `{"a": `, `{"a": `},
+	}
+	for _, tt := range tests {
+		got, err := (&JSONExtractor{}).ExtractText([]byte(tt.data))
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	var b strings.Builder
+	if err := writeJSONText(&b, []byte(`{"a": "kept", "b": [`)); err == nil || b.String() != "a: kept\n" {
+		t.Errorf("writeJSONText on truncated JSON = %q, %v; want the values so far and an error", b.String(), err)
+	}
+}
@@ -5,6 +5,7 @@
 package pdf
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -13,8 +14,11 @@ import (
 	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"find-words/search/fold"
 )
 
 // Default caps for PDF text extraction.
@@ -23,19 +27,60 @@ const (
 	DefaultPerPageCap = 128 * 1024 // 128 KiB per-page text cap
 )
 
+// wordPattern returns the plural-aware regex for a search term, to be run over case-folded
+// text, and whether its matches need a whole-word check (see wholeWordMatches). A term
+// containing spaces is a phrase: its words must appear in order, separated by any whitespace
+// (line breaks included). A /regular expression/ term is used as-is, case-insensitively.
+// diacritics folds diacritics too. Mirrors the Matcher in the search package.
+func wordPattern(term string, diacritics bool) (string, bool) {
+	if len(term) > 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/") {
+		return "(?i)(?:" + term[1:len(term)-1] + ")", false
+	}
+	words := strings.Fields(fold.String(term, diacritics))
+	if len(words) == 0 {
+		return `a\A`, false
+	}
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	return fmt.Sprintf(`%s(?:es|s)?`, strings.Join(words, `\s+`)), true
+}
+
+// wholeWordMatches returns the offsets of every match of term in folded text that is not
+// embedded in a larger word (Unicode letters, digits and marks count as word characters).
+// diacritics is how folded was folded.
+func wholeWordMatches(folded, term string, diacritics bool) []int {
+	pattern, boundary := wordPattern(term, diacritics)
+	re := regexp.MustCompile(pattern)
+	var positions []int
+	for from := 0; from <= len(folded); {
+		loc := re.FindStringIndex(folded[from:])
+		if loc == nil {
+			break
+		}
+		start, end := from+loc[0], from+loc[1]
+		if !boundary || fold.WholeWordAt(folded, start, end) {
+			positions = append(positions, start)
+			from = max(end, start+1)
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(folded[start:])
+		from = start + max(size, 1)
+	}
+	return positions
+}
+
 // checkTextContainsAllWords checks if all words appear within the distance window in the text.
-func checkTextContainsAllWords(text string, words []string, distance int) bool {
+func checkTextContainsAllWords(text string, words []string, distance int, diacritics bool) bool {
 	if len(words) == 0 {
 		return true
 	}
 
-	contentStr := strings.ToLower(text)
+	contentStr := fold.String(text, diacritics)
 
 	// Single-term case: just check presence quickly
 	if len(words) == 1 {
-		pattern := fmt.Sprintf(`\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(strings.ToLower(words[0])))
-		regex := regexp.MustCompile(pattern)
-		return regex.FindStringIndex(contentStr) != nil
+		return len(wholeWordMatches(contentStr, words[0], diacritics)) > 0
 	}
 
 	// Collect positions for each word
@@ -45,11 +90,8 @@ func checkTextContainsAllWords(text string, words []string, distance int) bool {
 	}
 	var matches []match
 	for i, word := range words {
-		pattern := fmt.Sprintf(`\b(?:%s(?:es|s)?)\b`, regexp.QuoteMeta(strings.ToLower(word)))
-		regex := regexp.MustCompile(pattern)
-		indexes := regex.FindAllStringIndex(contentStr, -1)
-		for _, idx := range indexes {
-			matches = append(matches, match{pos: idx[0], wordIndex: i})
+		for _, pos := range wholeWordMatches(contentStr, word, diacritics) {
+			matches = append(matches, match{pos: pos, wordIndex: i})
 		}
 	}
 
@@ -108,11 +150,13 @@ func asciiNormalize(s string) string {
 // Returns the extracted text, a boolean indicating if all words are within the distance window, and any error.
 // - pageCap: maximum number of pages to include (use <=0 for default)
 // - perPageCap: maximum bytes of text per page (use <=0 for default)
-// - words: search words
+// - words: search words (nil extracts up to the caps without short-circuiting)
 // - window: distance window
+// - diacritics: fold diacritics when matching words (--fold-diacritics)
 //
+// ctx is checked before each batch and page; once it is done the extraction stops with its error.
 // This function is guarded by the 'pdfcpu' build tag.
-func ExtractAllTextCapped(path string, pageCap, perPageCap int, words []string, window int) (string, bool, error) {
+func ExtractAllTextCapped(ctx context.Context, path string, pageCap, perPageCap int, words []string, window int, diacritics bool) (string, bool, error) {
 	// Defaults
 	if pageCap <= 0 {
 		pageCap = DefaultPageCap
@@ -209,6 +253,9 @@ func ExtractAllTextCapped(path string, pageCap, perPageCap int, words []string,
 	var aggregated strings.Builder
 
 	for start := 1; start <= pageCount && start <= pageCap; start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return "", false, err
+		}
 		end := start + batchSize - 1
 		if end > pageCount {
 			end = pageCount
@@ -264,6 +311,9 @@ func ExtractAllTextCapped(path string, pageCap, perPageCap int, words []string,
 			if de.IsDir() {
 				continue
 			}
+			if err := ctx.Err(); err != nil {
+				return "", false, err
+			}
 			fp := filepath.Join(tmpDir, de.Name())
 			data, _ := os.ReadFile(fp)
 			if len(data) == 0 {
@@ -287,7 +337,7 @@ func ExtractAllTextCapped(path string, pageCap, perPageCap int, words []string,
 
 		// Check if the aggregated text so far contains all words within the distance window.
 		currentText := aggregated.String()
-		if checkTextContainsAllWords(currentText, words, window) {
+		if len(words) > 0 && checkTextContainsAllWords(currentText, words, window, diacritics) {
 			return currentText, true, nil
 		}
 	}
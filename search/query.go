@@ -0,0 +1,224 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query is a parsed boolean search. Every group must match within the distance window
+// (AND); a group matches when any one of its alternatives does (OR). Negated terms must
+// not appear anywhere in the file.
+//
+// Grammar, over the command-line arguments (implicit AND between items, OR binds tighter):
+//
+//	query   := item+
+//	item    := '-' TERM | orExpr
+//	orExpr  := operand ('OR' operand)*
+//	operand := TERM | '(' orExpr ')'
+//
+// e.g. `invoice (wire OR ach) -test`. An argument containing whitespace is always a
+// single (phrase) term, and a /regular expression/ argument is always a single term.
+type Query struct {
+	Groups  [][]string
+	Negated []string
+}
+
+// NewQuery returns the plain AND query over words (one group per word)
+func NewQuery(words []string) *Query {
+	return &Query{Groups: singletonGroups(words)}
+}
+
+// ParseQuery parses command-line search arguments into a Query
+func ParseQuery(args []string) (*Query, error) {
+	p := &queryParser{toks: tokenizeQuery(args)}
+	q := &Query{}
+	for p.pos < len(p.toks) {
+		tok := p.toks[p.pos]
+		if isNegatedToken(tok) {
+			p.pos++
+			if p.peek() == "OR" {
+				return nil, fmt.Errorf("negated term %q cannot be part of an OR group", tok)
+			}
+			q.Negated = append(q.Negated, tok[1:])
+			continue
+		}
+		group, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		q.Groups = append(q.Groups, group)
+	}
+	if len(q.Groups) == 0 {
+		return nil, fmt.Errorf("query needs at least one term to search for")
+	}
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Validate checks that every /regular expression/ term in the query compiles
+func (q *Query) Validate() error {
+	for _, t := range append(q.Terms(), q.Negated...) {
+		if err := ValidateTerm(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Terms returns every positive term in query order (used for excerpts and highlighting)
+func (q *Query) Terms() []string {
+	var terms []string
+	for _, g := range q.Groups {
+		terms = append(terms, g...)
+	}
+	return terms
+}
+
+// MandatoryTerms returns the terms every match must contain: those of single-term groups.
+// Prefilters may only prune on these.
+func (q *Query) MandatoryTerms() []string {
+	return mandatoryTerms(q.Groups)
+}
+
+// String renders the query back in its own syntax, quoting phrases
+func (q *Query) String() string {
+	quote := func(t string) string {
+		if strings.ContainsAny(t, " \t\n") && !isRegexTerm(t) {
+			return `"` + t + `"`
+		}
+		return t
+	}
+	var parts []string
+	for _, g := range q.Groups {
+		if len(g) == 1 {
+			parts = append(parts, quote(g[0]))
+			continue
+		}
+		alts := make([]string, len(g))
+		for i, t := range g {
+			alts[i] = quote(t)
+		}
+		parts = append(parts, "("+strings.Join(alts, " OR ")+")")
+	}
+	for _, t := range q.Negated {
+		parts = append(parts, "-"+quote(t))
+	}
+	return strings.Join(parts, " ")
+}
+
+type queryParser struct {
+	toks []string
+	pos  int
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return ""
+}
+
+// parseOr parses operand ('OR' operand)* into the flat list of alternatives
+func (p *queryParser) parseOr() ([]string, error) {
+	alts, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "OR" {
+		p.pos++
+		more, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		alts = append(alts, more...)
+	}
+	return alts, nil
+}
+
+func (p *queryParser) parseOperand() ([]string, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("query ends where a term was expected")
+	case tok == "OR":
+		return nil, fmt.Errorf("OR needs a term on both sides")
+	case tok == ")":
+		return nil, fmt.Errorf("unbalanced ')' in query")
+	case isNegatedToken(tok):
+		return nil, fmt.Errorf("negated term %q cannot be part of an OR group", tok)
+	case tok == "(":
+		p.pos++
+		alts, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			if p.peek() == "" {
+				return nil, fmt.Errorf("missing ')' in query")
+			}
+			return nil, fmt.Errorf("only OR is supported inside parentheses (got %q)", p.peek())
+		}
+		p.pos++
+		return alts, nil
+	}
+	p.pos++
+	return []string{tok}, nil
+}
+
+// tokenizeQuery splits parentheses off the edges of each argument ("(wire" -> "(", "wire").
+// Arguments containing whitespace are phrases and are kept whole.
+func tokenizeQuery(args []string) []string {
+	var toks []string
+	for _, a := range args {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if strings.ContainsAny(a, " \t\n") || isRegexTerm(strings.TrimPrefix(a, "-")) {
+			toks = append(toks, a)
+			continue
+		}
+		for strings.HasPrefix(a, "(") {
+			toks = append(toks, "(")
+			a = a[1:]
+		}
+		closing := 0
+		for strings.HasSuffix(a, ")") {
+			closing++
+			a = a[:len(a)-1]
+		}
+		if a != "" {
+			toks = append(toks, a)
+		}
+		for ; closing > 0; closing-- {
+			toks = append(toks, ")")
+		}
+	}
+	return toks
+}
+
+func isNegatedToken(tok string) bool {
+	return len(tok) > 1 && tok[0] == '-' && (!strings.ContainsAny(tok, " \t\n") || isRegexTerm(tok[1:]))
+}
+
+// singletonGroups makes every word its own group (plain AND)
+func singletonGroups(words []string) [][]string {
+	groups := make([][]string, 0, len(words))
+	for _, w := range words {
+		groups = append(groups, []string{w})
+	}
+	return groups
+}
+
+// mandatoryTerms returns the terms of single-alternative groups
+func mandatoryTerms(groups [][]string) []string {
+	var terms []string
+	for _, g := range groups {
+		if len(g) == 1 {
+			terms = append(terms, g[0])
+		}
+	}
+	return terms
+}
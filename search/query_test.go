@@ -0,0 +1,96 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		args    []string
+		groups  [][]string
+		negated []string
+		str     string
+	}{
+		{[]string{"invoice", "paid"}, [][]string{{"invoice"}, {"paid"}}, nil, "invoice paid"},
+		{[]string{"invoice", "(wire", "OR", "ach)", "-test"}, [][]string{{"invoice"}, {"wire", "ach"}}, []string{"test"}, "invoice (wire OR ach) -test"},
+		{[]string{"wire", "OR", "ach", "OR", "swift"}, [][]string{{"wire", "ach", "swift"}}, nil, "(wire OR ach OR swift)"},
+		{[]string{"((wire", "OR", "ach))"}, [][]string{{"wire", "ach"}}, nil, "(wire OR ach)"},
+		{[]string{"(a", "OR", "(b", "OR", "c))"}, [][]string{{"a", "b", "c"}}, nil, "(a OR b OR c)"},
+		{[]string{"quarterly report", "OR", "annual"}, [][]string{{"quarterly report", "annual"}}, nil, `("quarterly report" OR annual)`},
+		{[]string{"invoice", "-draft copy"}, [][]string{{"invoice"}, {"-draft copy"}}, nil, `invoice "-draft copy"`},
+		{[]string{"-", "x"}, [][]string{{"-"}, {"x"}}, nil, "- x"},
+		{[]string{"/inv(oice)?/", "-/te?st/"}, [][]string{{"/inv(oice)?/"}}, []string{"/te?st/"}, "/inv(oice)?/ -/te?st/"},
+		{[]string{"/(a|b) c/"}, [][]string{{"/(a|b) c/"}}, nil, "/(a|b) c/"},
+		{[]string{"or", "and"}, [][]string{{"or"}, {"and"}}, nil, "or and"}, // only uppercase OR is an operator
+		{[]string{" ", "x"}, [][]string{{"x"}}, nil, "x"},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.args)
+		if err != nil {
+			t.Errorf("ParseQuery(%q): %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(q.Groups, tt.groups) || !reflect.DeepEqual(q.Negated, tt.negated) {
+			t.Errorf("ParseQuery(%q) = %q -%q, want %q -%q", tt.args, q.Groups, q.Negated, tt.groups, tt.negated)
+		}
+		if got := q.String(); got != tt.str {
+			t.Errorf("ParseQuery(%q).String() = %q, want %q", tt.args, got, tt.str)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-test"},
+		{"a", "OR"},
+		{"OR", "a"},
+		{"a", "OR", "OR", "b"},
+		{"(a", "OR", "b"},
+		{"a)"},
+		{"(a", "b)"},
+		{"a", "OR", "-b"},
+		{"-a", "OR", "b"},
+		{"()"},
+		{"/[/"},
+		{"a", "-/(/"},
+	} {
+		if q, err := ParseQuery(args); err == nil {
+			t.Errorf("ParseQuery(%q) = %q, want an error", args, q)
+		}
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	q, err := ParseQuery([]string{"invoice", "(wire", "OR", "ach)", "-test", "paid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Terms(), []string{"invoice", "wire", "ach", "paid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Terms() = %q, want %q", got, want)
+	}
+	if got, want := q.MandatoryTerms(), []string{"invoice", "paid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MandatoryTerms() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckTextMatchesGroups(t *testing.T) {
+	text := "The invoice was paid by wire transfer last week"
+	tests := []struct {
+		groups   [][]string
+		distance int
+		want     bool
+	}{
+		{[][]string{{"invoice"}, {"wire", "ach"}}, 50, true},
+		{[][]string{{"invoice"}, {"ach", "swift"}}, 50, false},
+		{[][]string{{"invoice"}, {"wire"}}, 10, false}, // too far apart
+		{[][]string{{"paid by wire"}}, 0, true},
+		{[][]string{{"wire paid"}}, 50, false}, // phrases keep their word order
+	}
+	for _, tt := range tests {
+		if got := CheckTextMatchesGroups(text, tt.groups, tt.distance, false); got != tt.want {
+			t.Errorf("CheckTextMatchesGroups(%q, %d) = %v, want %v", tt.groups, tt.distance, got, tt.want)
+		}
+	}
+}
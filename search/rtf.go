@@ -0,0 +1,354 @@
+package search
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// rtfSkipDestinations are groups whose content is not document text: tables, metadata,
+// pictures and embedded objects, field instructions and the non-picture fallbacks
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true,
+	"object": true, "objdata": true, "fldinst": true, "nonshppict": true, "themedata": true,
+	"colorschememapping": true, "datastore": true, "latentstyles": true, "listtable": true,
+	"listoverridetable": true, "rsidtbl": true, "revtbl": true, "filetbl": true,
+	"generator": true, "xmlnstbl": true, "mmathPr": true, "template": true,
+}
+
+// rtfSymbols maps control words that stand for text to that text
+var rtfSymbols = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n", "page": "\n", "row": "\n",
+	"tab": "\t", "cell": "\t",
+	"emdash": "—", "endash": "–", "bullet": "•", "emspace": " ", "enspace": " ", "qmspace": " ",
+	"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+}
+
+// rtfCharsetCodepages maps \fcharset values to Windows code pages
+var rtfCharsetCodepages = map[int]int{
+	0: 1252, 77: 10000, 128: 932, 129: 949, 134: 936, 136: 950, 161: 1253, 162: 1254,
+	163: 1258, 177: 1255, 178: 1256, 186: 1257, 204: 1251, 222: 874, 238: 1250, 255: 437,
+}
+
+// rtfCodepage returns the decoder for a Windows code page (Windows-1252 when unknown)
+func rtfCodepage(cp int) encoding.Encoding {
+	switch cp {
+	case 437:
+		return charmap.CodePage437
+	case 850:
+		return charmap.CodePage850
+	case 866:
+		return charmap.CodePage866
+	case 874:
+		return charmap.Windows874
+	case 932:
+		return japanese.ShiftJIS
+	case 936:
+		return simplifiedchinese.GBK
+	case 949:
+		return korean.EUCKR
+	case 950:
+		return traditionalchinese.Big5
+	case 1250:
+		return charmap.Windows1250
+	case 1251:
+		return charmap.Windows1251
+	case 1253:
+		return charmap.Windows1253
+	case 1254:
+		return charmap.Windows1254
+	case 1255:
+		return charmap.Windows1255
+	case 1256:
+		return charmap.Windows1256
+	case 1257:
+		return charmap.Windows1257
+	case 1258:
+		return charmap.Windows1258
+	case 10000:
+		return charmap.Macintosh
+	}
+	return charmap.Windows1252
+}
+
+// rtfGroup is the state a {group} inherits from its parent and restores on exit
+type rtfGroup struct {
+	skip bool // inside a destination that is not text
+	uc   int  // fallback characters following \uN (\ucN)
+	cp   int  // code page of the current font (0: the document's \ansicpg)
+}
+
+// rtfParser turns an RTF stream into plain text
+type rtfParser struct {
+	r       *bufio.Reader
+	w       *bufio.Writer
+	group   rtfGroup
+	stack   []rtfGroup
+	ansiCP  int         // \ansicpg (or implied by \mac, \pc, \pca)
+	fontCP  map[int]int // font number -> code page, from \fcharset in the font table
+	font    int         // font being declared in the font table
+	fonttbl int         // stack depth of the font table group (0: not inside)
+	pending []byte      // \'hh bytes awaiting decoding (double-byte code pages span two)
+	toSkip  int         // fallback characters still to drop after \uN
+	high    rune        // high surrogate of a \uN pair awaiting its low half
+}
+
+// writeRTFText writes the text of an RTF document: \par, \line and friends become line
+// breaks, \'hh escapes are decoded by the declared code page (document or font charset),
+// \uN escapes become their character (surrogate pairs combined), and font/color/style tables, pictures, objects,
+// field instructions and \* destinations are skipped.
+func writeRTFText(w io.Writer, r io.Reader) error {
+	p := &rtfParser{
+		r:      bufio.NewReader(r),
+		w:      bufio.NewWriter(w),
+		group:  rtfGroup{uc: 1},
+		ansiCP: 1252,
+		fontCP: make(map[int]int),
+	}
+	err := p.run()
+	p.flush()
+	if ferr := p.w.Flush(); ferr != nil && err == io.EOF {
+		return ferr
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// rtfStreamText streams the text of an RTF document (see writeRTFText) through a pipe, so the
+// prefilter scans what the extractor will see. It takes over rc and closes it when parsing
+// ends; closing the returned reader stops the parser at its next write.
+func rtfStreamText(rc io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		err := writeRTFText(pw, rc)
+		rc.Close()
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+func (p *rtfParser) run() error {
+	for {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch c {
+		case '{':
+			p.flush()
+			p.stack = append(p.stack, p.group)
+		case '}':
+			p.flush()
+			if p.fonttbl == len(p.stack) {
+				p.fonttbl = 0
+			}
+			if n := len(p.stack); n > 0 {
+				p.group = p.stack[n-1]
+				p.stack = p.stack[:n-1]
+			}
+		case '\\':
+			if err := p.control(); err != nil {
+				return err
+			}
+		case '\r', '\n':
+			// Line breaks in the source are not text
+		default:
+			p.flush()
+			if p.toSkip > 0 {
+				p.toSkip--
+				continue
+			}
+			if !p.group.skip {
+				if err := p.w.WriteByte(c); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// control handles what follows a backslash: a control word, a hex escape or a control symbol
+func (p *rtfParser) control() error {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c == '\'':
+		hex := make([]byte, 2)
+		if _, err := io.ReadFull(p.r, hex); err != nil {
+			return err
+		}
+		b, err := strconv.ParseUint(string(hex), 16, 8)
+		if err != nil {
+			return nil
+		}
+		if p.toSkip > 0 {
+			p.toSkip--
+			return nil
+		}
+		if !p.group.skip {
+			p.pending = append(p.pending, byte(b))
+		}
+		return nil
+	case isASCIILetter(c):
+		return p.word(c)
+	}
+	p.flush()
+	switch c {
+	case '*':
+		p.group.skip = true // ignorable destination
+	case '\\', '{', '}':
+		p.text(string(c))
+	case '~':
+		p.text(" ")
+	case '_':
+		p.text("-")
+	case '\r', '\n':
+		p.text("\n")
+	}
+	return nil
+}
+
+// word reads the rest of a control word starting with first, and its optional parameter
+func (p *rtfParser) word(first byte) error {
+	name := []byte{first}
+	for {
+		c, err := p.r.ReadByte()
+		if err != nil {
+			return p.apply(string(name), 0, false)
+		}
+		if isASCIILetter(c) {
+			name = append(name, c)
+			continue
+		}
+		p.r.UnreadByte()
+		break
+	}
+	var num []byte
+	if c, err := p.r.ReadByte(); err == nil {
+		if c == '-' || (c >= '0' && c <= '9') {
+			num = append(num, c)
+			for {
+				c, err := p.r.ReadByte()
+				if err != nil {
+					break
+				}
+				if c >= '0' && c <= '9' {
+					num = append(num, c)
+					continue
+				}
+				if c != ' ' {
+					p.r.UnreadByte()
+				}
+				break
+			}
+		} else if c != ' ' {
+			// A space after a control word is its delimiter; anything else is text
+			p.r.UnreadByte()
+		}
+	}
+	param, err := strconv.Atoi(string(num))
+	return p.apply(string(name), param, err == nil)
+}
+
+// apply acts on one control word
+func (p *rtfParser) apply(name string, param int, hasParam bool) error {
+	p.flush()
+	if p.fonttbl > 0 {
+		// Inside the font table: learn each font's charset
+		switch name {
+		case "f":
+			p.font = param
+		case "fcharset":
+			if cp, ok := rtfCharsetCodepages[param]; ok {
+				p.fontCP[p.font] = cp
+			}
+		}
+		return nil
+	}
+	switch name {
+	case "fonttbl":
+		p.fonttbl = len(p.stack)
+		p.group.skip = true
+	case "bin":
+		// Binary data follows; never text
+		if hasParam && param > 0 {
+			_, err := p.r.Discard(param)
+			return err
+		}
+	case "ansicpg":
+		p.ansiCP = param
+	case "mac":
+		p.ansiCP = 10000
+	case "pc":
+		p.ansiCP = 437
+	case "pca":
+		p.ansiCP = 850
+	case "f":
+		p.group.cp = p.fontCP[param]
+	case "uc":
+		if hasParam && param >= 0 {
+			p.group.uc = param
+		}
+	case "u":
+		if param < 0 {
+			param += 65536
+		}
+		r, high := rune(param), p.high
+		p.high = 0
+		switch {
+		case r >= 0xD800 && r < 0xDC00:
+			p.high = r // characters beyond the BMP come as two \uN, high surrogate first
+		case high != 0 && utf16.IsSurrogate(r):
+			p.text(string(utf16.DecodeRune(high, r)))
+		default:
+			p.text(string(r))
+		}
+		p.toSkip = p.group.uc
+	default:
+		if rtfSkipDestinations[name] {
+			p.group.skip = true
+		} else if s, ok := rtfSymbols[name]; ok {
+			p.text(s)
+		}
+	}
+	return nil
+}
+
+// text writes s unless the current group is skipped
+func (p *rtfParser) text(s string) {
+	if !p.group.skip {
+		p.w.WriteString(s)
+	}
+}
+
+// flush decodes pending \'hh bytes with the current code page
+func (p *rtfParser) flush() {
+	if len(p.pending) == 0 {
+		return
+	}
+	cp := p.group.cp
+	if cp == 0 {
+		cp = p.ansiCP
+	}
+	s, err := rtfCodepage(cp).NewDecoder().Bytes(p.pending)
+	if err == nil {
+		p.w.Write(s)
+	}
+	p.pending = p.pending[:0]
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
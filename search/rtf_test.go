@@ -0,0 +1,38 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteRTFText(t *testing.T) {
+	tests := []struct {
+		name, rtf, want string
+	}{
+		{"paragraphs", `{\rtf1\ansi Hello\par World}`, "Hello\nWorld"},
+		{"font table skipped", `{\rtf1{\fonttbl{\f0 Times New Roman;}}\f0 Body}`, "Body"},
+		{"ignorable destination", `{\rtf1{\*\generator Writer;}Text}`, "Text"},
+		{"escaped braces", `{\rtf1 a\{b\}c\\d}`, `a{b}c\d`},
+		{"cp1252 hex escape", `{\rtf1\ansi\ansicpg1252 caf\'e9}`, "café"},
+		{"font charset", `{\rtf1{\fonttbl{\f1\fcharset204 Arial;}}\f1 \'cf\'f0\'e8\'e2\'e5\'f2}`, "Привет"},
+		{"shift-jis font", `{\rtf1{\fonttbl{\f1\fcharset128 MS Mincho;}}\f1 \'89\'ef\'8b\'63}`, "会議"},
+		{"unicode with fallback", `{\rtf1 na\u239?ve}`, "naïve"},
+		{"unicode without fallback", `{\rtf1\uc0 \u26085\u26412}`, "日本"},
+		{"negative unicode", `{\rtf1 \u-3913?}`, "\uf0b7"},
+		{"surrogate pair", `{\rtf1 smile \u-10179?\u-8704?}`, "smile 😀"},
+		{"surrogate pair without fallback", `{\rtf1\uc0 \u-10179\u-8704 ok}`, "😀ok"},
+		{"lone high surrogate", `{\rtf1 a\u-10179?b}`, "ab"},
+		{"binary data", "{\\rtf1 x\\bin3 {}\\y}", "xy"},
+		{"picture skipped", `{\rtf1 before{\pict\pngblip 89504e47}after}`, "beforeafter"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		if err := writeRTFText(&out, strings.NewReader(tt.rtf)); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := strings.TrimSpace(out.String()); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
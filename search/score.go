@@ -0,0 +1,206 @@
+package search
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Relevance model. A result's Score adds up five signals, each scaled to [0, 1]:
+//   - term frequency, BM25-style: the saturated count of each group, normalized by the
+//     length of the cleaned text against bm25AvgWords (there are no corpus statistics while
+//     results stream in, so every group weighs the same instead of by IDF);
+//   - proximity: how tight the tightest window covering every group is, against --distance;
+//   - windows: how many separate windows cover every group;
+//   - title: the share of groups that also match the email subject or the front matter title;
+//   - recency: halving every recencyHalfLife, from the email date or else the file's mtime.
+const (
+	bm25K1          = 1.2
+	bm25B           = 0.75
+	bm25AvgWords    = 1000
+	recencyHalfLife = 365 * 24 * time.Hour
+
+	weightTermFreq  = 3
+	weightProximity = 2
+	weightWindows   = 1
+	weightTitle     = 2
+	weightRecency   = 1
+)
+
+// SortKeys are the --sort orders: score (highest first), path (A to Z), mtime and date
+// (newest first) and size (largest first)
+var SortKeys = []string{"score", "path", "mtime", "size", "date"}
+
+// ValidateSort checks a --sort value ("" keeps the order results are found in)
+func ValidateSort(key string) error {
+	if key == "" || slices.Contains(SortKeys, key) {
+		return nil
+	}
+	return fmt.Errorf("unknown --sort %q (want %s)", key, strings.Join(SortKeys, ", "))
+}
+
+// CompareResults orders results by key: negative when a comes before b. Ties, and results
+// without the date being sorted on, fall back to their paths.
+func CompareResults(a, b SearchResult, key string) int {
+	c := 0
+	switch key {
+	case "score":
+		c = cmp.Compare(b.Score, a.Score)
+	case "mtime":
+		c = b.ModTime.Compare(a.ModTime)
+	case "size":
+		c = cmp.Compare(b.FileSize, a.FileSize)
+	case "date":
+		c = b.when().Compare(a.when())
+	}
+	if c != 0 {
+		return c
+	}
+	return cmp.Or(strings.Compare(a.FullPath(), b.FullPath()), strings.Compare(a.Attachment, b.Attachment))
+}
+
+// SortResults sorts results in place by key (see SortKeys); "" leaves them as they are
+func SortResults(results []SearchResult, key string) {
+	if key == "" {
+		return
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int { return CompareResults(a, b, key) })
+}
+
+// when is the date a result is sorted and aged by: the email date, else the modification time
+func (r SearchResult) when() time.Time {
+	if !r.Date.IsZero() {
+		return r.Date
+	}
+	return r.ModTime
+}
+
+// windowStats carries the distance check through the whole text: span is the tightest
+// window covering an occurrence of each of the required words within distance characters
+// (-1 when there is none) and windows how many such windows fit side by side.
+func windowStats(matches []termMatch, required int, distance int) (span, windows int) {
+	slices.SortFunc(matches, func(a, b termMatch) int { return cmp.Compare(a.pos, b.pos) })
+
+	span = -1
+	counts := make(map[int]int)
+	covered := 0
+	left := 0
+	lastEnd := -1 // index of the match that closed the last counted window
+
+	for right := 0; right < len(matches); right++ {
+		rw := matches[right].wordIndex
+		if counts[rw] == 0 {
+			covered++
+		}
+		counts[rw]++
+
+		// Every left end that still covers all words gives a window closing at right;
+		// the last one is the tightest
+		for covered == required && left <= right {
+			window := matches[right].pos - matches[left].pos
+			if window <= distance {
+				if span < 0 || window < span {
+					span = window
+				}
+				if left > lastEnd {
+					windows++
+					lastEnd = right
+				}
+			}
+			lw := matches[left].wordIndex
+			counts[lw]--
+			if counts[lw] == 0 {
+				covered--
+			}
+			left++
+		}
+	}
+	return span, windows
+}
+
+// scoreResult computes the Score of a result from its cleaned text (see the relevance
+// model above)
+func (se *SearchEngine) scoreResult(r SearchResult, clean string) float64 {
+	groups := se.groups()
+	distance := se.Distance
+	if distance <= 0 {
+		distance = DefaultDistance
+	}
+
+	var termFreq, proximity, windows, title float64
+	if len(groups) > 0 {
+		docLen := float64(len(strings.Fields(clean)))
+		norm := bm25K1 * (1 - bm25B + bm25B*docLen/bm25AvgWords)
+		titles := []string{r.EmailSubject, r.Meta.title()}
+
+		var matches []termMatch
+		inTitle := 0
+		for i, group := range groups {
+			freq, titled := 0, false
+			for _, term := range group {
+				m := newMatcher(term, wordFormSuffixes(), se.FoldDiacritics)
+				indexes := m.FindAllStringIndex(clean, -1)
+				for _, idx := range indexes {
+					matches = append(matches, termMatch{pos: idx[0], wordIndex: i})
+				}
+				freq += len(indexes)
+				titled = titled || slices.ContainsFunc(titles, m.MatchString)
+			}
+			f := float64(freq)
+			termFreq += f / (f + norm)
+			if titled {
+				inTitle++
+			}
+		}
+		termFreq /= float64(len(groups))
+		title = float64(inTitle) / float64(len(groups))
+
+		if span, n := windowStats(matches, len(groups), distance); n > 0 {
+			proximity = 1 - float64(span)/float64(distance+1)
+			windows = float64(n) / float64(n+2)
+		}
+	}
+
+	recency := 0.0
+	if when := r.when(); !when.IsZero() {
+		age := time.Since(when)
+		if age < 0 {
+			age = 0
+		}
+		recency = math.Pow(0.5, float64(age)/float64(recencyHalfLife))
+	}
+
+	score := weightTermFreq*termFreq + weightProximity*proximity + weightWindows*windows +
+		weightTitle*title + weightRecency*recency
+	return math.Round(score*1000) / 1000
+}
+
+// title returns the "title" field of front matter ("" when there is none)
+func (fm FrontMatter) title() string {
+	for _, f := range fm {
+		if strings.EqualFold(f.Key, "title") && len(f.Values) > 0 {
+			return f.Values[0]
+		}
+	}
+	return ""
+}
+
+// modTime returns the modification time of the file a result comes from: for a message or
+// an archive entry, that of its mailbox or archive
+func (se *SearchEngine) modTime(filePath string) time.Time {
+	if file, _, ok := se.splitMailbox(filePath); ok {
+		filePath = file
+	}
+	if archive, _, ok := SplitArchivePath(filePath); ok {
+		filePath = archive
+	}
+	st, err := os.Stat(filePath)
+	if err != nil {
+		return time.Time{}
+	}
+	return st.ModTime()
+}
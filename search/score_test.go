@@ -0,0 +1,100 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWindowStats(t *testing.T) {
+	m := func(pos, word int) termMatch { return termMatch{pos: pos, wordIndex: word} }
+	tests := []struct {
+		name     string
+		matches  []termMatch
+		required int
+		distance int
+		span     int
+		windows  int
+	}{
+		{"none", nil, 2, 10, -1, 0},
+		{"single word", []termMatch{m(0, 0), m(50, 0)}, 1, 10, 0, 2},
+		{"one window", []termMatch{m(0, 0), m(5, 1)}, 2, 10, 5, 1},
+		{"too far", []termMatch{m(0, 0), m(20, 1)}, 2, 10, -1, 0},
+		{"unsorted", []termMatch{m(5, 1), m(0, 0)}, 2, 10, 5, 1},
+		{"tightest wins", []termMatch{m(0, 0), m(8, 1), m(30, 0), m(32, 1)}, 2, 10, 2, 2},
+		{"overlapping windows count once", []termMatch{m(0, 0), m(2, 1), m(4, 0)}, 2, 10, 2, 1},
+		{"missing word", []termMatch{m(0, 0), m(1, 0)}, 2, 10, -1, 0},
+		{"three words", []termMatch{m(0, 0), m(3, 1), m(6, 2), m(100, 0), m(103, 1)}, 3, 10, 6, 1},
+	}
+	for _, tt := range tests {
+		span, windows := windowStats(slices.Clone(tt.matches), tt.required, tt.distance)
+		if span != tt.span || windows != tt.windows {
+			t.Errorf("%s: windowStats = %d, %d; want %d, %d", tt.name, span, windows, tt.span, tt.windows)
+		}
+	}
+}
+
+func TestScoreResult(t *testing.T) {
+	se := NewSearchEngine([]string{"invoice", "paid"}, nil, nil, false, 1, 1000)
+	score := func(r SearchResult, text string) float64 { return se.scoreResult(r, text) }
+
+	near := score(SearchResult{}, "the invoice was paid")
+	far := score(SearchResult{}, "the invoice was sent. "+strings.Repeat("filler ", 20)+"it was paid")
+	if near <= far {
+		t.Errorf("closer terms should score higher: %v <= %v", near, far)
+	}
+	often := score(SearchResult{}, "invoice paid. invoice paid. invoice paid.")
+	if often <= near {
+		t.Errorf("more windows should score higher: %v <= %v", often, near)
+	}
+	titled := score(SearchResult{EmailSubject: "Invoice paid"}, "the invoice was paid")
+	if titled <= near {
+		t.Errorf("terms in the subject should score higher: %v <= %v", titled, near)
+	}
+	recent := score(SearchResult{ModTime: time.Now()}, "the invoice was paid")
+	old := score(SearchResult{ModTime: time.Now().Add(-3 * recencyHalfLife)}, "the invoice was paid")
+	if recent <= old || old <= near {
+		t.Errorf("newer files should score higher: %v, %v, %v", recent, old, near)
+	}
+	if got := score(SearchResult{}, "nothing relevant"); got != 0 {
+		t.Errorf("a text without the terms scores %v, want 0", got)
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	results := []SearchResult{
+		{FilePath: "b.txt", Score: 1, FileSize: 30, ModTime: day(2)},
+		{FilePath: "a.txt", Score: 3, FileSize: 10, ModTime: day(1), Date: day(9)},
+		{FilePath: "c.txt", Score: 3, FileSize: 20, ModTime: day(3)},
+	}
+	paths := func(rs []SearchResult) []string {
+		out := make([]string, len(rs))
+		for i, r := range rs {
+			out[i] = r.FilePath
+		}
+		return out
+	}
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"", []string{"b.txt", "a.txt", "c.txt"}},
+		{"score", []string{"a.txt", "c.txt", "b.txt"}}, // ties by path
+		{"path", []string{"a.txt", "b.txt", "c.txt"}},
+		{"mtime", []string{"c.txt", "b.txt", "a.txt"}},
+		{"size", []string{"b.txt", "c.txt", "a.txt"}},
+		{"date", []string{"a.txt", "c.txt", "b.txt"}}, // the email date, else the mtime
+	}
+	for _, tt := range tests {
+		rs := slices.Clone(results)
+		SortResults(rs, tt.key)
+		if got := paths(rs); !slices.Equal(got, tt.want) {
+			t.Errorf("SortResults(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	if ValidateSort("relevance") == nil || ValidateSort("") != nil || ValidateSort("size") != nil {
+		t.Error("ValidateSort accepts exactly \"\" and the SortKeys")
+	}
+}
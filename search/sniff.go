@@ -0,0 +1,257 @@
+package search
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/richardlehane/mscfb"
+
+	"find-words/config"
+)
+
+// sniffBytes is how much of a file the magic-byte checks read (a PDF header may follow up
+// to 1 KiB of junk)
+const sniffBytes = 1024
+
+// oleMagic starts every OLE compound file (.doc, .msg, .xls, .ppt)
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// magicFormats are the formats with a signature: their files are checked against their
+// content when extracted, so a mislabeled file (RTF or HTML saved as .doc) is caught
+var magicFormats = map[string]bool{
+	".pdf": true, ".rtf": true, ".doc": true, ".msg": true,
+	".docx": true, ".xlsx": true, ".pptx": true, ".odt": true, ".ods": true, ".odp": true, ".epub": true,
+}
+
+// sniffedFormats are the formats sniffContent can tell: magicFormats plus mail and HTML
+var sniffedFormats = []string{
+	".pdf", ".rtf", ".doc", ".msg", ".docx", ".xlsx", ".pptx", ".odt", ".ods", ".odp", ".epub",
+	".mbox", ".eml", ".html",
+}
+
+// mailHeaders are the header names that make a block of "Name: value" lines an email
+var mailHeaders = map[string]bool{
+	"from": true, "to": true, "cc": true, "subject": true, "date": true, "received": true,
+	"return-path": true, "message-id": true, "mime-version": true, "delivered-to": true,
+	"reply-to": true, "content-type": true, "x-mailer": true,
+}
+
+// selects reports whether discovery keeps path. Files the globs select are kept. Files
+// without an extension, or with one no type group lists (.dat, .bin), are kept when their
+// content is of a selected format; only they are opened here, and only when the selection
+// takes a format sniffing can tell. The formats found are recorded for name.
+func (f typeFilter) selects(path string) bool {
+	if f.allows(path) {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if !f.sniffs || ext != "" && f.known[ext] {
+		return false
+	}
+	format := sniffFormat(path)
+	if format == "" || !f.allows("file"+format) {
+		return false
+	}
+	f.sniffed.Store(path, format)
+	return true
+}
+
+// name returns the name the format of path is told from: path itself, or for a file
+// selects placed by its content, path with that format appended ("notes" → "notes.eml"),
+// so formatExt, IsBinaryFormat and the other name-based checks see the format it is
+func (f typeFilter) name(path string) string {
+	if f.sniffed != nil {
+		if format, ok := f.sniffed.Load(path); ok {
+			return path + format.(string)
+		}
+	}
+	return path
+}
+
+// contentFormat returns the format a document is read as: format, unless that is one with
+// a signature and content shows another (an RTF or HTML file saved as .doc). The content
+// is in memory already, so this costs no I/O.
+func contentFormat(format string, content string) string {
+	if !magicFormats[format] {
+		return format
+	}
+	if sniffed := sniffContent(strings.NewReader(content), int64(len(content))); sniffed != "" {
+		return sniffed
+	}
+	return format
+}
+
+// extractorFor returns the extractor for a document of format, or for the format its
+// content shows when that differs and has an extractor (see contentFormat)
+func (r *ExtractorRegistry) extractorFor(format string, content string) (Extractor, bool) {
+	if actual := contentFormat(format, content); actual != format {
+		if extractor, ok := r.GetExtractor(actual); ok {
+			return extractor, true
+		}
+	}
+	return r.GetExtractor(format)
+}
+
+// sniffFormat returns the format the content of path shows ("" when none)
+func sniffFormat(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	st, err := file.Stat()
+	if err != nil || !st.Mode().IsRegular() {
+		return ""
+	}
+	return sniffContent(file, st.Size())
+}
+
+// sniffContent identifies a document from its leading bytes: %PDF, {\rtf, zip packages
+// ([Content_Types].xml for Office, mimetype for OpenDocument and EPUB), OLE compound files
+// (Word or Outlook), mbox "From " lines, RFC 822 headers and HTML. Plain zips, other OLE
+// files and unrecognized content give "".
+func sniffContent(r io.ReaderAt, size int64) string {
+	head := make([]byte, sniffBytes)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte(`{\rtf`)):
+		return ".rtf"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return sniffZip(r, size)
+	case bytes.HasPrefix(head, oleMagic):
+		return sniffOLE(r)
+	case bytes.Contains(head, []byte("%PDF-")):
+		return ".pdf"
+	}
+	if n == sniffBytes {
+		// Drop the line the read cut short
+		if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+			head = head[:i+1]
+		}
+	}
+
+	text := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF")), " \t\r\n")
+	if rest, ok := bytes.CutPrefix(text, []byte("From ")); ok {
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 && looksLikeHeaders(rest[i+1:]) {
+			return ".mbox"
+		}
+		return ""
+	}
+	if looksLikeHeaders(text) {
+		return ".eml"
+	}
+	lower := bytes.ToLower(text)
+	if bytes.HasPrefix(lower, []byte("<!doctype html")) || bytes.HasPrefix(lower, []byte("<html")) {
+		return ".html"
+	}
+	return ""
+}
+
+// sniffZip tells Office and OpenDocument packages and EPUBs apart by their entries
+func sniffZip(r io.ReaderAt, size int64) string {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ""
+	}
+	names := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		names[f.Name] = f
+	}
+	if f, ok := names["mimetype"]; ok {
+		rc, err := f.Open()
+		if err != nil {
+			return ""
+		}
+		b, _ := io.ReadAll(io.LimitReader(rc, 128))
+		rc.Close()
+		switch strings.TrimSpace(string(b)) {
+		case "application/vnd.oasis.opendocument.text":
+			return ".odt"
+		case "application/vnd.oasis.opendocument.spreadsheet":
+			return ".ods"
+		case "application/vnd.oasis.opendocument.presentation":
+			return ".odp"
+		case "application/epub+zip":
+			return ".epub"
+		}
+		return ""
+	}
+	if _, ok := names["[Content_Types].xml"]; !ok {
+		return ""
+	}
+	switch {
+	case names["word/document.xml"] != nil:
+		return ".docx"
+	case names["xl/workbook.xml"] != nil:
+		return ".xlsx"
+	case names["ppt/presentation.xml"] != nil:
+		return ".pptx"
+	}
+	return ""
+}
+
+// sniffOLE tells Word documents (a WordDocument stream) from Outlook messages (MAPI
+// property streams) by the top-level entries of a compound file
+func sniffOLE(r io.ReaderAt) string {
+	cf, err := mscfb.New(r)
+	if err != nil {
+		return ""
+	}
+	for ent, err := cf.Next(); err == nil; ent, err = cf.Next() {
+		if len(ent.Path) != 0 {
+			continue
+		}
+		switch {
+		case ent.Name == "WordDocument":
+			return ".doc"
+		case strings.HasPrefix(ent.Name, "__substg1.0_"), ent.Name == msgProperties:
+			return ".msg"
+		}
+	}
+	return ""
+}
+
+// looksLikeHeaders reports whether text opens with an RFC 822 header block: every line up
+// to the first blank one is "Name: value" or a folded continuation, and at least two of the
+// names are common mail headers
+func looksLikeHeaders(text []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(text))
+	known, lines := 0, 0
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			break
+		}
+		if lines > 0 && (line[0] == ' ' || line[0] == '\t') {
+			continue
+		}
+		name, _, ok := strings.Cut(line, ":")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return false
+		}
+		if mailHeaders[strings.ToLower(name)] {
+			known++
+		}
+		lines++
+	}
+	return known >= 2
+}
+
+// knownExtensions returns the extensions ("*.ext" globs) of every type group
+func knownExtensions() map[string]bool {
+	known := make(map[string]bool)
+	for _, g := range config.TypeGroups() {
+		for _, glob := range g.Globs {
+			if ext, ok := globExtension(glob); ok {
+				known[ext] = true
+			}
+		}
+	}
+	return known
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zipFile returns a zip archive holding the given entries, stored in order
+func zipFile(t *testing.T, entries ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i+1 < len(entries); i += 2 {
+		w, err := zw.Create(entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(entries[i+1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSniffContent(t *testing.T) {
+	mail := "From: ann@example.com\r\nTo: bob@example.com\r\nSubject: hi\r\n\r\nbody"
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"rtf", []byte(`{\rtf1\ansi hello}`), ".rtf"},
+		{"pdf", []byte("%PDF-1.7\n"), ".pdf"},
+		{"pdf after junk", []byte(strings.Repeat("x", 500) + "%PDF-1.4"), ".pdf"},
+		{"docx", zipFile(t, "[Content_Types].xml", "<Types/>", "word/document.xml", "<w:document/>"), ".docx"},
+		{"xlsx", zipFile(t, "[Content_Types].xml", "<Types/>", "xl/workbook.xml", "<workbook/>"), ".xlsx"},
+		{"pptx", zipFile(t, "[Content_Types].xml", "<Types/>", "ppt/presentation.xml", "<p/>"), ".pptx"},
+		{"odt", zipFile(t, "mimetype", "application/vnd.oasis.opendocument.text", "content.xml", "<x/>"), ".odt"},
+		{"ods", zipFile(t, "mimetype", "application/vnd.oasis.opendocument.spreadsheet"), ".ods"},
+		{"epub", zipFile(t, "mimetype", "application/epub+zip"), ".epub"},
+		{"other mimetype", zipFile(t, "mimetype", "application/x-other"), ""},
+		{"plain zip", zipFile(t, "a.txt", "hello"), ""},
+		{"word", wordDocument(0, []wordText{{"text", true}}, nil), ".doc"},
+		{"outlook", compoundFile([]string{"__substg1.0_0037001F"}, map[string][]byte{"__substg1.0_0037001F": []byte("s")}), ".msg"},
+		{"other ole", compoundFile([]string{"Workbook"}, map[string][]byte{"Workbook": []byte("w")}), ""},
+		{"eml", []byte(mail), ".eml"},
+		{"eml with bom", []byte("\xEF\xBB\xBF\r\n" + mail), ".eml"},
+		{"folded header", []byte("Subject: a long\r\n  subject\r\nFrom: ann\r\n\r\n"), ".eml"},
+		{"one header", []byte("Subject: hi\r\n\r\nbody"), ""},
+		{"not headers", []byte("Dear Bob: hello\nFrom: me\n"), ""},
+		{"mbox", []byte("From ann@example.com Mon Jan  1 00:00:00 2024\n" + mail), ".mbox"},
+		{"from sentence", []byte("From here on, we walk.\nNothing else."), ""},
+		{"html", []byte("  <!DOCTYPE html><html><body>x</body></html>"), ".html"},
+		{"html tag", []byte("<HTML><p>x</p>"), ".html"},
+		{"text", []byte("just some notes"), ""},
+		{"empty", nil, ""},
+	}
+	for _, tt := range tests {
+		if got := sniffContent(bytes.NewReader(tt.content), int64(len(tt.content))); got != tt.want {
+			t.Errorf("%s: sniffContent = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContentFormat(t *testing.T) {
+	rtf := `{\rtf1 saved as doc}`
+	for _, tt := range []struct{ format, content, want string }{
+		{".doc", rtf, ".rtf"},
+		{".doc", "<html><body>x</body></html>", ".html"},
+		{".doc", "unrecognized", ".doc"},
+		{".txt", rtf, ".txt"}, // only formats with a signature are checked
+		{".pdf", "%PDF-1.5", ".pdf"},
+	} {
+		if got := contentFormat(tt.format, tt.content); got != tt.want {
+			t.Errorf("contentFormat(%q, %q) = %q, want %q", tt.format, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestTypeFilterSniffs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"notes":      "From: ann@example.com\nTo: bob@example.com\n\nhello",
+		"export.dat": `{\rtf1 hello}`,
+		"readme":     "plain text",
+		"mail.txt":   "From: ann@example.com\nTo: bob@example.com\n\nhello",
+		"a.eml":      "anything",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := newTypeFilter([]string{"-g", "*.eml", "-g", "*.rtf"})
+	for name, want := range map[string]string{
+		"notes":      "notes.eml",
+		"export.dat": "export.dat.rtf",
+		"readme":     "",
+		"mail.txt":   "", // a known extension is never sniffed
+		"a.eml":      "a.eml",
+	} {
+		path := filepath.Join(dir, name)
+		if got := f.selects(path); got != (want != "") {
+			t.Errorf("selects(%s) = %v, want %v", name, got, want != "")
+			continue
+		}
+		if want != "" && f.name(path) != filepath.Join(dir, want) {
+			t.Errorf("name(%s) = %s, want %s", name, f.name(path), filepath.Join(dir, want))
+		}
+	}
+
+	// A selection of formats sniffing cannot tell opens nothing
+	if f := newTypeFilter([]string{"-g", "*.txt"}); f.sniffs || f.selects(filepath.Join(dir, "notes")) {
+		t.Error("a *.txt selection must not sniff")
+	}
+	// Each filter keeps its own sniffed formats
+	if other := newTypeFilter([]string{"-g", "*.eml"}); other.name(filepath.Join(dir, "notes")) != filepath.Join(dir, "notes") {
+		t.Error("a new filter must not see formats sniffed by another")
+	}
+}
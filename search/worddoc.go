@@ -0,0 +1,267 @@
+package search
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// maxWordStreamBytes bounds the WordDocument and table streams read from a .doc
+const maxWordStreamBytes = 64 << 20
+
+var (
+	errNotWordDocument = errors.New("not a Word 97-2003 document")
+	errWordEncrypted   = errors.New("encrypted Word document")
+)
+
+// Subdocuments of a Word document, in the order their text is stored after the main
+// text (FibRgLw97 ccpText .. ccpHdrTxbx); the value is the index in FibRgLw97
+const (
+	wordMainText    = 3
+	wordFootnotes   = 4
+	wordHeaders     = 5
+	wordMacro       = 6
+	wordAnnotations = 7
+	wordEndnotes    = 8
+	wordTextboxes   = 9
+	wordHdrTextbox  = 10
+)
+
+// wordPiece is one entry of the piece table: characters [cpStart, cpEnd) stored at fc in
+// the WordDocument stream, as cp1252 bytes when compressed, else as UTF-16LE
+type wordPiece struct {
+	cpStart, cpEnd uint32
+	fc             uint32
+	compressed     bool
+}
+
+// wordDocumentText returns the text of a Word 97-2003 (.doc) compound file. The FIB is read
+// from the WordDocument stream and the piece table (CLX) from the table stream it names, so
+// text comes out in document order whether its pieces are stored compressed (cp1252) or as
+// UTF-16. Field codes are dropped while their results are kept, and object, picture and
+// note-reference placeholders are removed. The main text and text boxes are always returned;
+// extras adds footnotes, headers and footers, comments and endnotes (--doc-extras).
+func wordDocumentText(r io.ReaderAt, extras bool) (string, error) {
+	cf, err := mscfb.New(r)
+	if err != nil {
+		return "", err
+	}
+	streams := make(map[string][]byte)
+	for ent, err := cf.Next(); err == nil; ent, err = cf.Next() {
+		// Embedded documents in ObjectPool have streams of the same names
+		if len(ent.Path) != 0 {
+			continue
+		}
+		switch ent.Name {
+		case "WordDocument", "0Table", "1Table":
+			if ent.Size > maxWordStreamBytes {
+				return "", fmt.Errorf("%s stream too large (%d bytes)", ent.Name, ent.Size)
+			}
+			b, err := io.ReadAll(ent)
+			if err != nil {
+				return "", err
+			}
+			streams[ent.Name] = b
+		}
+	}
+
+	word := streams["WordDocument"]
+	le := binary.LittleEndian
+	if len(word) < 34 || le.Uint16(word) != 0xA5EC {
+		return "", errNotWordDocument
+	}
+	if le.Uint16(word[2:]) < 0x00C1 {
+		// Word 6 and Word 95 files have another FIB layout
+		return "", errNotWordDocument
+	}
+	flags := le.Uint16(word[0x0A:])
+	if flags&0x0100 != 0 {
+		return "", errWordEncrypted
+	}
+	table := streams["0Table"]
+	if flags&0x0200 != 0 {
+		table = streams["1Table"]
+	}
+
+	// FibBase (32 bytes), then the variable-length FibRgW, FibRgLw and FibRgFcLcb arrays
+	pos := 32
+	csw := int(le.Uint16(word[pos:]))
+	pos += 2 + 2*csw
+	if pos+2 > len(word) {
+		return "", errNotWordDocument
+	}
+	cslw := int(le.Uint16(word[pos:]))
+	rgLw := pos + 2
+	pos = rgLw + 4*cslw
+	if cslw <= wordHdrTextbox || pos+2 > len(word) {
+		return "", errNotWordDocument
+	}
+	cbRgFcLcb := int(le.Uint16(word[pos:]))
+	rgFcLcb := pos + 2
+	const clxIndex = 33 // fcClx/lcbClx in FibRgFcLcb97
+	if cbRgFcLcb <= clxIndex || rgFcLcb+8*(clxIndex+1) > len(word) {
+		return "", errNotWordDocument
+	}
+	fcClx := le.Uint32(word[rgFcLcb+8*clxIndex:])
+	lcbClx := le.Uint32(word[rgFcLcb+8*clxIndex+4:])
+	if uint64(fcClx)+uint64(lcbClx) > uint64(len(table)) {
+		return "", fmt.Errorf("piece table outside the table stream")
+	}
+	pieces, err := wordPieces(table[fcClx : fcClx+lcbClx])
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	var cp uint32
+	for i := wordMainText; i <= wordHdrTextbox; i++ {
+		n := le.Uint32(word[rgLw+4*i:])
+		want := i == wordMainText || i == wordTextboxes
+		if extras && i != wordMacro {
+			want = true
+		}
+		if want && n > 0 {
+			if out.Len() > 0 {
+				out.WriteString("\n")
+			}
+			writeWordRange(&out, word, pieces, cp, cp+n)
+		}
+		cp += n
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// wordPieces parses a CLX: any number of Prc (formatting) blocks, then the Pcdt holding
+// the piece table, a PlcPcd of n+1 character positions followed by n 8-byte PCDs
+func wordPieces(clx []byte) ([]wordPiece, error) {
+	le := binary.LittleEndian
+	for i := 0; i < len(clx); {
+		switch clx[i] {
+		case 0x01:
+			if i+3 > len(clx) {
+				return nil, errors.New("truncated CLX")
+			}
+			cb := int(int16(le.Uint16(clx[i+1:])))
+			if cb < 0 {
+				return nil, errors.New("malformed CLX")
+			}
+			i += 3 + cb
+		case 0x02:
+			if i+5 > len(clx) {
+				return nil, errors.New("truncated CLX")
+			}
+			lcb := int(le.Uint32(clx[i+1:]))
+			plc := clx[i+5:]
+			if lcb < 4 || lcb > len(plc) || (lcb-4)%12 != 0 {
+				return nil, errors.New("malformed piece table")
+			}
+			n := (lcb - 4) / 12
+			pieces := make([]wordPiece, 0, n)
+			for k := 0; k < n; k++ {
+				pcd := plc[4*(n+1)+8*k:]
+				fc := le.Uint32(pcd[2:])
+				p := wordPiece{
+					cpStart:    le.Uint32(plc[4*k:]),
+					cpEnd:      le.Uint32(plc[4*(k+1):]),
+					fc:         fc & 0x3FFFFFFF,
+					compressed: fc&0x40000000 != 0,
+				}
+				if p.compressed {
+					p.fc /= 2
+				}
+				if p.cpEnd > p.cpStart {
+					pieces = append(pieces, p)
+				}
+			}
+			return pieces, nil
+		default:
+			return nil, errors.New("malformed CLX")
+		}
+	}
+	return nil, errors.New("no piece table")
+}
+
+// writeWordRange writes the characters [from, to) of the document, decoding each piece that
+// overlaps the range. Pieces pointing outside the WordDocument stream are skipped.
+func writeWordRange(w *strings.Builder, word []byte, pieces []wordPiece, from, to uint32) {
+	var fields []bool // open fields; true while still in their instructions
+	for _, p := range pieces {
+		start, end := p.cpStart, p.cpEnd
+		if start < from {
+			start = from
+		}
+		if end > to {
+			end = to
+		}
+		if start >= end {
+			continue
+		}
+		off, n := uint64(p.fc)+uint64(start-p.cpStart), uint64(end-start)
+		if p.compressed {
+			if off+n > uint64(len(word)) {
+				continue
+			}
+			for _, b := range word[off : off+n] {
+				fields = writeWordChar(w, charmap.Windows1252.DecodeByte(b), fields)
+			}
+			continue
+		}
+		off = uint64(p.fc) + 2*uint64(start-p.cpStart)
+		if off+2*n > uint64(len(word)) {
+			continue
+		}
+		units := make([]uint16, n)
+		for k := range units {
+			units[k] = binary.LittleEndian.Uint16(word[off+2*uint64(k):])
+		}
+		for _, r := range utf16.Decode(units) {
+			fields = writeWordChar(w, r, fields)
+		}
+	}
+}
+
+// writeWordChar writes one document character as text. Fields are 0x13 instructions 0x14
+// result 0x15 and may nest; only their results are text. Cell and row marks become tabs,
+// paragraph, line, page and column breaks newlines, and other control characters
+// (objects, pictures, footnote and comment references) are dropped.
+func writeWordChar(w *strings.Builder, r rune, fields []bool) []bool {
+	switch r {
+	case 0x13:
+		return append(fields, true)
+	case 0x14:
+		if n := len(fields); n > 0 {
+			fields[n-1] = false
+		}
+		return fields
+	case 0x15:
+		if n := len(fields); n > 0 {
+			fields = fields[:n-1]
+		}
+		return fields
+	}
+	for _, instructions := range fields {
+		if instructions {
+			return fields
+		}
+	}
+	switch {
+	case r == 0x07 || r == '\t':
+		w.WriteByte('\t')
+	case r == 0x0B || r == 0x0C || r == 0x0D || r == 0x0E:
+		w.WriteByte('\n')
+	case r == 0x1E:
+		w.WriteByte('-')
+	case r == 0xA0:
+		w.WriteByte(' ')
+	case r < 0x20:
+	default:
+		w.WriteRune(r)
+	}
+	return fields
+}
@@ -0,0 +1,226 @@
+package search
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// compoundFile builds a version 3 compound file holding streams at the root. Every stream
+// is padded to the 4096-byte mini stream cutoff, so all of them live in regular sectors.
+func compoundFile(names []string, streams map[string][]byte) []byte {
+	const sector = 512
+	const endOfChain, freeSect, noStream = 0xFFFFFFFE, 0xFFFFFFFF, 0xFFFFFFFF
+	le := binary.LittleEndian
+
+	fat := make([]uint32, sector/4)
+	for i := range fat {
+		fat[i] = freeSect
+	}
+	fat[0] = 0xFFFFFFFD // the FAT sector itself
+	fat[1] = endOfChain // the directory
+	next := uint32(2)
+	var body []byte
+	starts := make([]uint32, len(names))
+	sizes := make([]int, len(names))
+	for i, name := range names {
+		data := streams[name]
+		sizes[i] = max(len(data), 4096)
+		padded := make([]byte, (sizes[i]+sector-1)/sector*sector)
+		copy(padded, data)
+		starts[i] = next
+		for k := 1; k < len(padded)/sector; k++ {
+			fat[next] = next + 1
+			next++
+		}
+		fat[next] = endOfChain
+		next++
+		body = append(body, padded...)
+	}
+
+	entry := func(name string, typ byte, child, right uint32, start uint32, size int) []byte {
+		e := make([]byte, 128)
+		u := utf16.Encode([]rune(name))
+		for i, c := range u {
+			le.PutUint16(e[2*i:], c)
+		}
+		le.PutUint16(e[64:], uint16(2*len(u)+2))
+		e[66], e[67] = typ, 1 // black
+		le.PutUint32(e[68:], noStream)
+		le.PutUint32(e[72:], right)
+		le.PutUint32(e[76:], child)
+		le.PutUint32(e[116:], start)
+		le.PutUint32(e[120:], uint32(size))
+		return e
+	}
+	dir := entry("Root Entry", 5, 1, noStream, endOfChain, 0)
+	for i, name := range names {
+		right := uint32(noStream)
+		if i+1 < len(names) {
+			right = uint32(i + 2)
+		}
+		dir = append(dir, entry(name, 2, noStream, right, starts[i], sizes[i])...)
+	}
+	for len(dir) < sector {
+		dir = append(dir, entry("", 0, noStream, noStream, 0, 0)...)
+	}
+
+	header := make([]byte, sector)
+	copy(header, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	le.PutUint16(header[24:], 0x003E)
+	le.PutUint16(header[26:], 3)
+	le.PutUint16(header[28:], 0xFFFE)
+	le.PutUint16(header[30:], 9)
+	le.PutUint16(header[32:], 6)
+	le.PutUint32(header[44:], 1)
+	le.PutUint32(header[48:], 1)
+	le.PutUint32(header[56:], 4096)
+	le.PutUint32(header[60:], endOfChain)
+	le.PutUint32(header[68:], endOfChain)
+	le.PutUint32(header[76:], 0) // DIFAT: the one FAT sector
+	for off := 80; off < sector; off += 4 {
+		le.PutUint32(header[off:], freeSect)
+	}
+
+	var out bytes.Buffer
+	out.Write(header)
+	for _, v := range fat {
+		binary.Write(&out, le, v)
+	}
+	out.Write(dir[:sector])
+	out.Write(body)
+	return out.Bytes()
+}
+
+// wordText is one piece of a test document: compressed pieces are stored one byte per
+// character (Latin-1 runes only)
+type wordText struct {
+	text       string
+	compressed bool
+}
+
+// wordDocument builds a Word 97 document whose main text and footnotes are made of the
+// given pieces, with its piece table in the table stream the flags name
+func wordDocument(flags uint16, main, footnotes []wordText) []byte {
+	le := binary.LittleEndian
+	word := make([]byte, 1024)
+	le.PutUint16(word[0:], 0xA5EC)
+	le.PutUint16(word[2:], 0x00C1)
+	le.PutUint16(word[0x0A:], flags)
+	le.PutUint16(word[32:], 14) // csw
+	const rgLw = 34 + 28 + 2
+	le.PutUint16(word[rgLw-2:], 22) // cslw
+	const rgFcLcb = rgLw + 88 + 2
+	le.PutUint16(word[rgFcLcb-2:], 93) // cbRgFcLcb
+
+	var cps []uint32
+	var pcds []byte
+	var cp uint32
+	for _, p := range append(append([]wordText{}, main...), footnotes...) {
+		fc := uint32(len(word))
+		n := 0
+		if p.compressed {
+			for _, r := range p.text {
+				word = append(word, byte(r))
+				n++
+			}
+			fc = fc*2 | 0x40000000
+		} else {
+			for _, u := range utf16.Encode([]rune(p.text)) {
+				word = le.AppendUint16(word, u)
+				n++
+			}
+		}
+		cps = append(cps, cp)
+		cp += uint32(n)
+		pcd := make([]byte, 8)
+		le.PutUint32(pcd[2:], fc)
+		pcds = append(pcds, pcd...)
+	}
+	cps = append(cps, cp)
+	count := func(pieces []wordText) (n uint32) {
+		for _, p := range pieces {
+			n += uint32(len([]rune(p.text)))
+		}
+		return n
+	}
+	le.PutUint32(word[rgLw+4*wordMainText:], count(main))
+	le.PutUint32(word[rgLw+4*wordFootnotes:], count(footnotes))
+
+	// A Prc block to skip, then the Pcdt
+	clx := []byte{0x01, 0x02, 0x00, 0xAA, 0xBB, 0x02}
+	plc := []byte{}
+	for _, c := range cps {
+		plc = le.AppendUint32(plc, c)
+	}
+	plc = append(plc, pcds...)
+	clx = le.AppendUint32(clx, uint32(len(plc)))
+	clx = append(clx, plc...)
+	le.PutUint32(word[rgFcLcb+8*33:], 0)
+	le.PutUint32(word[rgFcLcb+8*33+4:], uint32(len(clx)))
+
+	table := "0Table"
+	if flags&0x0200 != 0 {
+		table = "1Table"
+	}
+	return compoundFile([]string{"WordDocument", table}, map[string][]byte{"WordDocument": word, table: clx})
+}
+
+func TestWordDocumentText(t *testing.T) {
+	main := []wordText{
+		{"Budget \x13 HYPERLINK \"http://x\" \x14report\x15 café\r", true},
+		{"Привет\x07world\x01\x0bnext\x1eline\r", false},
+		{"\x13 REF a \x13 nested \x15\x14shown\x15\r", true},
+	}
+	footnotes := []wordText{{"A footnote\r", true}}
+	tests := []struct {
+		name   string
+		flags  uint16
+		extras bool
+		want   string
+	}{
+		{"main text", 0, false, "Budget report café\nПривет\tworld\nnext-line\nshown"},
+		{"with extras", 0, true, "Budget report café\nПривет\tworld\nnext-line\nshown\n\nA footnote"},
+		{"1Table", 0x0200, false, "Budget report café\nПривет\tworld\nnext-line\nshown"},
+	}
+	for _, tt := range tests {
+		got, err := wordDocumentText(bytes.NewReader(wordDocument(tt.flags, main, footnotes)), tt.extras)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWordDocumentTextErrors(t *testing.T) {
+	encrypted := wordDocument(0x0100, []wordText{{"secret", true}}, nil)
+	if _, err := wordDocumentText(bytes.NewReader(encrypted), false); !errors.Is(err, errWordEncrypted) {
+		t.Errorf("encrypted: err = %v, want %v", err, errWordEncrypted)
+	}
+	notWord := compoundFile([]string{"WordDocument"}, map[string][]byte{"WordDocument": []byte("plain bytes")})
+	if _, err := wordDocumentText(bytes.NewReader(notWord), false); !errors.Is(err, errNotWordDocument) {
+		t.Errorf("not a FIB: err = %v, want %v", err, errNotWordDocument)
+	}
+	if _, err := wordDocumentText(bytes.NewReader([]byte("not a compound file")), false); err == nil {
+		t.Error("not a compound file: want an error")
+	}
+}
+
+func TestWordPieces(t *testing.T) {
+	for name, clx := range map[string][]byte{
+		"empty":        nil,
+		"unknown":      {0x07},
+		"truncated":    {0x02, 0x10},
+		"bad length":   {0x02, 0x05, 0, 0, 0, 1, 2, 3, 4, 5},
+		"negative prc": {0x01, 0xFF, 0xFF},
+	} {
+		if _, err := wordPieces(clx); err == nil {
+			t.Errorf("wordPieces(%s): want an error", name)
+		}
+	}
+}